@@ -1,14 +1,18 @@
 package ast
 import (
 	"bytes"
+	"strconv"
 	"strings"
 
 	"github.com/kakts/monkey/token"
 )
 // ASTのすべてのノードが実装しなければならない つまり TokenLiteral()を実装する必要がある
+// Pos()/End() はノードに対応するソースコード上の開始位置と終了位置（最後の文字の直後）を返す
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
+	End() token.Position
 }
 
 type Statement interface {
@@ -24,6 +28,10 @@ type Expression interface {
 // 構文解析器が生成するすべてのASTのルートノードになるもの
 type Program struct {
 	Statements []Statement
+
+	// ソースコード中のコメント (出現順)
+	// 評価には使わないが、整形などでソースコードを復元するときのために残す
+	Comments []*Comment
 }
 
 func (p *Program) String() string {
@@ -45,6 +53,30 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
+// // または /* */ のコメント
+type Comment struct {
+	Token token.Token // token.COMMENT トークン
+}
+
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+func (c *Comment) String() string       { return c.Token.Literal }
+func (c *Comment) Pos() token.Position  { return c.Token.Pos }
+func (c *Comment) End() token.Position  { return c.Token.End }
+
 
 type LetStatement struct {
 	Token token.Token // token.LET トークン
@@ -56,6 +88,16 @@ func (ls *LetStatement) statementNode() {}
 func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End
+}
 
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
@@ -84,6 +126,9 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+func (i *Identifier) End() token.Position { return i.Token.End }
+
 func (i *Identifier) String() string {
 	return i.Value
 }
@@ -98,6 +143,13 @@ func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
 
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -111,6 +163,93 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// ループを抜ける break;
+type BreakStatement struct {
+	Token token.Token // 'break' トークン
+}
+
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+func (bs *BreakStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BreakStatement) End() token.Position { return bs.Token.End }
+func (bs *BreakStatement) String() string {
+	return bs.TokenLiteral() + ";"
+}
+
+// ループの次の繰り返しに進む continue;
+type ContinueStatement struct {
+	Token token.Token // 'continue' トークン
+}
+
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+func (cs *ContinueStatement) Pos() token.Position { return cs.Token.Pos }
+func (cs *ContinueStatement) End() token.Position { return cs.Token.End }
+func (cs *ContinueStatement) String() string {
+	return cs.TokenLiteral() + ";"
+}
+
+// モジュールの読み込み
+// import "path" as name; はモジュール全体をnameに束縛し、
+// import { a, b } from "path"; はexportされた束縛a, bを同じ名前で束縛する
+type ImportStatement struct {
+	Token token.Token // 'import' トークン
+	Path *StringLiteral
+	Alias *Identifier // 選択的importの場合はnil
+	Names []*Identifier // 選択的importで指定した名前
+}
+
+func (is *ImportStatement) statementNode() {}
+func (is *ImportStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+func (is *ImportStatement) Pos() token.Position { return is.Token.Pos }
+func (is *ImportStatement) End() token.Position {
+	if is.Alias != nil {
+		return is.Alias.End()
+	}
+	return is.Path.End()
+}
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(is.TokenLiteral() + " ")
+	if is.Alias != nil {
+		out.WriteString(strconv.Quote(is.Path.Value))
+		out.WriteString(" as ")
+		out.WriteString(is.Alias.String())
+	} else {
+		names := []string{}
+		for _, n := range is.Names {
+			names = append(names, n.String())
+		}
+		out.WriteString("{ " + strings.Join(names, ", ") + " } from ")
+		out.WriteString(strconv.Quote(is.Path.Value))
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// モジュールの外から使えるようにする束縛 export let name = value;
+type ExportStatement struct {
+	Token token.Token // 'export' トークン
+	Statement *LetStatement
+}
+
+func (es *ExportStatement) statementNode() {}
+func (es *ExportStatement) TokenLiteral() string {
+	return es.Token.Literal
+}
+func (es *ExportStatement) Pos() token.Position { return es.Token.Pos }
+func (es *ExportStatement) End() token.Position { return es.Statement.End() }
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
 
 // 式のみからなる文
 type ExpressionStatement struct {
@@ -122,6 +261,18 @@ func (es *ExpressionStatement) statementNode() {}
 func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
+func (es *ExpressionStatement) Pos() token.Position {
+	if es.Expression != nil {
+		return es.Expression.Pos()
+	}
+	return es.Token.Pos
+}
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
 
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -140,11 +291,24 @@ func (il *IntegerLiteral) expressionNode() {}
 func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position { return il.Token.End }
 
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position  { return fl.Token.End }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type PrefixExpression struct {
 	Token token.Token
 	Operator string
@@ -153,6 +317,13 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode() {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -174,6 +345,19 @@ func (oe *InfixExpression) expressionNode() {}
 func (oe *InfixExpression) TokenLiteral() string {
 	return oe.Token.Literal
 }
+// 中置式の開始位置は演算子ではなく左辺の開始位置
+func (oe *InfixExpression) Pos() token.Position {
+	if oe.Left != nil {
+		return oe.Left.Pos()
+	}
+	return oe.Token.Pos
+}
+func (oe *InfixExpression) End() token.Position {
+	if oe.Right != nil {
+		return oe.Right.End()
+	}
+	return oe.Token.End
+}
 func (oe *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -185,6 +369,42 @@ func (oe *InfixExpression) String() string {
 	return out.String()
 }
 
+// 代入式 x = 5, x += 1, arr[0] = 1 など
+// 値は代入した値になる
+type AssignExpression struct {
+	Token token.Token // 代入演算子トークン
+	Target Expression // 代入先 IdentifierかIndexExpression
+	Operator string // "=", "+=" など
+	Value Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+// 代入式の開始位置は代入先の開始位置
+func (ae *AssignExpression) Pos() token.Position {
+	if ae.Target != nil {
+		return ae.Target.Pos()
+	}
+	return ae.Token.Pos
+}
+func (ae *AssignExpression) End() token.Position {
+	if ae.Value != nil {
+		return ae.Value.End()
+	}
+	return ae.Token.End
+}
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+	return out.String()
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -196,6 +416,9 @@ func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+func (b *Boolean) End() token.Position { return b.Token.End }
+
 func (b *Boolean) String() string {
 	return b.Token.Literal
 }
@@ -213,6 +436,17 @@ func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return ie.Token.End
+}
+
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -229,9 +463,73 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// while (条件) { 本体 }
+// 値は常にnullになる
+type WhileExpression struct {
+	Token token.Token // 'while' トークン
+	Condition Expression
+	Body *BlockStatement
+}
+
+func (we *WhileExpression) expressionNode() {}
+func (we *WhileExpression) TokenLiteral() string {
+	return we.Token.Literal
+}
+func (we *WhileExpression) Pos() token.Position { return we.Token.Pos }
+func (we *WhileExpression) End() token.Position {
+	if we.Body != nil {
+		return we.Body.End()
+	}
+	return we.Token.End
+}
+func (we *WhileExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(we.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(we.Body.String())
+
+	return out.String()
+}
+
+// for (変数 in 反復対象) { 本体 }
+// 値は常にnullになる
+type ForExpression struct {
+	Token token.Token // 'for' トークン
+	Variable *Identifier
+	Iterable Expression
+	Body *BlockStatement
+}
+
+func (fe *ForExpression) expressionNode() {}
+func (fe *ForExpression) TokenLiteral() string {
+	return fe.Token.Literal
+}
+func (fe *ForExpression) Pos() token.Position { return fe.Token.Pos }
+func (fe *ForExpression) End() token.Position {
+	if fe.Body != nil {
+		return fe.Body.End()
+	}
+	return fe.Token.End
+}
+func (fe *ForExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fe.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fe.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fe.Body.String())
+
+	return out.String()
+}
+
 type BlockStatement struct {
-	Token token.Token
+	Token token.Token // '{' トークン
 	Statements []Statement
+	Rbrace token.Token // '}' トークン
 }
 
 func (bs *BlockStatement) statementNode() {}
@@ -240,6 +538,9 @@ func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
 
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position { return bs.Rbrace.End }
+
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -254,6 +555,7 @@ type FunctionLiteral struct {
 	Token token.Token
 	Parameters []*Identifier
 	Body *BlockStatement
+	Name string // let文で束縛される場合はその名前
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -262,6 +564,14 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
 
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
+
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 	
@@ -280,9 +590,10 @@ func (fl *FunctionLiteral) String() string {
 }
 
 type CallExpression struct {
-	Token token.Token
+	Token token.Token // '(' トークン
 	Function Expression // identifier or FunctionLiteral
 	Arguments []Expression
+	Rparen token.Token // ')' トークン
 }
 
 func (ce *CallExpression) expressionNode() {}
@@ -291,6 +602,14 @@ func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+func (ce *CallExpression) Pos() token.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos
+}
+func (ce *CallExpression) End() token.Position { return ce.Rparen.End }
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -316,13 +635,42 @@ func (sl *StringLiteral) expressionNode() {}
 func (sl *StringLiteral) TokenLiteral() string {
 	return sl.Token.Literal
 }
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position { return sl.Token.End }
 func (sl *StringLiteral) String() string {
 	return sl.Token.Literal
 }
 
+// 埋め込み式を含む文字列 "hello ${name}"
+// Partsには文字列部分 (*StringLiteral) と埋め込み式が出現順に並ぶ 空の文字列部分は含まない
+type InterpolatedString struct {
+	Token    token.Token // token.STRING_START トークン
+	Parts    []Expression
+	EndToken token.Token // token.STRING_END トークン
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Pos() token.Position  { return is.Token.Pos }
+func (is *InterpolatedString) End() token.Position  { return is.EndToken.End }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	for _, part := range is.Parts {
+		if s, ok := part.(*StringLiteral); ok {
+			out.WriteString(s.Value)
+			continue
+		}
+		out.WriteString("${" + part.String() + "}")
+	}
+
+	return out.String()
+}
+
 type ArrayLiteral struct {
-	Token token.Token
+	Token token.Token // '[' トークン
 	Elements []Expression
+	Rbracket token.Token // ']' トークン
 }
 
 func (al *ArrayLiteral) expressionNode() {}
@@ -331,6 +679,9 @@ func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Literal
 }
 
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position { return al.Rbracket.End }
+
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -348,15 +699,23 @@ func (al *ArrayLiteral) String() string {
 
 // 配列のインデックスのastノード
 type IndexExpression struct {
-	Token token.Token
+	Token token.Token // '[' トークン
 	Left Expression // アクセスされるオブジェクト
 	Index Expression // インデックス　整数であるべき
+	Rbracket token.Token // ']' トークン
 }
 
 func (ie *IndexExpression) expressionNode() {}
 func (ie *IndexExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
+func (ie *IndexExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+func (ie *IndexExpression) End() token.Position { return ie.Rbracket.End }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -369,11 +728,74 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// メンバーアクセス object.member
+type MemberExpression struct {
+	Token token.Token // '.' トークン
+	Object Expression
+	Member *Identifier
+}
+
+func (me *MemberExpression) expressionNode() {}
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+func (me *MemberExpression) Pos() token.Position {
+	if me.Object != nil {
+		return me.Object.Pos()
+	}
+	return me.Token.Pos
+}
+func (me *MemberExpression) End() token.Position { return me.Member.End() }
+func (me *MemberExpression) String() string {
+	return me.Object.String() + "." + me.Member.String()
+}
+
+// スライス式 left[low:high]
+// 省略した位置はnil
+type SliceExpression struct {
+	Token token.Token // '[' トークン
+	Left Expression
+	Low Expression // 開始位置
+	High Expression // 終了位置 (この位置は含まない)
+	Rbracket token.Token // ']' トークン
+}
+
+func (se *SliceExpression) expressionNode() {}
+func (se *SliceExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+func (se *SliceExpression) Pos() token.Position {
+	if se.Left != nil {
+		return se.Left.Pos()
+	}
+	return se.Token.Pos
+}
+func (se *SliceExpression) End() token.Position { return se.Rbracket.End }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
 // ハッシュリテラル
 // 任意の式を　キー・ハッシュで許容する
 type HashLiteral struct {
-	Token token.Token
+	Token token.Token // '{' トークン
 	Pairs map[Expression]Expression
+	Keys []Expression // キーを書かれた順に並べたもの
+	Rbrace token.Token // '}' トークン
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -382,12 +804,15 @@ func (hl *HashLiteral) TokenLiteral() string {
 	return hl.Token.Literal
 }
 
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position { return hl.Rbrace.End }
+
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String() + ":" + value.String())
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String() + ":" + hl.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+
+	"github.com/kakts/monkey/compiler"
+	"github.com/kakts/monkey/evaluator"
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/object"
+	"github.com/kakts/monkey/parser"
+	"github.com/kakts/monkey/repl"
+	"github.com/kakts/monkey/vm"
+)
+
+// 終了コード
+const (
+	exitOK    = 0
+	exitError = 1 // 構文エラー、コンパイルエラー、捕捉されなかった実行時エラー
+	exitUsage = 2 // コマンドラインの誤り
+)
+
+const usage = `Usage:
+  monkey [flags] run <file> [args...]   run a script file ('-' reads stdin)
+  monkey [flags] repl                   start an interactive session
+  monkey [flags] -e <expr> [args...]    evaluate an expression and print the result
+  monkey fmt [-w] [files...]            format source files ('-w' rewrites them, stdin if none)
+
+The script arguments are bound to the array 'args'.
+
+Flags:
+`
+
+// コマンドラインを解釈して実行し、終了コードを返す
+func run(arguments []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	engine := flags.String("engine", repl.EngineEval, "execution engine: 'eval' or 'vm'")
+	expr := flags.String("e", "", "evaluate `expr` and print the result")
+	trace := flags.Bool("trace", false, "print an evaluation trace to stderr (eval engine only)")
+	overflowName := flags.String("overflow", object.OverflowWrap.String(),
+		"integer overflow policy: 'wrap', 'error' or 'promote' (to arbitrary precision)")
+
+	if err := flags.Parse(arguments); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if *engine != repl.EngineEval && *engine != repl.EngineVM {
+		fmt.Fprintf(stderr, "monkey: unknown engine %q\n", *engine)
+		return exitUsage
+	}
+	overflow, ok := object.ParseOverflowPolicy(*overflowName)
+	if !ok {
+		fmt.Fprintf(stderr, "monkey: unknown overflow policy %q\n", *overflowName)
+		return exitUsage
+	}
+	if *trace && *engine != repl.EngineEval {
+		fmt.Fprintln(stderr, "monkey: -trace is only supported by the eval engine")
+		return exitUsage
+	}
+
+	cfg := &runConfig{engine: *engine, trace: *trace, overflow: overflow, stdout: stdout, stderr: stderr}
+
+	// -e が指定された場合、残りの引数はすべてスクリプトの引数
+	if isFlagSet(flags, "e") {
+		return execute(cfg, "-e", *expr, flags.Args(), true)
+	}
+
+	if flags.NArg() == 0 {
+		return startRepl(stdin, stdout, *engine, overflow)
+	}
+
+	switch cmd := flags.Arg(0); cmd {
+	case "repl":
+		if flags.NArg() > 1 {
+			flags.Usage()
+			return exitUsage
+		}
+		if *trace {
+			fmt.Fprintln(stderr, "monkey: -trace is not supported in the repl")
+			return exitUsage
+		}
+		return startRepl(stdin, stdout, *engine, overflow)
+	case "run":
+		if flags.NArg() < 2 {
+			fmt.Fprintln(stderr, "monkey run: no script file given")
+			return exitUsage
+		}
+
+		filename := flags.Arg(1)
+		var src []byte
+		var err error
+		if filename == "-" {
+			src, err = io.ReadAll(stdin)
+		} else {
+			src, err = os.ReadFile(filename)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey: %s\n", err)
+			return exitError
+		}
+
+		return execute(cfg, filename, string(src), flags.Args()[2:], false)
+	case "fmt":
+		return formatFiles(flags.Args()[1:], stdin, stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "monkey: unknown command %q\n", cmd)
+		flags.Usage()
+		return exitUsage
+	}
+}
+
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func startRepl(stdin io.Reader, stdout io.Writer, engine string, overflow object.OverflowPolicy) int {
+	if u, err := user.Current(); err == nil {
+		fmt.Fprintf(stdout, "Hello %s! This is the Monkey programming language!\n", u.Username)
+	}
+	fmt.Fprintf(stdout, "Feel free to type in commands \n")
+	object.Stdout = stdout
+	repl.Start(stdin, stdout, engine, overflow)
+	return exitOK
+}
+
+// スクリプトを実行するときの設定
+type runConfig struct {
+	engine   string
+	trace    bool                  // 評価の経過をstderrに出力する
+	overflow object.OverflowPolicy // 整数演算の桁あふれの扱い
+
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// ソースを構文解析して実行する
+// エラーはstderrに出力し、printResultが真なら評価結果をstdoutに出力する
+func execute(cfg *runConfig, filename, src string, scriptArgs []string, printResult bool) int {
+	stdout, stderr := cfg.stdout, cfg.stderr
+
+	l := lexer.NewFile(filename, src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		for _, err := range p.Errors() {
+			fmt.Fprintln(stderr, err.Error())
+		}
+		return exitError
+	}
+
+	object.Stdout = stdout
+	args := argsArray(scriptArgs)
+
+	var result object.Object
+	if cfg.engine == repl.EngineVM {
+		symbolTable := compiler.NewSymbolTableWithBuiltins()
+		argsSymbol := symbolTable.Define("args")
+
+		comp := compiler.NewWithState(symbolTable, []object.Object{})
+		if err := comp.Compile(program); err != nil {
+			fmt.Fprintf(stderr, "%s: compile error: %s\n", filename, err)
+			return exitError
+		}
+
+		globals := vm.NewGlobalsStore()
+		globals[argsSymbol.Index] = args
+
+		machine := vm.NewWithGlobalsStore(comp.Bytecode(), globals)
+		machine.Overflow = cfg.overflow
+		if err := machine.Run(); err != nil {
+			printRuntimeError(stderr, filename, err)
+			return exitError
+		}
+		result = machine.LastPoppedStackElem()
+	} else {
+		env := object.NewEnvironment()
+		env.Set("args", args)
+
+		e := evaluator.New()
+		e.Overflow = cfg.overflow
+		if cfg.trace {
+			e.Tracer = evaluator.NewWriterTracer(stderr)
+		}
+
+		result = e.Eval(program, env)
+		if errObj, ok := result.(*object.Error); ok {
+			printRuntimeError(stderr, filename, errObj)
+			return exitError
+		}
+	}
+
+	if printResult && result != nil {
+		fmt.Fprintln(stdout, result.Inspect())
+	}
+
+	return exitOK
+}
+
+func printRuntimeError(stderr io.Writer, filename string, err error) {
+	if errObj, ok := err.(*object.Error); ok && errObj.Pos.IsValid() {
+		fmt.Fprintf(stderr, "%s: runtime error: %s\n", errObj.Pos, errObj.Message)
+		return
+	}
+	fmt.Fprintf(stderr, "%s: runtime error: %s\n", filename, err)
+}
+
+// スクリプトの引数を文字列の配列にする
+func argsArray(scriptArgs []string) *object.Array {
+	elements := make([]object.Object, len(scriptArgs))
+	for i, a := range scriptArgs {
+		elements[i] = &object.String{Value: a}
+	}
+	return &object.Array{Elements: elements}
+}
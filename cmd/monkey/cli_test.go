@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	dir, err := os.MkdirTemp("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "args.mk")
+	err = os.WriteFile(script, []byte(`puts(len(args)); puts(first(args));`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.mk")
+	err = os.WriteFile(broken, []byte("let x = ;"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args         []string
+		expectedCode int
+		stdout       string
+		stderr       string
+	}{
+		{[]string{"-e", "1 + 2"}, exitOK, "3\n", ""},
+		{[]string{"-engine=vm", "-e", "1 + 2"}, exitOK, "3\n", ""},
+		{[]string{"-e", "args[1]", "a", "b"}, exitOK, "b\n", ""},
+		{[]string{"-e", "1 + true"}, exitError, "", "-e:1:1: runtime error: type mismatch: INTEGER + BOOLEAN\n"},
+		{[]string{"-engine=vm", "-e", "1 + true"}, exitError, "", "-e:1:1: runtime error: type mismatch: INTEGER + BOOLEAN\n"},
+		{[]string{"-trace", "-e", "1"}, exitOK, "1\n", "BEGIN Program -e:1:1 1\n"},
+		{[]string{"-trace", "-engine=vm", "-e", "1"}, exitUsage, "", "monkey: -trace is only supported by the eval engine\n"},
+		{[]string{"-e", "1 / 0"}, exitError, "", "-e:1:1: runtime error: division by zero\n"},
+		{[]string{"-overflow=promote", "-e", "9223372036854775807 + 1"}, exitOK, "9223372036854775808\n", ""},
+		{[]string{"-overflow=error", "-engine=vm", "-e", "9223372036854775807 + 1"}, exitError, "", "integer overflow"},
+		{[]string{"-overflow=saturate", "-e", "1"}, exitUsage, "", "monkey: unknown overflow policy \"saturate\"\n"},
+		{[]string{"-e", "let = 1"}, exitError, "", "-e:1:5:"},
+		{[]string{"run", script, "x", "y"}, exitOK, "2\nx\n", ""},
+		{[]string{"-engine=vm", "run", script, "x", "y"}, exitOK, "2\nx\n", ""},
+		{[]string{"run", broken}, exitError, "", broken + ":1:9:"},
+		{[]string{"run", filepath.Join(dir, "missing.mk")}, exitError, "", "monkey: "},
+		{[]string{"run"}, exitUsage, "", "monkey run: no script file given\n"},
+		{[]string{"-engine=jit", "repl"}, exitUsage, "", "monkey: unknown engine \"jit\"\n"},
+		{[]string{"compile"}, exitUsage, "", "monkey: unknown command \"compile\"\n"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(""), &stdout, &stderr)
+
+		if code != tt.expectedCode {
+			t.Errorf("%v: wrong exit code. want=%d, got=%d (stderr=%q)",
+				tt.args, tt.expectedCode, code, stderr.String())
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: wrong stdout. want=%q, got=%q", tt.args, tt.stdout, stdout.String())
+		}
+		if !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("%v: wrong stderr. want to contain %q, got=%q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
+
+// -overflowはreplにも適用する
+func TestReplOverflow(t *testing.T) {
+	for _, engine := range []string{"eval", "vm"} {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"-engine=" + engine, "-overflow=promote", "repl"}, strings.NewReader("9223372036854775807 + 1\n"), &stdout, &stderr)
+
+		if code != exitOK {
+			t.Fatalf("%s: wrong exit code. want=%d, got=%d (stderr=%q)", engine, exitOK, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "9223372036854775808\n") {
+			t.Errorf("%s: overflow policy not applied. stdout=%q", engine, stdout.String())
+		}
+	}
+}
+
+func TestRunScriptFromStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"run", "-"}, strings.NewReader(`puts("hi")`), &stdout, &stderr)
+
+	if code != exitOK {
+		t.Fatalf("wrong exit code. want=%d, got=%d (stderr=%q)", exitOK, code, stderr.String())
+	}
+	if stdout.String() != "hi\n" {
+		t.Errorf("wrong stdout. got=%q", stdout.String())
+	}
+}
+
+func TestFmt(t *testing.T) {
+	dir, err := os.MkdirTemp("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	messy := filepath.Join(dir, "messy.mk")
+	err = os.WriteFile(messy, []byte("let  add=fn(a,b){a+b}\nputs((add(1,2)))"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.mk")
+	err = os.WriteFile(broken, []byte("let x = ;"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted := "let add = fn(a, b) { a + b };\nputs(add(1, 2));\n"
+
+	tests := []struct {
+		args         []string
+		stdin        string
+		expectedCode int
+		stdout       string
+		stderr       string
+	}{
+		{[]string{"fmt"}, "1+2", exitOK, "1 + 2;\n", ""},
+		{[]string{"fmt", messy}, "", exitOK, formatted, ""},
+		{[]string{"fmt", broken, messy}, "", exitError, formatted, broken + ":1:9:"},
+		{[]string{"fmt", filepath.Join(dir, "missing.mk")}, "", exitError, "", "monkey fmt: "},
+		{[]string{"fmt", "-w"}, "", exitUsage, "", "monkey fmt: cannot use -w with standard input\n"},
+		{[]string{"fmt", "-x"}, "", exitUsage, "", "flag provided but not defined: -x"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+		if code != tt.expectedCode {
+			t.Errorf("%v: wrong exit code. want=%d, got=%d (stderr=%q)",
+				tt.args, tt.expectedCode, code, stderr.String())
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: wrong stdout. want=%q, got=%q", tt.args, tt.stdout, stdout.String())
+		}
+		if !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("%v: wrong stderr. want to contain %q, got=%q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+
+	// -w はファイルを書き換え、何も出力しない
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"fmt", "-w", messy}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK || stdout.Len() != 0 {
+		t.Fatalf("wrong result for -w. code=%d, stdout=%q, stderr=%q", code, stdout.String(), stderr.String())
+	}
+	src, err := os.ReadFile(messy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != formatted {
+		t.Errorf("file not rewritten. want=%q, got=%q", formatted, string(src))
+	}
+}
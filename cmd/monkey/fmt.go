@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kakts/monkey/format"
+)
+
+// monkey fmt [-w] [files...]
+// ファイルを整形してstdoutに出力する -w の場合はファイルを書き換える
+// ファイルを指定しない場合はstdinを整形する
+func formatFiles(arguments []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monkey fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+
+	if err := flags.Parse(arguments); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(stderr, "monkey fmt: cannot use -w with standard input")
+			return exitUsage
+		}
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			return exitError
+		}
+		return formatFile("<stdin>", src, stdout, stderr, nil)
+	}
+
+	// エラーがあっても残りのファイルは整形する
+	code := exitOK
+	for _, filename := range flags.Args() {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			code = exitError
+			continue
+		}
+
+		var writeBack func(string) error
+		if *write {
+			writeBack = func(formatted string) error {
+				info, err := os.Stat(filename)
+				if err != nil {
+					return err
+				}
+				return os.WriteFile(filename, []byte(formatted), info.Mode().Perm())
+			}
+		}
+		if formatFile(filename, src, stdout, stderr, writeBack) != exitOK {
+			code = exitError
+		}
+	}
+	return code
+}
+
+// 整形した結果をwriteBackに渡す (nilの場合はstdoutに出力する)
+// 変わらない場合はファイルを書き換えない
+func formatFile(filename string, src []byte, stdout, stderr io.Writer, writeBack func(string) error) int {
+	formatted, err := format.Source(filename, string(src))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	if writeBack == nil {
+		fmt.Fprint(stdout, formatted)
+		return exitOK
+	}
+	if formatted == string(src) {
+		return exitOK
+	}
+	if err := writeBack(formatted); err != nil {
+		fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,296 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// バイトコード命令列
+// 1つの命令はオペコード1バイトと0個以上のオペランドからなる
+type Instructions []byte
+
+// 命令列を人が読める形式で出力する
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	operandCount := len(def.OperandWidths)
+
+	if len(operands) != operandCount {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n",
+			len(operands), operandCount)
+	}
+
+	switch operandCount {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+}
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota // 定数プールの値をスタックに積む
+
+	// 二項演算
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+
+	OpPop // スタックの先頭を捨てる
+
+	OpTrue
+	OpFalse
+
+	// 比較
+	OpEqual
+	OpNotEqual
+	OpGreaterThan
+	OpLessThan
+	OpGreaterEqual
+	OpLessEqual
+
+	// 前置演算
+	OpMinus
+	OpBang
+
+	// ジャンプ オペランドはジャンプ先の絶対位置
+	OpJumpNotTruthy
+	OpJump
+
+	OpNull
+
+	// グローバル束縛
+	OpGetGlobal
+	OpSetGlobal
+
+	// 配列、ハッシュリテラル、添字アクセス
+	OpArray
+	OpHash
+	OpIndex
+
+	// 関数呼び出し
+	OpCall
+	OpReturnValue // スタックの先頭を戻り値として返す
+	OpReturn      // 戻り値無しで返す (NULLを返す)
+
+	// ローカル束縛
+	OpGetLocal
+	OpSetLocal
+
+	OpGetBuiltin
+
+	// クロージャ
+	OpClosure
+	OpGetFree
+	OpCurrentClosure
+
+	// 捕捉 クロージャが外側の変数を共有できるよう、値ではなくセルを積む
+	OpCaptureLocal
+	OpCaptureFree
+
+	// 代入 束縛を書き換え、代入した値をスタックに残す
+	OpAssignGlobal
+	OpAssignLocal
+	OpAssignFree
+	OpSetIndex
+
+	OpDup2 // スタックの先頭2つを複製する
+
+	// ループ
+	OpLoopEnter // ループに入った時点のスタックの高さを記録する
+	OpLoopExit  // 記録したスタックの高さを捨てる
+	OpLoopJump  // スタックを記録した高さに戻してジャンプする (break, continue)
+	OpIterator  // スタックの先頭を反復子に置き換える
+	OpIterNext  // 反復子の次の値を積む 無ければジャンプする
+
+	OpInterpolate // スタックの先頭n個を文字列にして連結する
+
+	OpSlice // 対象、開始位置、終了位置を取り出してスライスを積む 省略した位置はnull
+
+	// モジュール
+	OpImport    // モジュールの関数を初回だけ呼び出し、その結果のモジュールを積む
+	OpModule    // モジュール名とn組の名前と値からモジュールを作る
+	OpGetMember // スタックの先頭のメンバーを積む
+)
+
+// オペコードの定義
+// Nameはデバッグ用、OperandWidthsは各オペランドのバイト数
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+
+	OpAdd: {"OpAdd", []int{}},
+	OpSub: {"OpSub", []int{}},
+	OpMul: {"OpMul", []int{}},
+	OpDiv: {"OpDiv", []int{}},
+	OpMod: {"OpMod", []int{}},
+
+	OpPop: {"OpPop", []int{}},
+
+	OpTrue:  {"OpTrue", []int{}},
+	OpFalse: {"OpFalse", []int{}},
+
+	OpEqual:       {"OpEqual", []int{}},
+	OpNotEqual:    {"OpNotEqual", []int{}},
+	OpGreaterThan: {"OpGreaterThan", []int{}},
+	OpLessThan:    {"OpLessThan", []int{}},
+
+	OpGreaterEqual: {"OpGreaterEqual", []int{}},
+	OpLessEqual:    {"OpLessEqual", []int{}},
+
+	OpMinus: {"OpMinus", []int{}},
+	OpBang:  {"OpBang", []int{}},
+
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJump:          {"OpJump", []int{2}},
+
+	OpNull: {"OpNull", []int{}},
+
+	OpGetGlobal: {"OpGetGlobal", []int{2}},
+	OpSetGlobal: {"OpSetGlobal", []int{2}},
+
+	// オペランドは要素数 (ハッシュの場合はキーと値の合計数)
+	OpArray: {"OpArray", []int{2}},
+	OpHash:  {"OpHash", []int{2}},
+	OpIndex: {"OpIndex", []int{}},
+
+	// オペランドは引数の数
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+
+	OpGetLocal: {"OpGetLocal", []int{1}},
+	OpSetLocal: {"OpSetLocal", []int{1}},
+
+	OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+
+	// オペランドは定数プール内の関数のインデックスと自由変数の数
+	OpClosure:        {"OpClosure", []int{2, 1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+
+	OpCaptureLocal: {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:  {"OpCaptureFree", []int{1}},
+
+	OpAssignGlobal: {"OpAssignGlobal", []int{2}},
+	OpAssignLocal:  {"OpAssignLocal", []int{1}},
+	OpAssignFree:   {"OpAssignFree", []int{1}},
+	OpSetIndex:     {"OpSetIndex", []int{}},
+
+	OpDup2: {"OpDup2", []int{}},
+
+	OpLoopEnter: {"OpLoopEnter", []int{}},
+	OpLoopExit:  {"OpLoopExit", []int{}},
+	OpLoopJump:  {"OpLoopJump", []int{2}},
+	OpIterator:  {"OpIterator", []int{}},
+	OpIterNext:  {"OpIterNext", []int{2}},
+
+	OpInterpolate: {"OpInterpolate", []int{2}},
+
+	OpSlice: {"OpSlice", []int{}},
+
+	// オペランドはモジュールの関数の定数プール内のインデックス、exportする束縛の数、メンバー名の定数のインデックス
+	OpImport:    {"OpImport", []int{2}},
+	OpModule:    {"OpModule", []int{2}},
+	OpGetMember: {"OpGetMember", []int{2}},
+}
+
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// オペコードとオペランドから命令を組み立てる
+// オペランドはビッグエンディアンで格納する
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instructionLen := 1
+	for _, w := range def.OperandWidths {
+		instructionLen += w
+	}
+
+	instruction := make([]byte, instructionLen)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// Makeの逆 命令からオペランドを読み出し、読んだバイト数と共に返す
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
@@ -0,0 +1,32 @@
+package code
+
+import (
+	"sort"
+
+	"github.com/kakts/monkey/token"
+)
+
+// 命令とソースコード上の位置の対応
+// 命令の開始位置の昇順に並び、直前の命令と位置が変わる命令だけを記録する
+type SourceMap []SourcePos
+
+type SourcePos struct {
+	Offset int            // 命令の開始位置
+	Pos    token.Position // 命令を出力したノードの位置
+}
+
+// offsetの命令 (オペランドの途中でもよい) に対応する位置を返す
+// 記録が無い場合はゼロ値を返す
+func (m SourceMap) Lookup(offset int) token.Position {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset > offset })
+	if i == 0 {
+		return token.Position{}
+	}
+	return m[i-1].Pos
+}
+
+// offset以降の命令の記録を取り除く
+func (m SourceMap) Truncate(offset int) SourceMap {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset >= offset })
+	return m[:i]
+}
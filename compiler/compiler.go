@@ -0,0 +1,959 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/code"
+	"github.com/kakts/monkey/module"
+	"github.com/kakts/monkey/object"
+	"github.com/kakts/monkey/token"
+)
+
+// *ast.Programをバイトコードに変換するコンパイラ
+type Compiler struct {
+	constants []object.Object // 定数プール
+
+	symbolTable *SymbolTable
+
+	// 関数毎のコンパイル状態 先頭がメインプログラム
+	scopes     []CompilationScope
+	scopeIndex int
+
+	// コンパイルしたモジュール (キーはmodule.Key) とコンパイル中のモジュール
+	modules   map[string]*compiledModule
+	importing module.Stack
+
+	// コンパイル中の最も内側のノードの位置
+	// 出力する命令に記録し、vmの実行時エラーの位置に使う
+	pos token.Position
+}
+
+// モジュールはトップレベルの文を関数としてコンパイルし、importした位置で一度だけ呼び出す
+type compiledModule struct {
+	fnIndex int      // モジュールの関数の定数プール内のインデックス
+	exports []string // exportする名前
+}
+
+// 直前に出力した命令
+// if式の結果を残すためにOpPopを取り除く際に使う
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+type CompilationScope struct {
+	instructions        code.Instructions
+	positions           code.SourceMap
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+
+	// コンパイル中のループ 最後が最も内側
+	loops []*loopContext
+}
+
+// break と continue のジャンプ先
+type loopContext struct {
+	continuePos int   // continue のジャンプ先
+	breakJumps  []int // ループの終わりが決まってから書き換える break の位置
+}
+
+// コンパイル結果
+type Bytecode struct {
+	Instructions code.Instructions
+	Positions    code.SourceMap // 命令とソースコード上の位置の対応
+	Constants    []object.Object
+	GlobalNames  []string // グローバル束縛のインデックス順の名前
+}
+
+func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: NewSymbolTableWithBuiltins(),
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+// REPLのように複数回コンパイルする場合に、シンボル表と定数プールを引き継ぐ
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
+// 組み込み関数を定義済みのシンボル表を生成する
+// NewWithStateに渡す初期状態として使う
+func NewSymbolTableWithBuiltins() *SymbolTable {
+	symbolTable := NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+	return symbolTable
+}
+
+func (c *Compiler) Compile(node ast.Node) error {
+	outer := c.pos
+	c.pos = node.Pos()
+	defer func() { c.pos = outer }()
+
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, s := range node.Statements {
+			err := c.Compile(s)
+			if err != nil {
+				return err
+			}
+		}
+
+	case *ast.ExpressionStatement:
+		err := c.Compile(node.Expression)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
+		}
+
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		err = c.emitInfixOperator(node.Operator)
+		if err != nil {
+			return err
+		}
+
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+
+	case *ast.WhileExpression:
+		return c.compileWhileExpression(node)
+
+	case *ast.ForExpression:
+		return c.compileForExpression(node)
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("break outside loop")
+		}
+		// ジャンプ先は後で書き換える
+		pos := c.emit(code.OpLoopJump, 9999)
+		loop.breakJumps = append(loop.breakJumps, pos)
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("continue outside loop")
+		}
+		c.emit(code.OpLoopJump, loop.continuePos)
+
+	case *ast.PrefixExpression:
+		err := c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.IfExpression:
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+
+		// ジャンプ先は後で書き換える
+		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+		err = c.compileBlockValue(node.Consequence)
+		if err != nil {
+			return err
+		}
+
+		jumpPos := c.emit(code.OpJump, 9999)
+
+		afterConsequencePos := len(c.currentInstructions())
+		c.changeOperand(jumpNotTruthyPos, afterConsequencePos)
+
+		if node.Alternative == nil {
+			c.emit(code.OpNull)
+		} else {
+			err := c.compileBlockValue(node.Alternative)
+			if err != nil {
+				return err
+			}
+		}
+
+		afterAlternativePos := len(c.currentInstructions())
+		c.changeOperand(jumpPos, afterAlternativePos)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			err := c.Compile(s)
+			if err != nil {
+				return err
+			}
+		}
+
+	case *ast.ImportStatement:
+		return c.compileImportStatement(node)
+
+	case *ast.ExportStatement:
+		return c.Compile(node.Statement)
+
+	case *ast.LetStatement:
+		// 右辺を先に評価するため、束縛の定義は右辺のコンパイル後に行う
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		symbol := c.symbolTable.Define(node.Name.Value)
+		if symbol.Scope == GlobalScope {
+			c.emit(code.OpSetGlobal, symbol.Index)
+		} else {
+			c.emit(code.OpSetLocal, symbol.Index)
+		}
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			// 未定義の識別子は後で定義されるグローバル束縛として扱う
+			// 実行時に値が無ければ identifier not found エラーになる
+			symbol = c.symbolTable.root().Define(node.Value)
+		}
+
+		c.loadSymbol(symbol)
+
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(integer))
+
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			err := c.Compile(part)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpInterpolate, len(node.Parts))
+
+	case *ast.HashLiteral:
+		// キーを書かれた順に積み、ハッシュの順序をソースと揃える
+		for _, k := range node.Keys {
+			err := c.Compile(k)
+			if err != nil {
+				return err
+			}
+			err = c.Compile(node.Pairs[k])
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	case *ast.IndexExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Index)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpIndex)
+
+	case *ast.MemberExpression:
+		err := c.Compile(node.Object)
+		if err != nil {
+			return err
+		}
+
+		name := &object.String{Value: node.Member.Value}
+		c.emit(code.OpGetMember, c.addConstant(name))
+
+	case *ast.SliceExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		// 省略した位置はnullを積む
+		for _, bound := range []ast.Expression{node.Low, node.High} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+			err = c.Compile(bound)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpSlice)
+
+	case *ast.FunctionLiteral:
+		// ローカルスコープでletに束縛される関数は、自分自身を自由変数ではなく
+		// OpCurrentClosureで参照できるようにする
+		inLocalScope := c.symbolTable.Outer != nil
+
+		c.enterScope()
+
+		if node.Name != "" && inLocalScope {
+			c.symbolTable.DefineFunctionName(node.Name)
+		}
+
+		for _, p := range node.Parameters {
+			c.symbolTable.Define(p.Value)
+		}
+
+		err := c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+
+		// 最後の式の値を戻り値にする
+		if c.lastInstructionIs(code.OpPop) {
+			c.replaceLastPopWithReturn()
+		}
+		if !c.lastInstructionIs(code.OpReturnValue) {
+			c.emit(code.OpReturn)
+		}
+
+		freeSymbols := c.symbolTable.FreeSymbols
+		numLocals := c.symbolTable.numDefinitions
+		instructions, positions := c.leaveScope()
+
+		// 捕捉する自由変数をスタックに積む
+		for _, s := range freeSymbols {
+			c.captureSymbol(s)
+		}
+
+		compiledFn := &object.CompiledFunction{
+			Instructions:  instructions,
+			Positions:     positions,
+			NumLocals:     numLocals,
+			NumParameters: len(node.Parameters),
+		}
+
+		fnIndex := c.addConstant(compiledFn)
+		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+
+	case *ast.ReturnStatement:
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpReturnValue)
+
+	case *ast.CallExpression:
+		err := c.Compile(node.Function)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range node.Arguments {
+			err := c.Compile(a)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpCall, len(node.Arguments))
+
+	default:
+		return fmt.Errorf("unsupported node %T", node)
+	}
+
+	return nil
+}
+
+// if式のブロックをコンパイルし、ブロックの値をスタックに残す
+// 最後が式文でないブロック (空のブロックやlet文で終わるブロック) の値はNULL
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	err := c.Compile(block)
+	if err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Positions:    c.scopes[c.scopeIndex].positions,
+		Constants:    c.constants,
+		GlobalNames:  c.symbolTable.GlobalNames(),
+	}
+}
+
+// 定数プールに追加し、そのインデックスを返す
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// 命令を出力し、その開始位置を返す
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	updatedInstructions := append(c.currentInstructions(), ins...)
+
+	c.scopes[c.scopeIndex].instructions = updatedInstructions
+
+	// 位置が直前の命令と変わる場合だけ記録する
+	scope := &c.scopes[c.scopeIndex]
+	if n := len(scope.positions); n == 0 || scope.positions[n-1].Pos != c.pos {
+		scope.positions = append(scope.positions, code.SourcePos{Offset: posNewInstruction, Pos: c.pos})
+	}
+
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	new := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].positions = c.scopes[c.scopeIndex].positions.Truncate(last.Position)
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+// 出力済みの命令のオペランドを書き換える (ジャンプ先の後埋め)
+// 中置演算子に対応する命令を出力する
+func (c *Compiler) emitInfixOperator(operator string) error {
+	switch operator {
+	case "+":
+		c.emit(code.OpAdd)
+	case "-":
+		c.emit(code.OpSub)
+	case "*":
+		c.emit(code.OpMul)
+	case "/":
+		c.emit(code.OpDiv)
+	case "%":
+		c.emit(code.OpMod)
+	case ">":
+		c.emit(code.OpGreaterThan)
+	case "<":
+		c.emit(code.OpLessThan)
+	case ">=":
+		c.emit(code.OpGreaterEqual)
+	case "<=":
+		c.emit(code.OpLessEqual)
+	case "==":
+		c.emit(code.OpEqual)
+	case "!=":
+		c.emit(code.OpNotEqual)
+	default:
+		return fmt.Errorf("unknown operator %s", operator)
+	}
+	return nil
+}
+
+// 代入式のコンパイル
+// 複合代入 (x += 1 など) は現在の値を読んでから右辺を評価し、演算結果を代入する
+// 代入した値はスタックに残り、式の値になる
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	compound := node.Operator != "="
+	operator := strings.TrimSuffix(node.Operator, "=")
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(target.Value)
+		if !ok {
+			// 未定義の識別子への代入は実行時に identifier not found エラーになる
+			symbol = c.symbolTable.root().Define(target.Value)
+		}
+
+		var assignOp code.Opcode
+		switch symbol.Scope {
+		case GlobalScope:
+			assignOp = code.OpAssignGlobal
+		case LocalScope:
+			assignOp = code.OpAssignLocal
+		case FreeScope:
+			assignOp = code.OpAssignFree
+		case BuiltinScope:
+			return fmt.Errorf("cannot assign to builtin: %s", target.Value)
+		default:
+			return fmt.Errorf("cannot assign to function name inside its own body: %s", target.Value)
+		}
+
+		if compound {
+			c.loadSymbol(symbol)
+		}
+
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		if compound {
+			err = c.emitInfixOperator(operator)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(assignOp, symbol.Index)
+
+	case *ast.IndexExpression:
+		err := c.Compile(target.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(target.Index)
+		if err != nil {
+			return err
+		}
+
+		if compound {
+			// 添字アクセス用にオブジェクトと添字を複製して現在の値を読む
+			c.emit(code.OpDup2)
+			c.emit(code.OpIndex)
+		}
+
+		err = c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		if compound {
+			err = c.emitInfixOperator(operator)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpSetIndex)
+
+	default:
+		return fmt.Errorf("cannot assign to %s", node.Target.String())
+	}
+
+	return nil
+}
+
+// while式のコンパイル
+// ループの値は常にnullになる
+func (c *Compiler) compileWhileExpression(node *ast.WhileExpression) error {
+	c.emit(code.OpLoopEnter)
+
+	conditionPos := len(c.currentInstructions())
+	err := c.Compile(node.Condition)
+	if err != nil {
+		return err
+	}
+
+	// ジャンプ先は後で書き換える
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	err = c.compileLoopBody(node.Body, conditionPos, func(endPos int) {
+		c.changeOperand(jumpNotTruthyPos, endPos)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpNull)
+	return nil
+}
+
+// for-in式のコンパイル
+// 反復子はループの間スタックに置き、ループ変数はletと同じく現在のスコープに定義する
+func (c *Compiler) compileForExpression(node *ast.ForExpression) error {
+	err := c.Compile(node.Iterable)
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpIterator)
+	c.emit(code.OpLoopEnter)
+
+	// ジャンプ先は後で書き換える
+	nextPos := c.emit(code.OpIterNext, 9999)
+
+	symbol := c.symbolTable.Define(node.Variable.Value)
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+
+	err = c.compileLoopBody(node.Body, nextPos, func(endPos int) {
+		c.changeOperand(nextPos, endPos)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 反復子を捨てる
+	c.emit(code.OpPop)
+	c.emit(code.OpNull)
+	return nil
+}
+
+// ループ本体をコンパイルし、先頭のcontinuePosに戻る
+// 本体の後にOpLoopExitを出力し、その位置をループの終わりとしてbreakとpatchEndに渡す
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement, continuePos int, patchEnd func(endPos int)) error {
+	loop := &loopContext{continuePos: continuePos}
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = append(scope.loops, loop)
+
+	err := c.Compile(body)
+	if err != nil {
+		return err
+	}
+
+	scope = &c.scopes[c.scopeIndex]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	c.emit(code.OpJump, continuePos)
+
+	endPos := len(c.currentInstructions())
+	patchEnd(endPos)
+	for _, pos := range loop.breakJumps {
+		c.changeOperand(pos, endPos)
+	}
+	c.emit(code.OpLoopExit)
+
+	return nil
+}
+
+// 最も内側のループ ループの外ならnil
+func (c *Compiler) currentLoop() *loopContext {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
+
+// && と || をジャンプ命令にコンパイルする
+// 左辺だけで結果が決まる場合は右辺を実行しない
+// 右辺の値は OpBang を2回適用して真偽値にする
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+
+	// ジャンプ先は後で書き換える
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if node.Operator == "&&" {
+		// 左辺が真なら右辺の値が結果になる
+		err = c.compileTruthiness(node.Right)
+		if err != nil {
+			return err
+		}
+		jumpPos := c.emit(code.OpJump, 9999)
+
+		c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+		c.emit(code.OpFalse)
+
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+		return nil
+	}
+
+	// 左辺が真なら右辺を飛ばしてtrueになる
+	c.emit(code.OpTrue)
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+	err = c.compileTruthiness(node.Right)
+	if err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+func (c *Compiler) compileTruthiness(node ast.Expression) error {
+	err := c.Compile(node)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpBang)
+	c.emit(code.OpBang)
+	return nil
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+// import文のコンパイル
+// 別名を付ける場合はモジュールを、選択的importの場合はexportされた値をそれぞれ束縛する
+func (c *Compiler) compileImportStatement(node *ast.ImportStatement) error {
+	importer := node.Token.Pos.Filename
+	path := module.Resolve(importer, node.Path.Value)
+
+	// メインのファイルも読み込み中として、モジュールからのimportを循環として検出する
+	if c.importing.Empty() && importer != "" {
+		c.importing.Push(importer)
+		defer c.importing.Pop()
+	}
+
+	mod, err := c.compileModule(path)
+	if err != nil {
+		return err
+	}
+
+	if node.Alias != nil {
+		c.emit(code.OpImport, mod.fnIndex)
+		c.setSymbol(c.symbolTable.Define(node.Alias.Value))
+		return nil
+	}
+
+	for _, name := range node.Names {
+		if !contains(mod.exports, name.Value) {
+			return fmt.Errorf("module %s does not export %s", path, name.Value)
+		}
+	}
+	for _, name := range node.Names {
+		c.emit(code.OpImport, mod.fnIndex)
+		c.emit(code.OpGetMember, c.addConstant(&object.String{Value: name.Value}))
+		c.setSymbol(c.symbolTable.Define(name.Value))
+	}
+	return nil
+}
+
+// モジュールを関数としてコンパイルする 同じファイルは一度だけコンパイルする
+// モジュールのトップレベルの束縛はグローバル束縛として、独立したシンボル表に定義する
+func (c *Compiler) compileModule(path string) (*compiledModule, error) {
+	key := module.Key(path)
+	if mod, ok := c.modules[key]; ok {
+		return mod, nil
+	}
+
+	if err := c.importing.Push(path); err != nil {
+		return nil, err
+	}
+	defer c.importing.Pop()
+
+	program, err := module.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	outer := c.symbolTable
+	c.enterScope()
+	c.symbolTable = NewModuleSymbolTable(outer)
+
+	err = c.Compile(program)
+	if err != nil {
+		c.leaveScope()
+		c.symbolTable = outer
+		return nil, err
+	}
+
+	// 関数の戻り値としてモジュールを作る
+	exports := module.Exports(program)
+	c.emit(code.OpConstant, c.addConstant(&object.String{Value: path}))
+	for _, name := range exports {
+		symbol, _ := c.symbolTable.Resolve(name)
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: name}))
+		c.loadSymbol(symbol)
+	}
+	c.emit(code.OpModule, len(exports))
+	c.emit(code.OpReturnValue)
+
+	instructions, positions := c.leaveScope()
+	c.symbolTable = outer
+
+	fn := &object.CompiledFunction{Instructions: instructions, Positions: positions}
+	mod := &compiledModule{fnIndex: c.addConstant(fn), exports: exports}
+
+	if c.modules == nil {
+		c.modules = map[string]*compiledModule{}
+	}
+	c.modules[key] = mod
+	return mod, nil
+}
+
+// スタックの先頭の値を束縛に設定する
+func (c *Compiler) setSymbol(symbol Symbol) {
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 関数本体のコンパイルを始める
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// 関数本体のコンパイルを終え、その命令列と位置の対応を返す
+func (c *Compiler) leaveScope() (code.Instructions, code.SourceMap) {
+	instructions := c.currentInstructions()
+	positions := c.scopes[c.scopeIndex].positions
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions, positions
+}
+
+// クロージャが捕捉する変数をスタックに積む
+// ローカル束縛と自由変数は値ではなくセルを積み、代入が外側の関数とクロージャの双方から見えるようにする
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpCaptureLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpCaptureFree, s.Index)
+	default:
+		c.loadSymbol(s)
+	}
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
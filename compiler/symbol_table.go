@@ -0,0 +1,131 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	BuiltinScope  SymbolScope = "BUILTIN"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION" // 関数自身の名前 (再帰呼び出し用)
+)
+
+// 識別子に対応する束縛の情報
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// スコープ毎の識別子の表
+// 関数の中ではOuterに外側のスコープの表を持つ
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+
+	// 外側のスコープから捕捉した自由変数 (元のスコープでのシンボル)
+	FreeSymbols []Symbol
+
+	// グローバル束縛のインデックスと名前の対応 (エラーメッセージ用)
+	// モジュールの表はメインプログラムの表と共有し、グローバル束縛のインデックスが重ならないようにする
+	globalNames *[]string
+}
+
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free, globalNames: &[]string{}}
+}
+
+// モジュールのトップレベルの表を生成する
+// モジュールの束縛はグローバル束縛の領域に置くが、名前はメインプログラムや他のモジュールと共有しない
+func NewModuleSymbolTable(main *SymbolTable) *SymbolTable {
+	symbolTable := NewSymbolTableWithBuiltins()
+	symbolTable.globalNames = main.root().globalNames
+	return symbolTable
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// 識別子を定義する
+// 同じスコープで同じ名前を再定義した場合は同じインデックスを使い回す
+// (evaluatorで同じ環境の束縛を上書きするのと同じく、捕捉済みのクロージャからも新しい値が見える)
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+		symbol.Index = len(*s.globalNames)
+		*s.globalNames = append(*s.globalNames, name)
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1}
+	symbol.Scope = FreeScope
+
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+// 識別子を解決する
+// 外側の関数のローカル束縛は自由変数として記録する
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	obj, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		obj, ok = s.Outer.Resolve(name)
+		if !ok {
+			return obj, ok
+		}
+
+		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+			return obj, ok
+		}
+
+		free := s.defineFree(obj)
+		return free, true
+	}
+	return obj, ok
+}
+
+// 最も外側の (グローバルスコープの) 表
+func (s *SymbolTable) root() *SymbolTable {
+	for s.Outer != nil {
+		s = s.Outer
+	}
+	return s
+}
+
+// グローバル束縛のインデックス順の名前一覧
+func (s *SymbolTable) GlobalNames() []string {
+	return *s.root().globalNames
+}
@@ -0,0 +1,100 @@
+package compiler
+
+import "testing"
+
+func TestDefineAndResolve(t *testing.T) {
+	global := NewSymbolTable()
+	a := global.Define("a")
+	// 再定義は同じインデックスを返す
+	if again := global.Define("a"); again != a {
+		t.Errorf("redefined global symbol differs. want=%+v, got=%+v", a, again)
+	}
+	b := global.Define("b")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	c := firstLocal.Define("c")
+	if again := firstLocal.Define("c"); again != c {
+		t.Errorf("redefined local symbol differs. want=%+v, got=%+v", c, again)
+	}
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	d := secondLocal.Define("d")
+
+	expected := []struct {
+		table  *SymbolTable
+		name   string
+		symbol Symbol
+	}{
+		{global, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{global, "b", Symbol{Name: "b", Scope: GlobalScope, Index: 1}},
+		{firstLocal, "a", a},
+		{firstLocal, "c", Symbol{Name: "c", Scope: LocalScope, Index: 0}},
+		{secondLocal, "b", b},
+		{secondLocal, "c", Symbol{Name: "c", Scope: FreeScope, Index: 0}},
+		{secondLocal, "d", d},
+	}
+
+	for _, tt := range expected {
+		result, ok := tt.table.Resolve(tt.name)
+		if !ok {
+			t.Errorf("name %s not resolvable", tt.name)
+			continue
+		}
+		if result != tt.symbol {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.name, tt.symbol, result)
+		}
+	}
+
+	if len(secondLocal.FreeSymbols) != 1 || secondLocal.FreeSymbols[0] != c {
+		t.Errorf("wrong free symbols. got=%+v", secondLocal.FreeSymbols)
+	}
+
+	names := secondLocal.GlobalNames()
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("wrong global names. got=%v", names)
+	}
+}
+
+func TestResolveBuiltins(t *testing.T) {
+	global := NewSymbolTableWithBuiltins()
+	local := NewEnclosedSymbolTable(global)
+
+	for _, table := range []*SymbolTable{global, local} {
+		symbol, ok := table.Resolve("len")
+		if !ok {
+			t.Fatalf("builtin len not resolvable")
+		}
+		if symbol.Scope != BuiltinScope || symbol.Index != 0 {
+			t.Errorf("wrong symbol for len. got=%+v", symbol)
+		}
+	}
+}
+
+// モジュールのトップレベルの束縛はグローバル束縛として、メインと重ならないインデックスを持つ
+func TestModuleSymbolTable(t *testing.T) {
+	global := NewSymbolTableWithBuiltins()
+	global.Define("a")
+
+	mod := NewModuleSymbolTable(global)
+	b := mod.Define("b")
+	if b != (Symbol{Name: "b", Scope: GlobalScope, Index: 1}) {
+		t.Errorf("wrong symbol for b. got=%+v", b)
+	}
+	// モジュールからメインの束縛は見えない
+	if _, ok := mod.Resolve("a"); ok {
+		t.Errorf("main symbol a resolvable from module")
+	}
+	if symbol, ok := mod.Resolve("len"); !ok || symbol.Scope != BuiltinScope {
+		t.Errorf("builtin len not resolvable from module. got=%+v", symbol)
+	}
+
+	c := global.Define("c")
+	if c.Index != 2 {
+		t.Errorf("wrong index for c. want=2, got=%d", c.Index)
+	}
+
+	names := global.GlobalNames()
+	if len(names) != 3 || names[0] != "a" || names[1] != "b" || names[2] != "c" {
+		t.Errorf("wrong global names. got=%v", names)
+	}
+}
@@ -0,0 +1,497 @@
+package monkey
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/kakts/monkey/evaluator"
+	"github.com/kakts/monkey/object"
+)
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+)
+
+// Goの値をMonkeyの値に変換する
+//
+//	nil                 -> null
+//	bool                -> BOOLEAN
+//	整数型               -> INTEGER
+//	*big.Int            -> INTEGER (int64に収まらなければBIGINT)
+//	浮動小数点数型        -> FLOAT
+//	string              -> STRING
+//	スライス、配列        -> ARRAY
+//	マップ               -> HASH (キーを昇順に並べる)
+//	関数                 -> BUILTIN
+//
+// object.Objectはそのまま使う
+// 自分自身を含むスライスやマップはMonkeyの値にできないのでエラーを返す
+func (i *Interpreter) ToObject(value interface{}) (object.Object, error) {
+	if value == nil {
+		return evaluator.NULL, nil
+	}
+	if obj, ok := value.(object.Object); ok {
+		return obj, nil
+	}
+	return i.valueToObject(reflect.ValueOf(value), map[goRef]bool{})
+}
+
+// 変換中のスライスやマップ
+// スライスは同じ配列の別の範囲を指すことがあるので長さも比べる
+type goRef struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// openは変換中のスライスとマップ
+func (i *Interpreter) valueToObject(v reflect.Value, open map[goRef]bool) (object.Object, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("integer overflow: %d", v.Uint())
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return evaluator.NULL, nil
+			}
+			ref := goRef{v.Type(), v.Pointer(), v.Len()}
+			if open[ref] {
+				return nil, fmt.Errorf("cannot convert %s containing itself", v.Type())
+			}
+			open[ref] = true
+			defer delete(open, ref)
+		}
+		elements := make([]object.Object, v.Len())
+		for n := 0; n < v.Len(); n++ {
+			elem, err := i.valueToObject(v.Index(n), open)
+			if err != nil {
+				return nil, err
+			}
+			elements[n] = elem
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		ref := goRef{v.Type(), v.Pointer(), 0}
+		if open[ref] {
+			return nil, fmt.Errorf("cannot convert %s containing itself", v.Type())
+		}
+		open[ref] = true
+		defer delete(open, ref)
+
+		pairs := []object.HashPair{}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := i.valueToObject(iter.Key(), open)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := key.(object.Hashable); !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+			value, err := i.valueToObject(iter.Value(), open)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, object.HashPair{Key: key, Value: value})
+		}
+
+		// Goのマップは順序が決まらないので、キーの順に並べてから追加する
+		sort.Slice(pairs, func(a, b int) bool {
+			return keyLess(pairs[a].Key, pairs[b].Key)
+		})
+		hash := object.NewHash()
+		for _, pair := range pairs {
+			hash.Set(pair.Key.(object.Hashable).HashKey(), pair)
+		}
+		return hash, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return i.wrapFunc(v), nil
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		if v.Type() == bigIntType {
+			n := new(big.Int).Set(v.Interface().(*big.Int))
+			return object.NormalizeInteger(n, object.OverflowPromote), nil
+		}
+		if obj, ok := v.Interface().(object.Object); ok {
+			return obj, nil
+		}
+		if v.Kind() == reflect.Interface {
+			return i.valueToObject(v.Elem(), open)
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported Go type: %s", v.Type())
+}
+
+// Goの関数を組み込み関数にする
+// 引数は関数の引数の型に変換して渡す
+// 戻り値の最後がerrorで、nilでなければMonkeyのエラーになる
+// 関数の中で起きたpanicもMonkeyのエラーになる
+func (i *Interpreter) wrapFunc(fn reflect.Value) *object.Builtin {
+	t := fn.Type()
+
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		numIn := t.NumIn()
+		if t.IsVariadic() {
+			if len(args) < numIn-1 {
+				return newError("wrong number of arguments: want at least %d, got=%d", numIn-1, len(args))
+			}
+		} else if len(args) != numIn {
+			return newError("wrong number of arguments: want=%d, got=%d", numIn, len(args))
+		}
+
+		in := make([]reflect.Value, len(args))
+		for n, arg := range args {
+			var paramType reflect.Type
+			if t.IsVariadic() && n >= numIn-1 {
+				paramType = t.In(numIn - 1).Elem()
+			} else {
+				paramType = t.In(n)
+			}
+
+			v, err := i.toValue(arg, paramType)
+			if err != nil {
+				return newError("argument %d: %s", n, err)
+			}
+			in[n] = v
+		}
+
+		out, panicked := callGo(fn, in)
+		if panicked != nil {
+			return newError("panic in Go function: %v", panicked)
+		}
+
+		if n := len(out); n > 0 && t.Out(n-1) == errorType {
+			if err := out[n-1].Interface(); err != nil {
+				return newError("%s", err)
+			}
+			out = out[:n-1]
+		}
+
+		switch len(out) {
+		case 0:
+			return evaluator.NULL
+		case 1:
+			obj, err := i.valueToObject(out[0], map[goRef]bool{})
+			if err != nil {
+				return newError("%s", err)
+			}
+			return obj
+		default:
+			// 複数の戻り値は配列にする
+			elements := make([]object.Object, len(out))
+			for n, o := range out {
+				obj, err := i.valueToObject(o, map[goRef]bool{})
+				if err != nil {
+					return newError("%s", err)
+				}
+				elements[n] = obj
+			}
+			return &object.Array{Elements: elements}
+		}
+	}}
+}
+
+// Goの関数を呼び出す panicしたらその値を返す
+func callGo(fn reflect.Value, in []reflect.Value) (out []reflect.Value, panicked interface{}) {
+	defer func() {
+		panicked = recover()
+	}()
+	return fn.Call(in), nil
+}
+
+// Monkeyの値をGoの値に変換する
+//
+//	null      -> nil
+//	BOOLEAN   -> bool
+//	INTEGER   -> int64
+//	BIGINT    -> *big.Int
+//	FLOAT     -> float64
+//	STRING    -> string
+//	ARRAY     -> []interface{}
+//	HASH      -> map[interface{}]interface{}
+//	FUNCTION  -> func(args ...interface{}) (interface{}, error)
+//
+// それ以外の値はobject.Objectのまま返す
+// 自分自身を含む配列やハッシュはGoの値にできないのでエラーを返す
+func (i *Interpreter) ToGo(obj object.Object) (interface{}, error) {
+	return i.toGo(obj, map[object.Object]bool{})
+}
+
+// openは変換中の配列とハッシュ
+func (i *Interpreter) toGo(obj object.Object, open map[object.Object]bool) (interface{}, error) {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.BigInt:
+		return new(big.Int).Set(obj.Value), nil
+	case *object.Float:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Array:
+		if open[obj] {
+			return nil, fmt.Errorf("cannot convert %s containing itself", obj.Type())
+		}
+		open[obj] = true
+		defer delete(open, obj)
+
+		elements := make([]interface{}, len(obj.Elements))
+		for n, elem := range obj.Elements {
+			v, err := i.toGo(elem, open)
+			if err != nil {
+				return nil, err
+			}
+			elements[n] = v
+		}
+		return elements, nil
+	case *object.Hash:
+		if open[obj] {
+			return nil, fmt.Errorf("cannot convert %s containing itself", obj.Type())
+		}
+		open[obj] = true
+		defer delete(open, obj)
+
+		m := make(map[interface{}]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, err := i.toGo(pair.Key, open)
+			if err != nil {
+				return nil, err
+			}
+			value, err := i.toGo(pair.Value, open)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	case *object.Function, *object.Builtin:
+		return func(args ...interface{}) (interface{}, error) {
+			result, err := i.call(obj, args)
+			if err != nil {
+				return nil, err
+			}
+			return i.ToGo(result)
+		}, nil
+	default:
+		return obj, nil
+	}
+}
+
+// Monkeyの値を指定した型のGoの値に変換する
+func (i *Interpreter) toValue(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t == objectType {
+		return reflect.ValueOf(&obj).Elem(), nil
+	}
+	if t == bigIntType {
+		switch n := obj.(type) {
+		case *object.Integer:
+			return reflect.ValueOf(big.NewInt(n.Value)), nil
+		case *object.BigInt:
+			return reflect.ValueOf(new(big.Int).Set(n.Value)), nil
+		}
+		return reflect.Value{}, typeError(obj, t)
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		goValue, err := i.ToGo(obj)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if goValue == nil {
+			return reflect.Zero(t), nil
+		}
+		v := reflect.ValueOf(goValue)
+		if !v.Type().AssignableTo(t) {
+			return reflect.Value{}, typeError(obj, t)
+		}
+		return v, nil
+	case reflect.Bool:
+		if b, ok := obj.(*object.Boolean); ok {
+			return reflect.ValueOf(b.Value).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if integer, ok := obj.(*object.Integer); ok {
+			v := reflect.New(t).Elem()
+			if v.OverflowInt(integer.Value) {
+				return reflect.Value{}, fmt.Errorf("integer %d overflows %s", integer.Value, t)
+			}
+			v.SetInt(integer.Value)
+			return v, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if integer, ok := obj.(*object.Integer); ok {
+			v := reflect.New(t).Elem()
+			if integer.Value < 0 || v.OverflowUint(uint64(integer.Value)) {
+				return reflect.Value{}, fmt.Errorf("integer %d overflows %s", integer.Value, t)
+			}
+			v.SetUint(uint64(integer.Value))
+			return v, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		// 整数は浮動小数点数の引数に渡せる
+		switch num := obj.(type) {
+		case *object.Float:
+			return reflect.ValueOf(num.Value).Convert(t), nil
+		case *object.Integer:
+			return reflect.ValueOf(float64(num.Value)).Convert(t), nil
+		}
+	case reflect.String:
+		if str, ok := obj.(*object.String); ok {
+			return reflect.ValueOf(str.Value).Convert(t), nil
+		}
+	case reflect.Slice:
+		if _, ok := obj.(*object.Null); ok {
+			return reflect.Zero(t), nil
+		}
+		if array, ok := obj.(*object.Array); ok {
+			v := reflect.MakeSlice(t, len(array.Elements), len(array.Elements))
+			for n, elem := range array.Elements {
+				ev, err := i.toValue(elem, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				v.Index(n).Set(ev)
+			}
+			return v, nil
+		}
+	case reflect.Map:
+		if _, ok := obj.(*object.Null); ok {
+			return reflect.Zero(t), nil
+		}
+		if hash, ok := obj.(*object.Hash); ok {
+			v := reflect.MakeMapWithSize(t, len(hash.Pairs))
+			for _, pair := range hash.Pairs {
+				kv, err := i.toValue(pair.Key, t.Key())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				vv, err := i.toValue(pair.Value, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				v.SetMapIndex(kv, vv)
+			}
+			return v, nil
+		}
+	case reflect.Func:
+		switch obj.(type) {
+		case *object.Function, *object.Builtin:
+			return i.makeFunc(obj, t), nil
+		case *object.Null:
+			return reflect.Zero(t), nil
+		}
+	}
+
+	return reflect.Value{}, typeError(obj, t)
+}
+
+// Monkeyの関数を指定した型のGoの関数にする
+// 値を受け取れるのは最初の戻り値だけで、残りの戻り値はゼロ値になる
+// 呼び出しで起きたエラーは、関数の最後の戻り値がerrorなら返し、そうでなければpanicにする
+func (i *Interpreter) makeFunc(fn object.Object, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		args := make([]interface{}, len(in))
+		for n, v := range in {
+			args[n] = v.Interface()
+		}
+
+		out := make([]reflect.Value, t.NumOut())
+		for n := range out {
+			out[n] = reflect.Zero(t.Out(n))
+		}
+		hasError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+
+		fail := func(err error) []reflect.Value {
+			if !hasError {
+				panic(err)
+			}
+			out[len(out)-1] = reflect.ValueOf(&err).Elem()
+			return out
+		}
+
+		result, err := i.call(fn, args)
+		if err != nil {
+			return fail(err)
+		}
+
+		numValues := len(out)
+		if hasError {
+			numValues--
+		}
+		if numValues > 0 {
+			v, err := i.toValue(result, t.Out(0))
+			if err != nil {
+				return fail(err)
+			}
+			out[0] = v
+		}
+		return out
+	})
+}
+
+// マップのキーを並べる順序 型が違う場合は型名の順にする
+func keyLess(a, b object.Object) bool {
+	switch a := a.(type) {
+	case *object.Integer:
+		if b, ok := b.(*object.Integer); ok {
+			return a.Value < b.Value
+		}
+	case *object.Float:
+		if b, ok := b.(*object.Float); ok {
+			return a.Value < b.Value
+		}
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			return a.Value < b.Value
+		}
+	case *object.Boolean:
+		if b, ok := b.(*object.Boolean); ok {
+			return !a.Value && b.Value
+		}
+	}
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+	return a.Inspect() < b.Inspect()
+}
+
+func typeError(obj object.Object, t reflect.Type) error {
+	return fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
@@ -1,63 +1,159 @@
 package evaluator
 import (
+	"context"
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 
 	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/module"
 	"github.com/kakts/monkey/object"
 )
 
 // Null Object.Booolean値をあらかじめ生成して参照するようにする
 var (
 	NULL = &object.Null{}
-	TRUE = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	TRUE = object.TRUE
+	FALSE = object.FALSE
+
+	// ループの制御
+	BREAK = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
-// ASTノードを評価する
+// 評価器
+// 評価全体で共有する設定と、評価中の状態を持つ
+type Evaluator struct {
+	// 評価の途中経過を受け取る nilならトレースしない
+	Tracer Tracer
+
+	// 評価に課す制限
+	Limits Limits
+
+	// 整数演算の結果がint64に収まらない場合の扱い
+	Overflow object.OverflowPolicy
+
+	running bool
+	ctx     context.Context
+	steps   int64
+	depth   int
+	allocs  int64
+
+	// 読み込んだモジュール (キーはmodule.Key) と読み込み中のモジュール
+	modules   map[string]*object.Module
+	importing module.Stack
+}
+
+func New() *Evaluator {
+	return &Evaluator{Limits: DefaultLimits()}
+}
+
+// ASTノードを既定の設定の評価器で評価する
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+// ASTノードを評価する
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	return e.EvalContext(context.Background(), node, env)
+}
+
+// ASTノードを評価する
+// ctxが取り消されるか期限を過ぎると、評価を中断してエラーを返す
+func (e *Evaluator) EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	return e.run(ctx, func() object.Object {
+		return e.eval(node, env)
+	})
+}
+
+func (e *Evaluator) eval(node ast.Node, env *object.Environment) object.Object {
+	if e.Tracer != nil {
+		e.Tracer.Enter(node, env)
+	}
+
+	var result object.Object
+	if err := e.step(); err != nil {
+		result = err
+	} else {
+		result = e.evalNode(node, env)
+	}
+
+	// エラーに発生位置を記録する
+	// 最も内側のノードで設定された位置を優先し、外側のノードでは上書きしない
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	if e.Tracer != nil {
+		e.Tracer.Exit(node, env, result)
+	}
+
+	return result
+}
+
+func (e *Evaluator) evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		// 文
-		fmt.Println("*ast.Program")
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 	case *ast.ExpressionStatement:
 		// 式 再帰的に評価
-		fmt.Println("*ast.ExpressionStatement")
-		return Eval(node.Expression, env)
+		return e.eval(node.Expression, env)
 	case *ast.PrefixExpression:
 		// 前置詞
-		right := Eval(node.Right, env)
-		return evalPrefixExpression(node.Operator, right)
+		right := e.eval(node.Right, env)
+		if isInterrupt(right) {
+			return right
+		}
+		return e.allocate(e.evalPrefixExpression(node.Operator, right))
 	case *ast.InfixExpression:
 		// 中置
-		left := Eval(node.Left, env)
-		if isError(left) {
+		if node.Operator == "&&" || node.Operator == "||" {
+			return e.evalLogicalExpression(node, env)
+		}
+		left := e.eval(node.Left, env)
+		if isInterrupt(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
-		if isError(right) {
+		right := e.eval(node.Right, env)
+		if isInterrupt(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return e.allocate(e.evalInfixExpression(node.Operator, left, right))
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		val := e.eval(node.ReturnValue, env)
+		if isInterrupt(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
+	case *ast.WhileExpression:
+		return e.evalWhileExpression(node, env)
+	case *ast.ForExpression:
+		return e.evalForExpression(node, env)
+	case *ast.ImportStatement:
+		return e.evalImportStatement(node, env)
+	case *ast.ExportStatement:
+		return e.eval(node.Statement, env)
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
-		if isError(val) {
+		val := e.eval(node.Value, env)
+		if isInterrupt(val) {
 			return val
 		}
 		// 変数の束縛のため、enviromnmentに文字列とオブジェクトを関連づける必要がある
 		env.Set(node.Name.Value, val)
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return e.allocate(&object.Integer{Value: node.Value})
+	case *ast.FloatLiteral:
+		return e.allocate(&object.Float{Value: node.Value})
 	case *ast.Boolean:
 		// プリミティブ値からBooleanオブジェクトのインスタンスを取得する
 		return nativeBoolToBooleanObject(node.Value)
@@ -67,52 +163,67 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return e.allocate(&object.Function{Parameters: params, Env: env, Body: body})
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
+		function := e.eval(node.Function, env)
+		if isInterrupt(function) {
 			return function
 		}
 		// 引数に渡す値の評価
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		args := e.evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isInterrupt(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return e.applyFunction(function, args)
 	case *ast.StringLiteral:
-		return &object.String{Value: node.Value}
+		return e.allocate(&object.String{Value: node.Value})
+	case *ast.InterpolatedString:
+		return e.evalInterpolatedString(node, env)
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		elements := e.evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isInterrupt(elements[0]) {
 			return elements[0]
 		}
 
-		return &object.Array{Elements: elements}
+		return e.allocate(&object.Array{Elements: elements})
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
+		left := e.eval(node.Left, env)
+		if isInterrupt(left) {
 			return left
 		}
 
-		index := Eval(node.Index, env)
-		if isError(index) {
+		index := e.eval(node.Index, env)
+		if isInterrupt(index) {
 			return index
 		}
 
 		return evalIndexExpression(left, index)
+	case *ast.MemberExpression:
+		obj := e.eval(node.Object, env)
+		if isInterrupt(obj) {
+			return obj
+		}
+		if member := object.Member(obj, node.Member.Value); member != nil {
+			return member
+		}
+		return NULL
+	case *ast.SliceExpression:
+		return e.allocate(e.evalSliceExpression(node, env))
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return e.allocate(e.evalHashLiteral(node, env))
+	case *ast.AssignExpression:
+		return e.evalAssignExpression(node, env)
 	}
 
 	return nil
 }
 
-func evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
+func (e *Evaluator) evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range stmts {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
 		// 直近の評価結果がobject.ReturnValueならば評価を中断し、アンラップした値を返す
 		// TODO ネストしたブロック文がある場合は、初出のobject.ReturnValueの値をアンラップしてしまう
@@ -124,10 +235,10 @@ func evalStatements(stmts []ast.Statement, env *object.Environment) object.Objec
 	return result
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -141,18 +252,15 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 }
 
 // ブロック文の評価
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
-		// returnの場合はすぐに返す
-		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
-				return result
-			}
+		// return、break、continueの場合はすぐに返す
+		if isInterrupt(result) {
+			return result
 		}
 	}
 
@@ -167,12 +275,12 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func (e *Evaluator) evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return e.evalMinusPrefixOperatorExpression(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -193,32 +301,44 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 // -前置詞を含む場合の評価
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+func (e *Evaluator) evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	if f, ok := right.(*object.Float); ok {
+		return &object.Float{Value: -f.Value}
+	}
+	if !object.IsInteger(right) {
 		return newError("unknown operator: -%s", right.Type())
 	}
 
-	value := right.(*object.Integer).Value
 	// 正負を反転した上で整数オブジェクトのインスタンスを返す
-	return &object.Integer{Value: -value}
+	return object.NegateInteger(right, e.Overflow)
 }
 
 // 中置式の評価
-func evalInfixExpression (
+func (e *Evaluator) evalInfixExpression (
 	operator string,
 	left, right object.Object,
 ) object.Object {
 	switch {
-	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+	case object.IsInteger(left) && object.IsInteger(right):
 		// 左右どちらも整数の場合
-		return evalIntegerInfixExpression(operator, left, right)
+		return e.evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		// 片方が浮動小数点数なら、もう片方も浮動小数点数にして計算する
+		return evalFloatInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equals(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equals(left, right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		// 連結する前に結果の大きさが制限に収まるか確かめる
+		if operator == "+" {
+			size := len(left.(*object.String).Value) + len(right.(*object.String).Value)
+			if err := e.reserve(int64(size)); err != nil {
+				return err
+			}
+		}
 		return evalStringInfixExpression(operator, left, right)
 	
 	default:
@@ -226,28 +346,84 @@ func evalInfixExpression (
 	}
 }
 
+// && と || の評価
+// 左辺だけで結果が決まる場合は右辺を評価しない (短絡評価)
+// 結果は左右の真偽値としての値から決まる真偽値になる
+func (e *Evaluator) evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.eval(node.Left, env)
+	if isInterrupt(left) {
+		return left
+	}
+
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := e.eval(node.Right, env)
+	if isInterrupt(right) {
+		return right
+	}
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
 // 左右が整数の場合の中置式の評価
-func evalIntegerInfixExpression(
+// ゼロ除算はエラーになり、int64に収まらない結果はe.Overflowに従って扱う
+func (e *Evaluator) evalIntegerInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	switch operator {
+	case "+", "-", "*", "/", "%":
+		return object.IntegerArithmetic(operator, left, right, e.Overflow)
+	case "<":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(object.CompareIntegers(left, right) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// 浮動小数点数を含む中置式の評価
+func evalFloatInfixExpression(
 	operator string,
 	left, right object.Object,
 ) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return &object.Float{Value: leftVal + rightVal}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return &object.Float{Value: leftVal - rightVal}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return &object.Float{Value: leftVal * rightVal}
 	case "/":
-		// ゼロ除算考慮しない
-		return &object.Integer{Value: leftVal / rightVal}
+		// IEEE 754に従い、ゼロ除算は無限大かNaNになる
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		// 余りの符号は左辺と同じになる
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -257,21 +433,105 @@ func evalIntegerInfixExpression(
 	}
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
-	if isError(condition) {
+func isNumber(obj object.Object) bool {
+	return object.IsInteger(obj) || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *object.Float:
+		return obj.Value
+	}
+	return 0
+}
+
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.eval(ie.Condition, env)
+	if isInterrupt(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return e.eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
 		// else ブロック
-		return Eval(ie.Alternative, env)
+		return e.eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
+func (e *Evaluator) evalWhileExpression(we *ast.WhileExpression, env *object.Environment) object.Object {
+	for {
+		condition := e.eval(we.Condition, env)
+		if isInterrupt(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		if result, done := e.evalLoopBody(we.Body, env); done {
+			return result
+		}
+	}
+}
+
+// for-inの評価
+// ループ変数はletと同じく現在の環境に束縛する
+func (e *Evaluator) evalForExpression(fe *ast.ForExpression, env *object.Environment) object.Object {
+	iterable := e.eval(fe.Iterable, env)
+	if isInterrupt(iterable) {
+		return iterable
+	}
+
+	it, err := object.Iterate(iterable)
+	if err != nil {
+		return err
+	}
+
+	// Rangeと文字列は要素を取り出すたびに新しいオブジェクトを生成する
+	fresh := iterable.Type() == object.RANGE_OBJ || iterable.Type() == object.STRING_OBJ
+
+	for {
+		value, ok := it.Next()
+		if !ok {
+			return NULL
+		}
+		if fresh {
+			if err := e.countAllocs(1); err != nil {
+				return err
+			}
+		}
+		env.Set(fe.Variable.Value, value)
+
+		if result, done := e.evalLoopBody(fe.Body, env); done {
+			return result
+		}
+	}
+}
+
+// ループ本体を1回評価する
+// breakならNULL、returnやエラーならその値を返し、doneがtrueになる
+func (e *Evaluator) evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	result := e.eval(body, env)
+	if result == nil {
+		return nil, false
+	}
+
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return NULL, true
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	}
+	return nil, false
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -297,6 +557,20 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// 評価を中断して呼び出し元に伝えるべき値か
+// エラーの他、式の途中で実行された return、break、continue も含む
+// (例: [1, if (x) { continue; }] はcontinueが配列の要素にならずループまで伝わる)
+func isInterrupt(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+	switch obj.Type() {
+	case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
+	}
+	return false
+}
+
 func evalIdentifier(
 	node *ast.Identifier,
 	env *object.Environment,
@@ -308,19 +582,19 @@ func evalIdentifier(
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	return newError("identifier not found: %s", node.Value)
 }
 
-func evalExpressions(
+func (e *Evaluator) evalExpressions(
 	exps []ast.Expression,
 	env *object.Environment,
 ) []object.Object {
 	var result []object.Object
 
 	// ast.Expressionsのリストの要素を現在の環境envのコンテキストで次々に評価する
-	for _, e := range exps {
-		evaluated := Eval(e, env)
-		if isError(evaluated) {
+	for _, exp := range exps {
+		evaluated := e.eval(exp, env)
+		if isInterrupt(evaluated) {
 			// エラーが発生したら評価を中止してエラーを返す
 			return []object.Object{evaluated}
 		}
@@ -331,20 +605,66 @@ func evalExpressions(
 	return result
 }
 
+// 関数オブジェクトを引数に適用する
+// ホスト側のGoのコードから関数を呼び出すために使う
+func (e *Evaluator) Apply(fn object.Object, args []object.Object) object.Object {
+	return e.run(context.Background(), func() object.Object {
+		return e.applyFunction(fn, args)
+	})
+}
+
 // 関数適用
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args))
+		}
+
+		// 呼び出しが深くなりすぎるとGoのスタックを使い果たすため、深さを制限する
+		if e.Limits.MaxDepth > 0 && e.depth >= e.Limits.MaxDepth {
+			return newError("stack overflow")
+		}
+		e.depth++
+		defer func() { e.depth-- }()
+
+		// 関数の環境も1つのオブジェクトとして数える
+		if err := e.countAllocs(1); err != nil {
+			return err
+		}
+
 		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
+		evaluated := e.eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		if err := e.checkArguments(args); err != nil {
+			return err
+		}
+		// 大きな文字列を作る組み込み関数は、作る前に大きさを確かめる
+		if fn.Size != nil {
+			if err := e.reserve(1 + fn.Size(args...)); err != nil {
+				return err
+			}
+		}
+		// 戻り値の無い組み込み関数はnilを返す
+		if result := fn.Call(e.callFunction, args...); result != nil {
+			return e.allocate(object.ApplyOverflowPolicy(result, e.Overflow))
+		}
+		return NULL
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
+// 組み込み関数から関数を呼び出す
+func (e *Evaluator) callFunction(fn object.Object, args ...object.Object) object.Object {
+	result := e.applyFunction(fn, args)
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
 // 関数に渡す環境の拡張 
 // 新しい*object.Environment環境を作る
 func extendFunctionEnv(
@@ -384,9 +704,23 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 
 // 配列インデックスの評価
 func evalIndexExpression(left, index object.Object) object.Object {
+	if left.Type() == object.ARRAY_OBJ || left.Type() == object.STRING_OBJ || left.Type() == object.RANGE_OBJ {
+		index = object.IndexInteger(index)
+		if isError(index) {
+			return index
+		}
+	}
+
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.RANGE_OBJ && index.Type() == object.INTEGER_OBJ:
+		if elem, ok := left.(*object.Range).At(index.(*object.Integer).Value); ok {
+			return elem
+		}
+		return NULL
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -395,12 +729,13 @@ func evalIndexExpression(left, index object.Object) object.Object {
 }
 
 // ハッシュリテラルの評価
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
-		if isError(key) {
+	for _, keyNode := range node.Keys {
+		valueNode := node.Pairs[keyNode]
+		key := e.eval(keyNode, env)
+		if isInterrupt(key) {
 			return key
 		}
 
@@ -411,31 +746,186 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 		}
 
 		// valueNodeの評価
-		value := Eval(valueNode, env)
-		if isError(value) {
+		value := e.eval(valueNode, env)
+		if isInterrupt(value) {
 			return value
 		}
 
 		hashed := hashKey.HashKey()
 		// hashedというHashKey対して、HashPairを割り当てる
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashed, object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
+// 負の添字は末尾から数える
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
-
-	if idx < 0 || idx > max {
+	idx, ok := object.NormalizeIndex(index.(*object.Integer).Value, int64(len(arrayObject.Elements)))
+	if !ok {
 		return NULL
 	}
 
 	return arrayObject.Elements[idx]
 }
 
+// スライス式の評価 省略した位置はnullとして渡す
+func (e *Evaluator) evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := e.eval(node.Left, env)
+	if isInterrupt(left) {
+		return left
+	}
+
+	bounds := []object.Object{NULL, NULL}
+	for i, exp := range []ast.Expression{node.Low, node.High} {
+		if exp == nil {
+			continue
+		}
+		bounds[i] = e.eval(exp, env)
+		if isInterrupt(bounds[i]) {
+			return bounds[i]
+		}
+	}
+
+	return object.Slice(left, bounds[0], bounds[1])
+}
+
+// 埋め込み式を含む文字列の評価
+// 埋め込み式の値はputsと同じくInspectの表現で文字列に埋め込む
+func (e *Evaluator) evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+
+	for _, part := range node.Parts {
+		val := e.eval(part, env)
+		if isInterrupt(val) {
+			return val
+		}
+		out.WriteString(val.Inspect())
+		if err := e.reserve(int64(out.Len())); err != nil {
+			return err
+		}
+	}
+
+	return e.allocate(&object.String{Value: out.String()})
+}
+
+// 文字列の添字はコードポイント単位で数える 負の添字は末尾から数える
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	ch, ok := str.(*object.String).CharAt(index.(*object.Integer).Value)
+	if !ok {
+		return NULL
+	}
+	return ch
+}
+
+// 代入式の評価
+// 識別子への代入は束縛が定義された環境まで外側を辿って書き換える
+// 複合代入 (x += 1 など) は現在の値を読んでから右辺を評価し、演算結果を代入する
+func (e *Evaluator) evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	compound := node.Operator != "="
+	operator := strings.TrimSuffix(node.Operator, "=")
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		var current object.Object
+		if compound {
+			current = evalIdentifier(target, env)
+			if isInterrupt(current) {
+				return current
+			}
+		}
+
+		val := e.eval(node.Value, env)
+		if isInterrupt(val) {
+			return val
+		}
+
+		if compound {
+			val = e.allocate(e.evalInfixExpression(operator, current, val))
+			if isInterrupt(val) {
+				return val
+			}
+		}
+
+		if _, ok := env.Assign(target.Value, val); !ok {
+			if _, ok := builtins[target.Value]; ok {
+				return newError("cannot assign to builtin: %s", target.Value)
+			}
+			return newError("identifier not found: %s", target.Value)
+		}
+		return val
+
+	case *ast.IndexExpression:
+		left := e.eval(target.Left, env)
+		if isInterrupt(left) {
+			return left
+		}
+
+		index := e.eval(target.Index, env)
+		if isInterrupt(index) {
+			return index
+		}
+
+		var current object.Object
+		if compound {
+			current = evalIndexExpression(left, index)
+			if isInterrupt(current) {
+				return current
+			}
+		}
+
+		val := e.eval(node.Value, env)
+		if isInterrupt(val) {
+			return val
+		}
+
+		if compound {
+			val = e.allocate(e.evalInfixExpression(operator, current, val))
+			if isInterrupt(val) {
+				return val
+			}
+		}
+
+		return evalIndexAssignment(left, index, val)
+
+	default:
+		return newError("cannot assign to %s", node.Target.String())
+	}
+}
+
+// 添字を指定した代入 arr[i] = v, hash[k] = v
+// 配列は範囲内の添字のみ書き換えられる 負の添字は末尾から数える
+func evalIndexAssignment(left, index, val object.Object) object.Object {
+	if left.Type() == object.ARRAY_OBJ {
+		index = object.IndexInteger(index)
+		if isError(index) {
+			return index
+		}
+	}
+
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		arrayObject := left.(*object.Array)
+		idx, ok := object.NormalizeIndex(index.(*object.Integer).Value, int64(len(arrayObject.Elements)))
+		if !ok {
+			return newError("index out of range: %d", index.(*object.Integer).Value)
+		}
+		arrayObject.Elements[idx] = val
+	case left.Type() == object.HASH_OBJ:
+		hashObject := left.(*object.Hash)
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		hashObject.Set(key.HashKey(), object.HashPair{Key: index, Value: val})
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+
+	return val
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
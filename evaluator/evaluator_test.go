@@ -29,6 +29,10 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"10 % 3", 1},
+		{"-10 % 3", -1},
+		{"10 % -3", 1},
+		{"2 + 7 % 4 * 2", 8},
 	}
 
 	for _, tt := range tests {
@@ -62,6 +66,42 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 / 4.0", 2.5},
+		{"2 * 1.25 - 1", 1.5},
+		{"1e3 / 10", 100},
+		{"5.5 % 2", 1.5},
+		{"-5.5 % 2", -1.5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input string
@@ -86,6 +126,44 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"0.1 + 0.2 == 0.3", false},
+		{"2.5 != 2.5", false},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"1" == 1`, false},
+		{`"1" != 1`, true},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[1, 2] != [2, 1]", true},
+		{"[1, 2.0] == [1.0, 2]", true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`{} == []`, false},
+		{"if (false) { 1 } == if (false) { 2 }", true},
+		{"if (false) { 1 } == false", false},
+		{"let f = fn() { 1 }; f == f", true},
+		{"fn() { 1 } == fn() { 1 }", false},
+		{"len == len", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 <= 1", false},
+		{"2 >= 1.5", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && 0", true},
+		{"if (false) { 1 } || 0", true},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
 	}
 
 	for _, tt := range tests {
@@ -247,6 +325,62 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"10 / (5 - 5)",
+			"division by zero",
+		},
+		{
+			"10 % 0",
+			"division by zero",
+		},
+		{
+			`"a" <= "b"`,
+			"unknown operator: STRING <= STRING",
+		},
+		{
+			"true && foobar",
+			"identifier not found: foobar",
+		},
+		{
+			"for (x in 5) { x }",
+			"not iterable: INTEGER",
+		},
+		{
+			`"value: ${foobar}"`,
+			"identifier not found: foobar",
+		},
+		{
+			"range(1, 2, 0)",
+			"`range` step must not be zero",
+		},
+		{
+			"x = 1",
+			"identifier not found: x",
+		},
+		{
+			"x += 1",
+			"identifier not found: x",
+		},
+		{
+			"len = 1",
+			"cannot assign to builtin: len",
+		},
+		{
+			"let arr = [1]; arr[1] = 2",
+			"index out of range: 1",
+		},
+		{
+			`let s = "abc"; s[0] = "x"`,
+			"index assignment not supported: STRING",
+		},
+		{
+			`let h = {}; h[fn() {}] = 1`,
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			`let x = "a"; x -= 1`,
+			"type mismatch: STRING - INTEGER",
+		},
 	}
 
 	for _, tt := range tests {
@@ -264,6 +398,111 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+// && と || は不要な右辺を評価しない
+func TestShortCircuitEvaluation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"false && foobar", false},
+		{"true || foobar", true},
+		{"let f = fn() { 1 / 0 }; false && f()", false},
+		{"let f = fn() { 1 / 0 }; 1 || f()", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = 5", 5},
+		{"let x = 1; x += 2; x", 3},
+		{"let x = 10; x -= 2; x *= 3; x /= 4; x %= 4; x", 2},
+		{"let a = 1; let b = 2; a = b = 3; a + b", 6},
+		{"let x = 1; let f = fn() { x = 10 }; f(); x", 10},
+		{"let x = 1; let f = fn(x) { x = 10 }; f(0); x", 1},
+		{"let counter = fn() { let c = 0; fn() { c += 1 } }; let f = counter(); f(); f(); f()", 3},
+		{"let arr = [1, 2, 3]; arr[1] = 20; arr[1]", 20},
+		{"let arr = [1, 2, 3]; arr[2] *= 5; arr[2]", 15},
+		{"let a = [1]; let b = a; b[0] = 9; a[0]", 9},
+		{`let h = {"a": 1}; h["a"] += 1; h["b"] = 5; h["a"] + h["b"]`, 7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// 自分自身を含む配列やハッシュを作っても、表示や比較でプロセスが止まらない
+func TestSelfReferencingContainers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [0]; a[0] = a; a", "[[...]]"},
+		{`let h = {}; h["h"] = h; h`, "{h: {...}}"},
+		{`let a = [1]; let h = {"a": a}; a[0] = h; [a, h]`, "[[{a: [...]}], {a: [{...}]}]"},
+		{"let a = [0]; a[0] = a; flatten(a, 100)", "[[[...]]]"},
+		{`let a = [0]; a[0] = a; "${a}"`, "[[...]]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { i += 1 }; i", 5},
+		{"while (false) { 1 }", nil},
+		{"let i = 0; while (true) { i += 1; if (i == 3) { break; } }; i", 3},
+		{"let s = 0; let i = 0; while (i < 10) { i += 1; if (i % 2 == 0) { continue; } s += i }; s", 25},
+		{"let s = 0; for (x in [1, 2, 3]) { s += x }; s", 6},
+		{"let s = 0; for (x in range(5)) { s += x }; s", 10},
+		{"let s = 0; for (x in range(2, 10, 3)) { s += x }; s", 15},
+		{"let s = 0; for (x in range(3, 0, -1)) { s = s * 10 + x }; s", 321},
+		{`let s = ""; for (c in "abc") { s = c + s }; len(s)`, 3},
+		{`let n = 0; for (k in {"a": 1, "b": 2}) { n += 1 }; n`, 2},
+		{"for (x in [1, 2, 3]) { x }; x", 3},
+		{"let f = fn() { for (x in range(10)) { if (x == 4) { return x; } } }; f()", 4},
+		{"let n = 0; for (i in range(3)) { for (j in range(3)) { if (j == 1) { break; } n += 1 } }; n", 3},
+		{"let r = 0; for (i in range(3)) { r += [1, if (i == 1) { continue; } else { i }][0] }; r", 2},
+		// rangeは配列と同じく長さ、添字、sort、reverse、==を使える
+		{"len(range(3))", 3},
+		{"len(range(10, 0, -3))", 4},
+		{"range(0, 10, 2)[1]", 2},
+		{"range(0, 10, 2)[-1]", 8},
+		{"range(3)[3]", nil},
+		{"sort(range(3), fn(a, b) { a > b })[0]", 2},
+		{"reverse(range(3))[0]", 2},
+		{"if (range(3) == range(0, 3)) { 1 } else { 0 }", 1},
+		{"if (range(3) == range(4)) { 1 } else { 0 }", 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input string
@@ -348,6 +587,69 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "Monkey"; "hello ${name}!"`, "hello Monkey!"},
+		{`"${1 + 2} ${1.5} ${true} ${[1, 2]}"`, "3 1.5 true [1, 2]"},
+		{`"${if (false) { 1 }}"`, "null"},
+		{`"a ${"b ${"c"}"}"`, "a b c"},
+		{`let f = fn(x) { "x=${x}" }; f(5)`, "x=5"},
+		{`"tab\tquote\" \${x} \u{1F412}"`, "tab\tquote\" ${x} 🐒"},
+		{"`raw\n${x}`", "raw\n${x}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. want=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+}
+
+// 文字列はコードポイント単位で数える
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"abc"[0]`, "a"},
+		{`"abc"[2]`, "c"},
+		{`"日本語"[1]`, "本"},
+		{`let 名前 = "モンキー"; 名前[3]`, "ー"},
+		{`"héllo"[1]`, "é"},
+		{`"abc"[3]`, nil},
+		{`"abc"[-1]`, "c"},
+		{`"日本語"[-3]`, "日"},
+		{`"abc"[-4]`, nil},
+		{`""[0]`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. want=%q, got=%q", expected, str.Value)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 // 組み込み関数のテスト
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
@@ -357,8 +659,44 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("日本")`, 2},
+		{`len("🐒 monkey")`, 8},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`floor(3.7)`, 3},
+		{`floor(-3.2)`, -4},
+		{`ceil(3.2)`, 4},
+		{`round(2.5)`, 3},
+		{`round(-2.5)`, -3},
+		{`floor(7)`, 7},
+		{`floor("a")`, "argument to `floor` must be INTEGER or FLOAT, got STRING"},
+		{`floor(1e300)`, "argument to `floor` out of range: 1e+300"},
+		{`pow(2, 10)`, 1024},
+		{`pow(2, "a")`, "argument to `pow` must be INTEGER or FLOAT, got STRING"},
+		{`sqrt(16)`, 4.0},
+		{`sqrt(2.25)`, 1.5},
+		{`pow(2, -1)`, 0.5},
+		{`pow(4, 0.5)`, 2.0},
+		{`len(split("a b c"))`, 3},
+		{`index_of("monkey", "key")`, 3},
+		{`int("42") + parse_int("ff", 16)`, 297},
+		{`len(format("%5d", 1))`, 5},
+		{`upper(1)`, "argument to `upper` must be STRING, got INTEGER"},
+		{`reduce(map([1, 2, 3], fn(x) { x * x }), fn(a, b) { a + b })`, 14},
+		{`len(filter(range(10), fn(x) { x % 2 == 0 }))`, 5},
+		{`let total = 0; each([1, 2, 3], fn(x) { total += x }); total`, 6},
+		{`sort([3, 1, 2], fn(a, b) { a > b })[0]`, 3},
+		{`let f = fn(x) { return x + 1; 0 }; map([1], f)[0]`, 2},
+		{`map([1], fn(x) { x + true })`, "type mismatch: INTEGER + BOOLEAN"},
+		{`map([1], 1)`, "argument to `map` must be FUNCTION, got INTEGER"},
+		{`len({"a": 1, "b": 2})`, 2},
+		{`let h = {"a": 1}; let g = set(h, "b", 2); len(h) + len(g)`, 3},
+		{`len(delete({"a": 1, "b": 2}, "a"))`, 1},
+		{`merge({"a": 1}, {"a": 5})["a"]`, 5},
+		{`reduce(values({"a": 1, "b": 2}), fn(a, b) { a + b })`, 3},
+		{`keys([])`, "argument to `keys` must be HASH, got ARRAY"},
+		{`has({}, fn(x) { x })`, "unusable as hash key: FUNCTION"},
+		{`join([1])`, "elements of `join` must be STRING, got INTEGER"},
 	}
 	
 	for _, tt := range tests {
@@ -367,6 +705,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		switch expected := tt.expected.(type) {
 		case int:
 			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
 		case string:
 			errObj, ok := evaluated.(*object.Error)
 			if !ok {
@@ -441,6 +781,14 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
 	}
@@ -456,6 +804,49 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // 結果のInspect
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4][2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][-2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4][-100:100]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][3:1]", "[]"},
+		{"[][0:1]", "[]"},
+		{"let a = [1, 2, 3]; let n = 1; a[n:n + 1]", "[2]"},
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a", "[1, 2, 3]"},
+		{`"hello"[1:3]`, "el"},
+		{`"日本語です"[1:-1]`, "本語で"},
+		{`"hello"[:-3]`, "he"},
+		{`"hello"[5:]`, ""},
+		{`[1, 2, 3][if (false) { 1 }:2]`, "[1, 2]"},
+		{"[1, 2][1.5:]", "ERROR: slice index must be INTEGER, got FLOAT"},
+		{`[1, 2]["a":]`, "ERROR: slice index must be INTEGER, got STRING"},
+		{`{"a": 1}[0:1]`, "ERROR: slice operator not supported: HASH"},
+		{"5[:1]", "ERROR: slice operator not supported: INTEGER"},
+		{"let a = [1, 2, 3]; a[-1] = 9; a", "[1, 2, 9]"},
+		{"let a = [1, 2, 3]; a[-2] += 5; a", "[1, 7, 3]"},
+		{"let a = [1, 2, 3]; a[-4] = 9", "ERROR: index out of range: -4"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		got := evaluated.Inspect()
+		if err, ok := evaluated.(*object.Error); ok {
+			got = "ERROR: " + err.Message
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -496,6 +887,29 @@ func TestHashLiterals(t *testing.T) {
 	}
 }
 
+// ハッシュはキーを追加した順に表示・反復する
+func TestHashOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"z": 1, "a": 2, 10: 3, 2: 4, true: 5}`, "{z: 1, a: 2, 10: 3, 2: 4, true: 5}"},
+		{`{"a": 1, "b": 2, "a": 3}`, "{a: 3, b: 2}"},
+		{`let h = {"b": 1}; h["a"] = 2; h["b"] = 3; h`, "{b: 3, a: 2}"},
+		{`let h = {"c": 1, "a": 2, "b": 3}; keys(h)`, "[c, a, b]"},
+		{`let out = []; for (k in {"y": 1, "x": 2}) { out = push(out, k) }; out`, "[y, x]"},
+		{`merge({"b": 1, "a": 2}, {"c": 3, "b": 4})`, "{b: 4, a: 2, c: 3}"},
+		{`set(delete({"a": 1, "b": 2}, "a"), "a", 3)`, "{b: 2, a: 3}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input string
@@ -541,4 +955,102 @@ func TestHashIndexExpressions(t *testing.T) {
 			testNullObject(t, evaluated)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // 結果のInspect エラーの場合は "ERROR: " とメッセージ
+	}{
+		{`let h = {"name": "monkey", "age": 3}; h.name`, "monkey"},
+		{`let h = {"name": "monkey"}; h.missing`, "null"},
+		{`{"inner": {"x": 1}}.inner.x`, "1"},
+		{`let h = {"keys": 1}; h.keys`, "1"},
+		{`{"b": 1, "a": 2}.keys()`, "[b, a]"},
+		{`{"a": 1}.has("a")`, "true"},
+		{`"hello".upper()`, "HELLO"},
+		{`"a,b,c".split(",").join("-")`, "a-b-c"},
+		{`"日本語".len()`, "3"},
+		{`[1, 2].push(3)`, "[1, 2, 3]"},
+		{`[1, 2, 3].map(fn(x) { x * 2 }).filter(fn(x) { x > 2 })`, "[4, 6]"},
+		{`[1, 2, 3].reduce(fn(a, b) { a + b }, 10)`, "16"},
+		{`let arr = [3, 1, 2]; arr.sort(); arr`, "[3, 1, 2]"},
+		{`let upper = "abc".upper; upper()`, "ABC"},
+		{`"abc".foo()`, "ERROR: unknown method foo for STRING"},
+		{`5.foo`, "ERROR: member access not supported: INTEGER"},
+		{`let h = {}; h.x.y`, "ERROR: member access not supported: NULL"},
+		{`"abc".upper(1)`, "ERROR: wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		got := evaluated.Inspect()
+		if errObj, ok := evaluated.(*object.Error); ok {
+			got = "ERROR: " + errObj.Message
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// エラーの発生位置のテスト
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		expectedPos string
+	}{
+		{"foobar", "1:1"},
+		{"let a = 1;\nlet b = a + c;", "2:13"},
+		{"let f = fn(x) {\n  x + true\n};\nf(1)", "2:3"},
+		{"5;\n  -true", "2:3"},
+		{"len(1, 2)", "1:1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error position for %q. expected=%s, got=%s", tt.input, tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		policy   object.OverflowPolicy
+		expected string
+	}{
+		{"9223372036854775807 + 1", object.OverflowWrap, "-9223372036854775808"},
+		{"9223372036854775807 + 1", object.OverflowError, "ERROR: 1:1: integer overflow: 9223372036854775807 + 1"},
+		{"9223372036854775807 + 1", object.OverflowPromote, "9223372036854775808"},
+		{"pow(2, 100)", object.OverflowPromote, "1267650600228229401496703205376"},
+		{"pow(2, 100)", object.OverflowError, "ERROR: 1:1: integer overflow: 1267650600228229401496703205376"},
+		{"pow(2, 64)", object.OverflowWrap, "0"},
+		{"pow(2, 100) / pow(2, 99)", object.OverflowPromote, "2"},
+		{"pow(3, 100000000)", object.OverflowPromote, "ERROR: 1:1: result of `pow` too large: 3 ** 100000000"},
+		// int64に収まらない添字は範囲外
+		{"[1, 2][pow(2, 64)]", object.OverflowPromote, "ERROR: 1:1: index out of range: 18446744073709551616"},
+		{`"ab"[-pow(2, 64)]`, object.OverflowPromote, "ERROR: 1:1: index out of range: -18446744073709551616"},
+		{"let a = [1]; a[pow(2, 64)] = 2", object.OverflowPromote, "ERROR: 1:14: index out of range: 18446744073709551616"},
+		{"range(pow(2, 64))", object.OverflowPromote, "ERROR: 1:1: argument to `range` out of range: 18446744073709551616"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		e := New()
+		e.Overflow = tt.policy
+
+		if got := e.Eval(program, object.NewEnvironment()).Inspect(); got != tt.expected {
+			t.Errorf("%q (%s): want=%s, got=%s", tt.input, tt.policy, tt.expected, got)
+		}
+	}
+}
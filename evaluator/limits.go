@@ -0,0 +1,162 @@
+package evaluator
+
+import (
+	"context"
+	"time"
+
+	"github.com/kakts/monkey/object"
+)
+
+// 評価に課す制限
+// 0の項目は制限しない
+type Limits struct {
+	MaxSteps  int64         // 評価するノードの数
+	MaxDepth  int           // 関数呼び出しのネストの深さ
+	MaxAllocs int64         // 生成するオブジェクトの数 配列とハッシュは要素の数、文字列はバイト数も含める
+	Timeout   time.Duration // 1回の評価にかけられる時間
+}
+
+// 関数呼び出しの深さの既定の上限
+// vmのフレーム数の上限と揃えている
+const DefaultMaxDepth = 1024
+
+// New()が使う既定の制限
+// 無限再帰でホストのプロセスが落ちないよう、呼び出しの深さだけを制限する
+func DefaultLimits() Limits {
+	return Limits{MaxDepth: DefaultMaxDepth}
+}
+
+// contextの取り消しを確認する間隔 (ステップ数)
+const ctxCheckInterval = 1024
+
+// これ以上の大きさの値を作る場合は、間隔によらずその場でcontextの取り消しを確認する
+// 文字列の連結を繰り返すと、少ないステップで大量のメモリを使うため
+const largeAlloc = 4096
+
+// 評価を1回分実行する
+// 最も外側の呼び出しでカウンタを初期化し、contextと時間制限を設定する
+// 評価中に (ホストの関数などから) 入れ子で呼ばれた場合は、同じ制限を共有する
+func (e *Evaluator) run(ctx context.Context, fn func() object.Object) object.Object {
+	if e.running {
+		return fn()
+	}
+
+	if e.Limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Limits.Timeout)
+		defer cancel()
+	}
+
+	e.running = true
+	e.ctx = ctx
+	e.steps, e.depth, e.allocs = 0, 0, 0
+	defer func() {
+		e.running = false
+		e.ctx = nil
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+	return fn()
+}
+
+// ノードを1つ評価するごとに呼び、制限を超えていればエラーを返す
+func (e *Evaluator) step() *object.Error {
+	e.steps++
+	if e.Limits.MaxSteps > 0 && e.steps > e.Limits.MaxSteps {
+		return newError("step limit exceeded: %d", e.Limits.MaxSteps)
+	}
+
+	if e.steps%ctxCheckInterval == 0 {
+		return e.checkContext()
+	}
+	return nil
+}
+
+func (e *Evaluator) checkContext() *object.Error {
+	if e.ctx != nil {
+		if err := e.ctx.Err(); err != nil {
+			return contextError(err)
+		}
+	}
+	return nil
+}
+
+// 大きさnの値を作る前に、制限を超えないか確かめる
+// 数えるのは値を作った後のallocateで行う
+func (e *Evaluator) reserve(n int64) *object.Error {
+	if e.Limits.MaxAllocs > 0 && e.allocs+n > e.Limits.MaxAllocs {
+		return newError("allocation limit exceeded: %d", e.Limits.MaxAllocs)
+	}
+	if n >= largeAlloc {
+		return e.checkContext()
+	}
+	return nil
+}
+
+// 新しく生成したオブジェクトを数える
+// 制限を超えた場合はオブジェクトの代わりにエラーを返す
+func (e *Evaluator) allocate(obj object.Object) object.Object {
+	if err := e.countAllocs(allocCost(obj)); err != nil {
+		return err
+	}
+	return obj
+}
+
+func (e *Evaluator) countAllocs(n int64) *object.Error {
+	if n >= largeAlloc {
+		if err := e.checkContext(); err != nil {
+			return err
+		}
+	}
+	if e.Limits.MaxAllocs <= 0 {
+		return nil
+	}
+
+	e.allocs += n
+	if e.allocs > e.Limits.MaxAllocs {
+		return newError("allocation limit exceeded: %d", e.Limits.MaxAllocs)
+	}
+	return nil
+}
+
+// オブジェクトを生成した数
+// 配列とハッシュは要素の数、文字列はバイト数、任意精度の整数はワード数も数える
+// 使い回している真偽値とNULL、エラーは数えない
+func allocCost(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case nil, *object.Error:
+		return 0
+	case *object.Boolean, *object.Null:
+		return 0
+	case *object.String:
+		return 1 + int64(len(obj.Value))
+	case *object.BigInt:
+		return 1 + int64(len(obj.Value.Bits()))
+	case *object.Array:
+		return 1 + int64(len(obj.Elements))
+	case *object.Hash:
+		return 1 + int64(len(obj.Pairs))
+	default:
+		return 1
+	}
+}
+
+// 大きな値を受け取る組み込み関数 (pushなど) は値の大きさに比例して時間がかかるため、
+// 呼び出す前にcontextの取り消しを確認する
+func (e *Evaluator) checkArguments(args []object.Object) *object.Error {
+	for _, arg := range args {
+		if allocCost(arg) >= largeAlloc {
+			return e.checkContext()
+		}
+	}
+	return nil
+}
+
+func contextError(err error) *object.Error {
+	if err == context.DeadlineExceeded {
+		return newError("execution timed out")
+	}
+	return newError("execution canceled: %s", err)
+}
@@ -0,0 +1,142 @@
+package evaluator
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/object"
+	"github.com/kakts/monkey/parser"
+)
+
+func testEvalWithLimits(ctx context.Context, input string, limits Limits) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	e := New()
+	e.Limits = limits
+	return e.EvalContext(ctx, program, object.NewEnvironment())
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		limits   Limits
+		expected string
+	}{
+		{"let f = fn(x) { f(x) }; f(1)", DefaultLimits(), "stack overflow"},
+		{"let f = fn(x) { f(x) }; f(1)", Limits{MaxDepth: 10}, "stack overflow"},
+		{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(9)", Limits{MaxDepth: 10}, ""},
+		{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(10)", Limits{MaxDepth: 10}, "stack overflow"},
+		{"1 + 2 + 3", Limits{MaxSteps: 7}, ""},
+		{"1 + 2 + 3", Limits{MaxSteps: 6}, "step limit exceeded: 6"},
+		{"let f = fn(x) { f(x) }; f(1)", Limits{MaxSteps: 1000}, "step limit exceeded: 1000"},
+		{"[1, 2, 3]", Limits{MaxAllocs: 7}, ""},
+		{"[1, 2, 3]", Limits{MaxAllocs: 6}, "allocation limit exceeded: 6"},
+		{`let s = "a"; let f = fn(s, n) { if (n == 0) { s } else { f(s + s, n - 1) } }; f(s, 100)`,
+			Limits{MaxAllocs: 100}, "allocation limit exceeded: 100"},
+		{`let f = fn(a) { f(push(a, 1)) }; f([])`, Limits{MaxAllocs: 1000}, "allocation limit exceeded: 1000"},
+		{"let f = fn(x) { f(x) }; f(1)", Limits{Timeout: time.Millisecond}, "execution timed out"},
+		// 文字列は長さも数えるので、少ないステップで大きくなる文字列も止まる
+		{`"abc" + "def"`, Limits{MaxAllocs: 15}, ""},
+		{`"abc" + "def"`, Limits{MaxAllocs: 14}, "allocation limit exceeded: 14"},
+		{`let s = "a"; while (true) { s = s + s }`, Limits{MaxAllocs: 1 << 20, Timeout: 200 * time.Millisecond},
+			"allocation limit exceeded: 1048576"},
+		{`let s = "a"; while (true) { s += s }`, Limits{MaxAllocs: 1 << 20}, "allocation limit exceeded: 1048576"},
+		{`let s = "a"; while (true) { s = "${s}${s}" }`, Limits{MaxAllocs: 1 << 20}, "allocation limit exceeded: 1048576"},
+		{`let s = "a"; while (true) { s = repeat(s, 2) }`, Limits{MaxAllocs: 1 << 20}, "allocation limit exceeded: 1048576"},
+		{`let s = "a"; while (true) { s = s + s }`, Limits{Timeout: 10 * time.Millisecond}, "execution timed out"},
+		// 1回の呼び出しで大きな文字列を作る組み込み関数は、作る前に制限を確かめる
+		{`repeat("ab", 16777216)`, Limits{MaxAllocs: 1000000}, "allocation limit exceeded: 1000000"},
+		{`repeat("ab", 1000)`, Limits{MaxAllocs: 2010}, ""},
+		{`let a = repeat("a", 4000); replace(a, "a", a)`, Limits{MaxAllocs: 1000000}, "allocation limit exceeded: 1000000"},
+		{`let a = repeat("a", 600000); join([a, a, a])`, Limits{MaxAllocs: 1000000}, "allocation limit exceeded: 1000000"},
+	}
+
+	for _, tt := range tests {
+		// Timeout以外のケースで深い再帰が終わらなくならないよう、深さは常に制限する
+		if tt.limits.MaxDepth == 0 && tt.limits.Timeout == 0 {
+			tt.limits.MaxDepth = DefaultMaxDepth
+		}
+
+		evaluated := testEvalWithLimits(context.Background(), tt.input, tt.limits)
+		errObj, isErr := evaluated.(*object.Error)
+
+		if tt.expected == "" {
+			if isErr {
+				t.Errorf("%q: unexpected error: %s", tt.input, errObj.Message)
+			}
+			continue
+		}
+		if !isErr {
+			t.Errorf("%q: no error returned. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("%q: wrong error message. want=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
+
+// 制限を超える組み込み関数の結果は作らずにエラーにする
+func TestBuiltinResultReserved(t *testing.T) {
+	inputs := []string{
+		`repeat("ab", 16777216)`,
+		`let a = repeat("a", 4096); replace(a, "a", a)`,
+		`let a = repeat("a", 4096); join(split(repeat(",", 1000), ","), a)`,
+	}
+
+	for _, input := range inputs {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		evaluated := testEvalWithLimits(context.Background(), input, Limits{MaxAllocs: 100000})
+		runtime.ReadMemStats(&after)
+
+		if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "allocation limit exceeded: 100000" {
+			t.Errorf("%q: no allocation limit error. got=%T", input, evaluated)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("%q: allocated %d bytes before reporting the limit", input, allocated)
+		}
+	}
+}
+
+func TestEvalContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	evaluated := testEvalWithLimits(ctx, "1", DefaultLimits())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error returned. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "execution canceled: context canceled" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// 深さを無制限にしても、contextの期限で止まる
+	start := time.Now()
+	evaluated = testEvalWithLimits(ctx, "let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) + f(n - 1) } }; f(40)", Limits{})
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "execution timed out" {
+		t.Errorf("evaluation was not stopped by the deadline. got=%+v", evaluated)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("evaluation took too long to stop: %s", elapsed)
+	}
+}
+
+func TestLimitsResetBetweenEvaluations(t *testing.T) {
+	e := New()
+	e.Limits = Limits{MaxSteps: 10}
+	env := object.NewEnvironment()
+
+	for i := 0; i < 3; i++ {
+		program := parser.New(lexer.New("1 + 2")).ParseProgram()
+		if evaluated := e.Eval(program, env); isError(evaluated) {
+			t.Fatalf("evaluation %d failed: %s", i, evaluated.Inspect())
+		}
+	}
+}
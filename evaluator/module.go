@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/module"
+	"github.com/kakts/monkey/object"
+)
+
+// import文の評価
+// 同じファイルは一度だけ評価し、以降のimportでは同じモジュールを使う
+func (e *Evaluator) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	importer := node.Token.Pos.Filename
+	path := module.Resolve(importer, node.Path.Value)
+
+	// メインのファイルも読み込み中として、モジュールからのimportを循環として検出する
+	if e.importing.Empty() && importer != "" {
+		e.importing.Push(importer)
+		defer e.importing.Pop()
+	}
+
+	mod := e.loadModule(path, node.Names)
+	if isError(mod) {
+		return mod
+	}
+
+	if node.Alias != nil {
+		env.Set(node.Alias.Value, mod)
+		return nil
+	}
+
+	// exportされていない名前があれば、どの名前も束縛しない
+	values := make([]object.Object, len(node.Names))
+	for i, name := range node.Names {
+		values[i] = object.Member(mod, name.Value)
+		if isError(values[i]) {
+			return values[i]
+		}
+	}
+	for i, name := range node.Names {
+		env.Set(name.Value, values[i])
+	}
+	return nil
+}
+
+// モジュールを読み込み、独立した環境で評価する
+// namesは選択的importの名前 exportされていない名前があればモジュールを評価せずにエラーを返す
+func (e *Evaluator) loadModule(path string, names []*ast.Identifier) object.Object {
+	key := module.Key(path)
+	if mod, ok := e.modules[key]; ok {
+		return mod
+	}
+
+	if err := e.importing.Push(path); err != nil {
+		return newError("%s", err)
+	}
+	defer e.importing.Pop()
+
+	program, err := module.Parse(path)
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	exports := module.Exports(program)
+	for _, name := range names {
+		if !contains(exports, name.Value) {
+			return newError("module %s does not export %s", path, name.Value)
+		}
+	}
+
+	env := object.NewEnvironment()
+	if result := e.eval(program, env); isError(result) {
+		return result
+	}
+
+	mod := &object.Module{Name: path, Exports: map[string]object.Object{}}
+	for _, name := range exports {
+		mod.Exports[name], _ = env.Get(name)
+	}
+
+	if e.modules == nil {
+		e.modules = map[string]*object.Module{}
+	}
+	e.modules[key] = mod
+	return mod
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/object"
+	"github.com/kakts/monkey/parser"
+)
+
+// テスト用のモジュールを一時ディレクトリに書き出す
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImport(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/math.mk": `
+let helper = fn(x) { x * 2 };
+export let double = fn(x) { helper(x) };
+export let pi = 3;
+let count = 0;
+export let next = fn() { count = count + 1; count };
+`,
+		"lib/uses_math.mk": `
+import { next } from "math.mk";
+export let twice = fn() { next(); next() };
+`,
+		"lib/fail.mk":   `export let x = 1 / 0;`,
+		"lib/syntax.mk": `let = 1;`,
+		"lib/return.mk": `return 1;`,
+		"cycle_a.mk":    `import "cycle_b.mk" as b;`,
+		"cycle_b.mk":    `import "cycle_a.mk" as a;`,
+	})
+
+	tests := []struct {
+		input    string
+		expected string // 結果のInspect エラーの場合は "ERROR: " とメッセージ
+	}{
+		{`import "lib/math.mk" as m; m.double(21)`, "42"},
+		{`import { double, pi } from "./lib/math.mk"; double(pi)`, "6"},
+		{`import "lib/math.mk" as m; m.helper`, "ERROR: module " + filepath.Join(dir, "lib/math.mk") + " does not export helper"},
+		{`import { pi, helper } from "lib/math.mk"; pi`, "ERROR: module " + filepath.Join(dir, "lib/math.mk") + " does not export helper"},
+		// モジュールは一度だけ評価され、状態を共有する
+		{`import "lib/math.mk" as m; import { next } from "lib/math.mk"; next(); m.next()`, "2"},
+		{`import "lib/uses_math.mk" as u; import "lib/math.mk" as m; u.twice(); m.next()`, "3"},
+		// importした位置から相対パスを解決する
+		{`import { twice } from "lib/uses_math.mk"; twice()`, "2"},
+		{`let m = 1; m.x`, "ERROR: member access not supported: INTEGER"},
+		{`import "lib/fail.mk" as f; f`, "ERROR: division by zero"},
+		{`import "lib/syntax.mk" as s; s`, "ERROR: cannot import " + filepath.Join(dir, "lib/syntax.mk") + ": " +
+			filepath.Join(dir, "lib/syntax.mk") + ":1:5: Expected next token to be IDENT, got = instead (hint: '=' cannot be used as a name)"},
+		{`import "lib/return.mk" as r; r`, "ERROR: cannot import " + filepath.Join(dir, "lib/return.mk") + ": " +
+			filepath.Join(dir, "lib/return.mk") + ":1:1: return outside function"},
+		// メインのファイルから始まる循環も検出する
+		{`import "main.mk" as m; m`, "ERROR: import cycle: " + filepath.Join(dir, "main.mk") + " -> " + filepath.Join(dir, "main.mk")},
+		{`import "cycle_a.mk" as a; a`, "ERROR: import cycle: " +
+			filepath.Join(dir, "cycle_a.mk") + " -> " + filepath.Join(dir, "cycle_b.mk") + " -> " + filepath.Join(dir, "cycle_a.mk")},
+	}
+
+	for _, tt := range tests {
+		got := testImport(dir, tt.input)
+		if got != tt.expected {
+			t.Errorf("wrong result for %q.\nwant=%s\ngot =%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// ファイルが無い場合のメッセージはOSによって異なるため先頭だけを比べる
+func TestImportMissingFile(t *testing.T) {
+	dir := t.TempDir()
+
+	got := testImport(dir, `import "missing.mk" as m;`)
+	expected := "ERROR: cannot import " + filepath.Join(dir, "missing.mk") + ": "
+	if !strings.HasPrefix(got, expected) {
+		t.Errorf("wrong error. want prefix=%s, got=%s", expected, got)
+	}
+}
+
+// dir/main.mk に書かれたプログラムとして評価する
+func testImport(dir, input string) string {
+	path := filepath.Join(dir, "main.mk")
+	program := parser.New(lexer.NewFile(path, input)).ParseProgram()
+	evaluated := New().Eval(program, object.NewEnvironment())
+
+	switch evaluated := evaluated.(type) {
+	case nil:
+		return "null"
+	case *object.Error:
+		return "ERROR: " + evaluated.Message
+	default:
+		return evaluated.Inspect()
+	}
+}
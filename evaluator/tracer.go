@@ -0,0 +1,67 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/object"
+)
+
+// 評価の途中経過を受け取るフック
+// Enterはノードを評価する前に、Exitは評価した結果とともに呼ばれる
+type Tracer interface {
+	Enter(node ast.Node, env *object.Environment)
+	Exit(node ast.Node, env *object.Environment, result object.Object)
+}
+
+// トレースに表示するソースや値の最大の長さ
+const traceMaxWidth = 40
+
+const traceIndentPlaceholder = "\t"
+
+// 評価の経過をネストの深さに応じて字下げして書き出すTracer
+// parser_tracing.goと同じくBEGIN/ENDの組で出力する
+type WriterTracer struct {
+	out   io.Writer
+	level int
+}
+
+func NewWriterTracer(out io.Writer) *WriterTracer {
+	return &WriterTracer{out: out}
+}
+
+func (t *WriterTracer) Enter(node ast.Node, env *object.Environment) {
+	fmt.Fprintf(t.out, "%sBEGIN %s %s %s\n",
+		t.indent(), nodeName(node), node.Pos(), truncate(node.String()))
+	t.level++
+}
+
+func (t *WriterTracer) Exit(node ast.Node, env *object.Environment, result object.Object) {
+	t.level--
+
+	value := "nil"
+	if result != nil {
+		value = truncate(result.Inspect())
+	}
+	fmt.Fprintf(t.out, "%sEND %s => %s\n", t.indent(), nodeName(node), value)
+}
+
+func (t *WriterTracer) indent() string {
+	return strings.Repeat(traceIndentPlaceholder, t.level)
+}
+
+// *ast.InfixExpression -> InfixExpression
+func nodeName(node ast.Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
+
+// 長い表現は省略し、改行は空白にして1行に収める
+func truncate(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > traceMaxWidth {
+		return string(r[:traceMaxWidth-3]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,126 @@
+package evaluator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/object"
+	"github.com/kakts/monkey/parser"
+)
+
+// 呼ばれた順にフックを記録するTracer
+// envs[i]はevents[i]のときの環境
+type recordingTracer struct {
+	events []string
+	envs   []*object.Environment
+}
+
+func (r *recordingTracer) Enter(node ast.Node, env *object.Environment) {
+	r.events = append(r.events, "enter "+nodeName(node))
+	r.envs = append(r.envs, env)
+}
+
+func (r *recordingTracer) Exit(node ast.Node, env *object.Environment, result object.Object) {
+	value := "nil"
+	if result != nil {
+		value = result.Inspect()
+	}
+	r.events = append(r.events, "exit "+nodeName(node)+" "+value)
+	r.envs = append(r.envs, env)
+}
+
+func TestTracerHooks(t *testing.T) {
+	program := parser.New(lexer.New("-1")).ParseProgram()
+	env := object.NewEnvironment()
+
+	tracer := &recordingTracer{}
+	e := New()
+	e.Tracer = tracer
+	e.Eval(program, env)
+
+	expected := []string{
+		"enter Program",
+		"enter ExpressionStatement",
+		"enter PrefixExpression",
+		"enter IntegerLiteral",
+		"exit IntegerLiteral 1",
+		"exit PrefixExpression -1",
+		"exit ExpressionStatement -1",
+		"exit Program -1",
+	}
+
+	if len(tracer.events) != len(expected) {
+		t.Fatalf("wrong number of events. want=%d, got=%d\n%v",
+			len(expected), len(tracer.events), tracer.events)
+	}
+	for i, want := range expected {
+		if tracer.events[i] != want {
+			t.Errorf("events[%d] wrong. want=%q, got=%q", i, want, tracer.events[i])
+		}
+	}
+	for i, got := range tracer.envs {
+		if got != env {
+			t.Errorf("envs[%d] is not the top-level environment", i)
+		}
+	}
+}
+
+func TestTracerSeesFunctionEnvironment(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn(x) { x }; f(7)")).ParseProgram()
+	env := object.NewEnvironment()
+
+	tracer := &recordingTracer{}
+	e := New()
+	e.Tracer = tracer
+	e.Eval(program, env)
+
+	found := false
+	for i, ev := range tracer.events {
+		if ev != "enter BlockStatement" {
+			continue
+		}
+		// 関数本体は引数を束縛した新しい環境で評価される
+		bodyEnv := tracer.envs[i]
+		if bodyEnv == env {
+			t.Errorf("function body was traced with the top-level environment")
+		}
+		if x, ok := bodyEnv.Get("x"); !ok || x.Inspect() != "7" {
+			t.Errorf("function environment does not bind x=7. got=%v", x)
+		}
+		found = true
+	}
+	if !found {
+		t.Fatalf("function body was not traced: %v", tracer.events)
+	}
+}
+
+func TestWriterTracer(t *testing.T) {
+	program := parser.New(lexer.New("let a = 1; a + 2")).ParseProgram()
+
+	var out bytes.Buffer
+	e := New()
+	e.Tracer = NewWriterTracer(&out)
+	e.Eval(program, object.NewEnvironment())
+
+	expected := `BEGIN Program 1:1 let a = 1;(a + 2)
+	BEGIN LetStatement 1:1 let a = 1;
+		BEGIN IntegerLiteral 1:9 1
+		END IntegerLiteral => 1
+	END LetStatement => nil
+	BEGIN ExpressionStatement 1:12 (a + 2)
+		BEGIN InfixExpression 1:12 (a + 2)
+			BEGIN Identifier 1:12 a
+			END Identifier => 1
+			BEGIN IntegerLiteral 1:16 2
+			END IntegerLiteral => 2
+		END InfixExpression => 3
+	END ExpressionStatement => 3
+END Program => 3
+`
+
+	if out.String() != expected {
+		t.Errorf("wrong trace.\nwant=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
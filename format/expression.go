@@ -0,0 +1,298 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/parser"
+	"github.com/kakts/monkey/token"
+)
+
+// 括弧の要らない式 (リテラル、識別子、if式など) の優先順位
+const primary = parser.INDEX + 1
+
+// 式の優先順位 これより低い優先順位が求められる位置では括弧が要らない
+func precedence(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(exp.Token.Type)
+	case *ast.AssignExpression:
+		return parser.ASSIGN
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.SliceExpression, *ast.MemberExpression:
+		return parser.INDEX
+	default:
+		return primary
+	}
+}
+
+// 式を出力する precより低い優先順位の式は括弧で囲む
+func (p *printer) expr(exp ast.Expression, prec int) {
+	if precedence(exp) < prec {
+		p.write("(")
+		p.expr(exp, parser.LOWEST)
+		p.write(")")
+		return
+	}
+
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.write(exp.Value)
+
+	case *ast.IntegerLiteral:
+		p.write(exp.Token.Literal)
+
+	case *ast.FloatLiteral:
+		p.write(exp.Token.Literal)
+
+	case *ast.Boolean:
+		p.write(exp.Token.Literal)
+
+	case *ast.StringLiteral:
+		p.stringLiteral(exp)
+
+	case *ast.InterpolatedString:
+		p.interpolatedString(exp)
+
+	case *ast.PrefixExpression:
+		p.write(exp.Operator)
+		// -(-x) を --x と書くとデクリメントのように読めるため括弧を残す
+		if right, ok := exp.Right.(*ast.PrefixExpression); ok && right.Operator == exp.Operator {
+			p.write("(")
+			p.expr(right, parser.LOWEST)
+			p.write(")")
+			break
+		}
+		p.expr(exp.Right, parser.PREFIX)
+
+	case *ast.InfixExpression:
+		// 左結合なので、右辺は同じ優先順位でも括弧が要る
+		prec := parser.Precedence(exp.Token.Type)
+		p.expr(exp.Left, prec)
+		p.write(" " + exp.Operator + " ")
+		p.expr(exp.Right, prec+1)
+
+	case *ast.AssignExpression:
+		// 右結合なので、右辺の代入式には括弧が要らない
+		p.expr(exp.Target, parser.CALL)
+		p.write(" " + exp.Operator + " ")
+		p.expr(exp.Value, parser.ASSIGN)
+
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expr(exp.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(exp.Consequence)
+		if exp.Alternative != nil {
+			p.write(" else ")
+			p.block(exp.Alternative)
+		}
+
+	case *ast.WhileExpression:
+		p.write("while (")
+		p.expr(exp.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(exp.Body)
+
+	case *ast.ForExpression:
+		p.write("for (" + exp.Variable.Value + " in ")
+		p.expr(exp.Iterable, parser.LOWEST)
+		p.write(") ")
+		p.block(exp.Body)
+
+	case *ast.FunctionLiteral:
+		params := make([]string, len(exp.Parameters))
+		for i, param := range exp.Parameters {
+			params[i] = param.Value
+		}
+		p.write("fn(" + strings.Join(params, ", ") + ") ")
+		p.block(exp.Body)
+
+	case *ast.CallExpression:
+		p.expr(exp.Function, parser.CALL)
+		p.write("(")
+		for i, arg := range exp.Arguments {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expr(arg, parser.LOWEST)
+		}
+		p.write(")")
+
+	case *ast.IndexExpression:
+		p.expr(exp.Left, parser.CALL)
+		p.write("[")
+		p.expr(exp.Index, parser.LOWEST)
+		p.write("]")
+
+	case *ast.SliceExpression:
+		p.expr(exp.Left, parser.CALL)
+		p.write("[")
+		if exp.Low != nil {
+			p.expr(exp.Low, parser.LOWEST)
+		}
+		p.write(":")
+		if exp.High != nil {
+			p.expr(exp.High, parser.LOWEST)
+		}
+		p.write("]")
+
+	case *ast.MemberExpression:
+		p.expr(exp.Object, parser.CALL)
+		p.write("." + exp.Member.Value)
+
+	case *ast.ArrayLiteral:
+		elements := make([]element, len(exp.Elements))
+		for i, el := range exp.Elements {
+			el := el
+			elements[i] = element{el.Pos(), el.End(), func() { p.expr(el, parser.LOWEST) }}
+		}
+		p.list("[", "]", exp.Token, exp.Rbracket, elements)
+
+	case *ast.HashLiteral:
+		elements := make([]element, len(exp.Keys))
+		for i, key := range exp.Keys {
+			key, value := key, exp.Pairs[key]
+			elements[i] = element{key.Pos(), value.End(), func() {
+				p.expr(key, parser.LOWEST)
+				p.write(": ")
+				p.expr(value, parser.LOWEST)
+			}}
+		}
+		p.list("{", "}", exp.Token, exp.Rbrace, elements)
+	}
+}
+
+// 配列とハッシュの要素 (ハッシュはキーと値の組)
+type element struct {
+	pos, end token.Position
+	print    func()
+}
+
+// 配列とハッシュの要素を出力する
+// ソースコードで複数行に書かれている場合は要素を1行に1つずつ出力する
+// 要素の途中にコメントがある場合は、文ごと書かれたとおりに出力されるため1行で出力しておく
+func (p *printer) list(open, close string, start, end token.Token, elements []element) {
+	if len(elements) == 0 || start.Pos.Line == end.Pos.Line || p.hasCommentInElements(elements) {
+		p.write(open)
+		for i, el := range elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			el.print()
+		}
+		p.write(close)
+		return
+	}
+
+	p.write(open)
+	p.newline()
+	p.indent++
+	p.lastLine = 0
+	for i, el := range elements {
+		p.leadingComments(el.pos)
+		p.blankLine(el.pos.Line)
+
+		el.print()
+		if i < len(elements)-1 {
+			p.write(",")
+		}
+
+		// 同じ行のコメントでも、次の要素より後ろにあるものは次の要素に続ける
+		limit := end.Pos
+		if i+1 < len(elements) {
+			limit = elements[i+1].pos
+		}
+		p.lastLine = el.end.Line
+		p.trailingComments(p.elementEnd(el.pos, el.end), limit)
+		p.newline()
+	}
+	p.leadingComments(end.Pos)
+	p.indent--
+	p.write(close)
+	p.lastLine = end.Pos.Line
+}
+
+// 要素の途中 (要素の後ろの , まで) にまだ出力していないコメントがあれば真
+func (p *printer) hasCommentInElements(elements []element) bool {
+	for _, el := range elements {
+		if p.hasCommentBetween(el.pos, p.elementEnd(el.pos, el.end)) {
+			return true
+		}
+	}
+	return false
+}
+
+// 要素の後ろの , (無い場合は要素) の終了位置
+func (p *printer) elementEnd(pos, end token.Position) token.Position {
+	_, last := p.nodeTokens(pos, end)
+	if last+1 < len(p.tokens) && p.tokens[last+1].Type == token.COMMA {
+		last++
+	}
+	return p.tokens[last].End
+}
+
+// 文字列リテラルは書かれたとおりに出力する (生文字列やエスケープの書き方を保つ)
+func (p *printer) stringLiteral(str *ast.StringLiteral) {
+	p.write(p.literalText(str.Token))
+}
+
+// 埋め込み式を含む文字列 文字列部分も書かれたとおりに出力する
+func (p *printer) interpolatedString(str *ast.InterpolatedString) {
+	p.write(`"`)
+	for _, part := range str.Parts {
+		if s, ok := part.(*ast.StringLiteral); ok && s.Token.Type != token.STRING {
+			p.write(p.literalText(s.Token))
+			continue
+		}
+		p.write("${")
+		p.expr(part, parser.LOWEST)
+		p.write("}")
+	}
+	p.write(`"`)
+}
+
+// 文字列リテラルのソースコード上の書き方
+// 埋め込み式を含む文字列の文字列部分は、トークンの前後の " } ${ を除いた部分を返す
+func (p *printer) literalText(tok token.Token) string {
+	start, end := tok.Pos.Offset, tok.End.Offset
+	switch tok.Type {
+	case token.STRING_START, token.STRING_MIDDLE:
+		start, end = start+1, end-2
+	case token.STRING_END:
+		start, end = start+1, end-1
+	}
+	return p.src[start:end]
+}
+
+// 式を出力したときに ( [ - のいずれかで始まるか
+// これらで始まる式文は、直前の式文の続きとして読まれてしまう
+func continuesExpression(exp ast.Expression, prec int) bool {
+	if precedence(exp) < prec {
+		return true
+	}
+
+	switch exp := exp.(type) {
+	case *ast.PrefixExpression:
+		return exp.Operator == "-"
+	case *ast.ArrayLiteral:
+		return true
+	case *ast.InfixExpression:
+		return continuesExpression(exp.Left, parser.Precedence(exp.Token.Type))
+	case *ast.AssignExpression:
+		return continuesExpression(exp.Target, parser.CALL)
+	case *ast.CallExpression:
+		return continuesExpression(exp.Function, parser.CALL)
+	case *ast.IndexExpression:
+		return continuesExpression(exp.Left, parser.CALL)
+	case *ast.SliceExpression:
+		return continuesExpression(exp.Left, parser.CALL)
+	case *ast.MemberExpression:
+		return continuesExpression(exp.Object, parser.CALL)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,310 @@
+// Package format はMonkeyのソースコードを決まった形に整形する
+// インデントはタブ、括弧は演算子の優先順位から必要なものだけを付け、コメントと空行は残す
+// 整形した結果をもう一度整形しても変わらない
+package format
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/parser"
+	"github.com/kakts/monkey/token"
+)
+
+// ソースコードを整形する 構文エラーがある場合はエラーを返す
+// filenameはエラーメッセージの位置に使う
+func Source(filename, src string) (string, error) {
+	p := parser.New(lexer.NewFile(filename, src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		msgs := make([]string, len(p.Errors()))
+		for i, err := range p.Errors() {
+			msgs[i] = err.Error()
+		}
+		return "", errors.New(strings.Join(msgs, "\n"))
+	}
+
+	pr := &printer{src: src, comments: program.Comments}
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		pr.tokens = append(pr.tokens, tok)
+	}
+	pr.stmtList(program.Statements, token.Position{Offset: len(src)}, false)
+	return pr.out.String(), nil
+}
+
+type printer struct {
+	src string // 文字列リテラルは書かれたとおりに出力する
+
+	out       bytes.Buffer
+	indent    int
+	lineStart bool // 現在の行にまだ何も出力していない
+
+	comments []*ast.Comment
+	next     int // 次に出力するコメント
+
+	// 最後に出力したもののソースコード上の行
+	// 次に出力するものとの間に空行があれば1行の空行を残す
+	lastLine int
+
+	tokens []token.Token // ソースコードのトークン (コメントを除く)
+}
+
+func (p *printer) write(s string) {
+	if p.lineStart {
+		p.out.WriteString(strings.Repeat("\t", p.indent))
+		p.lineStart = false
+	}
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.out.WriteByte('\n')
+	p.lineStart = true
+}
+
+// ソースコード上でlineの前に空行があれば空行を出力する
+// lastLineが0 (プログラムやブロックの先頭) の場合は出力しない
+func (p *printer) blankLine(line int) {
+	if p.lastLine > 0 && line > p.lastLine+1 {
+		p.newline()
+	}
+}
+
+// 文を1行に1つずつ出力する endはブロックの閉じかっこ (プログラムの場合は終端) の位置
+// ブロックの最後の式文はブロックの値になるため ; を付けない
+func (p *printer) stmtList(stmts []ast.Statement, end token.Position, isBlock bool) {
+	for i, stmt := range stmts {
+		p.leadingComments(p.stmtStart(stmt))
+		p.blankLine(stmt.Pos().Line)
+
+		var next ast.Statement
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+		}
+		p.stmtKeepingComments(stmt, next, isBlock && next == nil)
+
+		p.trailingComments(p.stmtEnd(stmt), end)
+		p.newline()
+	}
+	p.leadingComments(end)
+}
+
+// 文を出力する
+// 式の途中にコメントがある文は、コメントをトークンの間から動かさないよう書かれたとおりに出力する
+func (p *printer) stmtKeepingComments(stmt ast.Statement, next ast.Statement, last bool) {
+	outLen, lineStart, comment := p.out.Len(), p.lineStart, p.next
+
+	p.stmt(stmt, next, last)
+	p.lastLine = stmt.End().Line
+
+	end := p.stmtEnd(stmt)
+	if p.next == len(p.comments) || p.comments[p.next].Pos().Offset >= end.Offset {
+		return
+	}
+
+	p.out.Truncate(outLen)
+	p.lineStart = lineStart
+	p.next = comment
+
+	p.write(p.src[p.stmtStart(stmt).Offset:end.Offset])
+	for p.next < len(p.comments) && p.comments[p.next].Pos().Offset < end.Offset {
+		p.next++
+	}
+	p.lastLine = end.Line
+}
+
+// 文のソースコード上の開始位置と終了位置
+// 構文木の位置には文を囲む括弧と後ろの ; が含まれないため、トークンを見て広げる
+func (p *printer) stmtStart(stmt ast.Statement) token.Position {
+	first, _ := p.stmtTokens(stmt)
+	return p.tokens[first].Pos
+}
+
+func (p *printer) stmtEnd(stmt ast.Statement) token.Position {
+	_, last := p.stmtTokens(stmt)
+	return p.tokens[last].End
+}
+
+// 文の最初と最後のトークンの番号
+func (p *printer) stmtTokens(stmt ast.Statement) (first, last int) {
+	first, last = p.nodeTokens(stmt.Pos(), stmt.End())
+	if last+1 < len(p.tokens) && p.tokens[last+1].Type == token.SEMICOLON {
+		last++
+	}
+	return first, last
+}
+
+// posからendまでのノードの最初と最後のトークンの番号
+// ノードを囲む括弧も含める
+func (p *printer) nodeTokens(pos, end token.Position) (first, last int) {
+	first = p.tokenAt(pos)
+	last = p.tokenAt(end) - 1
+
+	// 範囲の中で閉じていない括弧の数だけ前後の括弧を含める
+	depth, low := 0, 0
+	for _, tok := range p.tokens[first : last+1] {
+		switch tok.Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+			if depth < low {
+				low = depth
+			}
+		}
+	}
+	for n := -low; n > 0 && first > 0 && p.tokens[first-1].Type == token.LPAREN; n-- {
+		first--
+	}
+	for n := depth - low; n > 0 && last+1 < len(p.tokens) && p.tokens[last+1].Type == token.RPAREN; n-- {
+		last++
+	}
+	return first, last
+}
+
+// posの位置か、それより後ろにある最初のトークンの番号
+func (p *printer) tokenAt(pos token.Position) int {
+	return sort.Search(len(p.tokens), func(i int) bool { return p.tokens[i].Pos.Offset >= pos.Offset })
+}
+
+// posより前にあるコメントをそれぞれ1行に出力する
+func (p *printer) leadingComments(pos token.Position) {
+	for p.next < len(p.comments) && p.comments[p.next].Pos().Offset < pos.Offset {
+		c := p.comments[p.next]
+		p.blankLine(c.Pos().Line)
+		p.write(commentText(c))
+		p.newline()
+		p.lastLine = c.End().Line
+		p.next++
+	}
+}
+
+// 出力したものの後ろにコメントを続ける
+// endと同じ行にありlimitより前にあるコメントが対象
+// endより前のコメント (式の途中のコメント) は出力せずに残し、文ごと書かれたとおりに出力させる
+func (p *printer) trailingComments(end, limit token.Position) {
+	for p.next < len(p.comments) {
+		c := p.comments[p.next]
+		if c.Pos().Offset < end.Offset || c.Pos().Line != end.Line || c.Pos().Offset >= limit.Offset {
+			return
+		}
+
+		p.write(" " + commentText(c))
+		if c.End().Line > p.lastLine {
+			p.lastLine = c.End().Line
+		}
+		p.next++
+	}
+}
+
+// startとendの間にまだ出力していないコメントがあれば真
+func (p *printer) hasCommentBetween(start, end token.Position) bool {
+	for _, c := range p.comments[p.next:] {
+		if c.Pos().Offset >= end.Offset {
+			return false
+		}
+		if c.Pos().Offset > start.Offset {
+			return true
+		}
+	}
+	return false
+}
+
+// 行コメントの末尾の空白は取り除く
+func commentText(c *ast.Comment) string {
+	if strings.HasPrefix(c.Token.Literal, "//") {
+		return strings.TrimRight(c.Token.Literal, " \t\r")
+	}
+	return c.Token.Literal
+}
+
+// nextは後ろに続く文 (無い場合はnil)、lastはブロックの最後の文の場合に真
+func (p *printer) stmt(stmt ast.Statement, next ast.Statement, last bool) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.write("let " + stmt.Name.Value + " = ")
+		p.expr(stmt.Value, parser.LOWEST)
+		p.write(";")
+
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expr(stmt.ReturnValue, parser.LOWEST)
+		p.write(";")
+
+	case *ast.BreakStatement:
+		p.write("break;")
+
+	case *ast.ContinueStatement:
+		p.write("continue;")
+
+	case *ast.ImportStatement:
+		p.write("import ")
+		if stmt.Alias != nil {
+			p.stringLiteral(stmt.Path)
+			p.write(" as " + stmt.Alias.Value)
+		} else {
+			names := make([]string, len(stmt.Names))
+			for i, name := range stmt.Names {
+				names[i] = name.Value
+			}
+			p.write("{ " + strings.Join(names, ", ") + " } from ")
+			p.stringLiteral(stmt.Path)
+		}
+		p.write(";")
+
+	case *ast.ExportStatement:
+		p.write("export ")
+		p.stmt(stmt.Statement, next, last)
+
+	case *ast.ExpressionStatement:
+		p.expr(stmt.Expression, parser.LOWEST)
+		if !last && needsSemicolon(stmt.Expression, next) {
+			p.write(";")
+		}
+	}
+}
+
+// 式文の後ろに ; が必要かどうか
+// ブロックで終わる if, while, for の後ろには付けない
+// ただし次の文が ( [ - で始まる場合は、前の式の続き (呼び出し、添字、引き算) と読まれないよう付ける
+func needsSemicolon(exp ast.Expression, next ast.Statement) bool {
+	switch exp.(type) {
+	case *ast.IfExpression, *ast.WhileExpression, *ast.ForExpression:
+	default:
+		return true
+	}
+
+	stmt, ok := next.(*ast.ExpressionStatement)
+	return ok && continuesExpression(stmt.Expression, parser.LOWEST)
+}
+
+// ブロック
+// ソースコードで1行に書かれた、文が1つだけのブロックは1行のまま出力する
+func (p *printer) block(block *ast.BlockStatement) {
+	hasComment := p.hasCommentBetween(block.Token.Pos, block.Rbrace.Pos)
+
+	if len(block.Statements) == 0 && !hasComment {
+		p.write("{}")
+		return
+	}
+	if len(block.Statements) == 1 && !hasComment && block.Token.Pos.Line == block.Rbrace.Pos.Line {
+		p.write("{ ")
+		p.stmt(block.Statements[0], nil, true)
+		p.write(" }")
+		return
+	}
+
+	p.write("{")
+	p.newline()
+	p.indent++
+	p.lastLine = 0
+	p.stmtList(block.Statements, block.Rbrace.Pos, true)
+	p.indent--
+	p.write("}")
+	p.lastLine = block.Rbrace.Pos.Line
+}
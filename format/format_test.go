@@ -0,0 +1,184 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"let  x=1", "let x = 1;\n"},
+		{"1+2*3; (1+2)*3", "1 + 2 * 3;\n(1 + 2) * 3;\n"},
+		{"((a))", "a;\n"},
+		{"a - (b - c) - d; a - b - c", "a - (b - c) - d;\na - b - c;\n"},
+		{"-(a + b); -a * b; !(-a); - -a", "-(a + b);\n-a * b;\n!-a;\n-(-a);\n"},
+		{"!(!a); !!!a; -(-(-1)); -!a", "!(!a);\n!(!(!a));\n-(-(-1));\n-!a;\n"},
+		{"a && b || (c && d); a && (b || c)", "a && b || c && d;\na && (b || c);\n"},
+		{"1 < 2 == (3 < 4); (1 == 2) == false", "1 < 2 == 3 < 4;\n1 == 2 == false;\n"},
+		{"a = b = c; (a = b) + 1; a[0] += 1", "a = b = c;\n(a = b) + 1;\na[0] += 1;\n"},
+		{"(-a)[0]; (a + b).c; f(1)(2)[0].x", "(-a)[0];\n(a + b).c;\nf(1)(2)[0].x;\n"},
+		{"(fn(x){x})(1)", "fn(x) { x }(1);\n"},
+		{"a[1:]; a[:-1]; a[ : ]", "a[1:];\na[:-1];\na[:];\n"},
+		{`{"a":1,"b":[1,2]}`, "{\"a\": 1, \"b\": [1, 2]};\n"},
+		{"let f = fn(a,b){a+b};", "let f = fn(a, b) { a + b };\n"},
+		{"let f = fn() {};", "let f = fn() {};\n"},
+		{
+			"let f = fn(x) { let y = x; y }",
+			"let f = fn(x) {\n\tlet y = x;\n\ty\n};\n",
+		},
+		{
+			"let f = fn(x) {\n  if (x) { return 1; }\n  puts(x);\n  x\n};",
+			"let f = fn(x) {\n\tif (x) { return 1; }\n\tputs(x);\n\tx\n};\n",
+		},
+		{
+			"while (i < 3) {\ni += 1; if (i == 2) { continue; }\n}\nfor (x in xs) { puts(x) }",
+			"while (i < 3) {\n\ti += 1;\n\tif (i == 2) { continue; }\n}\nfor (x in xs) { puts(x) }\n",
+		},
+		{
+			"if (a) { 1 } else {\n2\n}",
+			"if (a) { 1 } else {\n\t2\n}\n",
+		},
+		// 次の文が ( [ - で始まる場合は ; を残す
+		{"if (a) { 1 }; [1]; if (b) { 2 }; -1; if (c) { 3 }; (x); if (d) { 4 } x",
+			"if (a) { 1 };\n[1];\nif (b) { 2 };\n-1;\nif (c) { 3 }\nx;\nif (d) { 4 }\nx;\n"},
+		{"if (a) { 1 }; (b + c) * d", "if (a) { 1 };\n(b + c) * d;\n"},
+		// 文字列リテラルは埋め込み式を含む場合も書かれたとおりに出力する
+		{"`raw\n\\n`; \"a\\tb \\u{41}\"", "`raw\n\\n`;\n\"a\\tb \\u{41}\";\n"},
+		{`"x ${1+2} \u{41}\"\$${a}${"in${b}"}"`, `"x ${1 + 2} \u{41}\"\$${a}${"in${b}"}";` + "\n"},
+		{`"\u{1F600}${ x }\t${y}\u{1F600}"`, `"\u{1F600}${x}\t${y}\u{1F600}";` + "\n"},
+		{`"\${a} ${ {"k": 1}["k"] }"`, `"\${a} ${{"k": 1}["k"]}";` + "\n"},
+		{`import "a.mk" as a; import {x,y} from "b.mk"; export let z=a.f(x)`,
+			"import \"a.mk\" as a;\nimport { x, y } from \"b.mk\";\nexport let z = a.f(x);\n"},
+		{`"abc".upper().len()`, "\"abc\".upper().len();\n"},
+	}
+
+	for _, tt := range tests {
+		got, err := Source("", tt.input)
+		if err != nil {
+			t.Errorf("error for %q: %s", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q.\nwant=%q\ngot =%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"// only a comment", "// only a comment\n"},
+		{"let x = 1;   // one   \nlet y = 2; /* two */", "let x = 1; // one\nlet y = 2; /* two */\n"},
+		{"// head\n\n\n\nlet x = 1;\n\n\nlet y = 2;\n// tail", "// head\n\nlet x = 1;\n\nlet y = 2;\n// tail\n"},
+		{
+			"let f = fn() {\n\n  // first\n  let a = 1;  // a\n\n  a\n  // end\n};",
+			"let f = fn() {\n\t// first\n\tlet a = 1; // a\n\n\ta\n\t// end\n};\n",
+		},
+		// 1行のブロックの中のコメントは、ブロックを複数行にして残す
+		{"if (a) { /* c */ 1 }", "if (a) {\n\t/* c */\n\t1\n}\n"},
+		// 式の途中にコメントがある文は書かれたとおりに出力する
+		{"f(1, /* x */ 2); g()", "f(1, /* x */ 2);\ng();\n"},
+		{"f(1, // x\n2);", "f(1, // x\n2);\n"},
+		{"let y = x /* c */ + 1;", "let y = x /* c */ + 1;\n"},
+		{"let y = (x + 1 /* c */) ;  // d", "let y = (x + 1 /* c */) ; // d\n"},
+		{"(a) /* c */ + b\nc", "(a) /* c */ + b\nc;\n"},
+		{"if (a) {\n  f(1 /* c */)\n}", "if (a) {\n\tf(1 /* c */)\n}\n"},
+		{"let a = [\n  1 /* c */,\n  2\n];", "let a = [\n  1 /* c */,\n  2\n];\n"},
+		{"let a = [\n  1, /* c */\n  2\n];", "let a = [\n\t1, /* c */\n\t2\n];\n"},
+		{"[1, 2, // c\n 3]", "[\n\t1,\n\t2, // c\n\t3\n];\n"},
+		{"let h = {\"a\": 1, \"b\": 2, /* b */\n \"c\": 3};", "let h = {\n\t\"a\": 1,\n\t\"b\": 2, /* b */\n\t\"c\": 3\n};\n"},
+		{
+			"let h = {\n  \"a\": 1, // one\n\n  // two\n  \"b\": 2\n}; // h",
+			"let h = {\n\t\"a\": 1, // one\n\n\t// two\n\t\"b\": 2\n}; // h\n",
+		},
+		{
+			"let a = [1,\n  2]; // after",
+			"let a = [\n\t1,\n\t2\n]; // after\n",
+		},
+		{
+			"if (a) {\n  1\n} // done\nx",
+			"if (a) {\n\t1\n} // done\nx;\n",
+		},
+		{"/* multi\n   line */\nlet x = 1;", "/* multi\n   line */\nlet x = 1;\n"},
+	}
+
+	for _, tt := range tests {
+		got, err := Source("", tt.input)
+		if err != nil {
+			t.Errorf("error for %q: %s", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q.\nwant=%q\ngot =%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// 整形しても構文木は変わらず、整形した結果をもう一度整形しても変わらない
+func TestSourceIsStable(t *testing.T) {
+	inputs := []string{
+		`let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; puts(fib(10));`,
+		"let h = {\"a\": [1, 2, {\"b\": fn(x) {\nx * 2\n}}],\n\"c\": -(1 + 2) % 3};\nh[\"a\"][2][\"b\"](h.c)",
+		"let i = 0;\nwhile (i < 10) { i += 1; if (i % 2 == 0) { continue; } puts(\"odd ${i}\"); }",
+		"for (x in range(3)) {\n// loop\nputs(x, \"${x * 2}\\n\") /* c */\n}\n",
+		"let arr = [1, 2, 3]; arr[1:][-1]; arr.map(fn(x) { x + 1 }).filter(fn(x) { x > 2 });",
+		"if (a) { b } else { c }; [a, b]; if (a) { b }; -a; if (a) { b }; (a + b) * c",
+		"a = b += c; !(a == b) != !c; a - (b + c) * (d - e) / (f % g)",
+		"- -a; !!b; -(-(c)) + !(!d)",
+		"let y = x /* c */ + 1; let f = fn() {\n  g(1, // one\n    2)\n};\n((a) /* c */ + b) * c",
+	}
+
+	for _, input := range inputs {
+		formatted, err := Source("", input)
+		if err != nil {
+			t.Errorf("error for %q: %s", input, err)
+			continue
+		}
+
+		if want, got := parse(t, input), parse(t, formatted); want != got {
+			t.Errorf("formatting changed the program %q.\nwant=%s\ngot =%s", input, want, got)
+		}
+
+		again, err := Source("", formatted)
+		if err != nil {
+			t.Errorf("error for formatted %q: %s", formatted, err)
+			continue
+		}
+		if again != formatted {
+			t.Errorf("formatting is not idempotent for %q.\nfirst =%q\nsecond=%q", input, formatted, again)
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source("a.mk", "let = 1;\nlet x = ;")
+	if err == nil {
+		t.Fatalf("no error returned")
+	}
+
+	expected := "a.mk:1:5: Expected next token to be IDENT, got = instead (hint: '=' cannot be used as a name)\n" +
+		"a.mk:2:9: no prefix parse function for ; found (hint: missing expression)"
+	if err.Error() != expected {
+		t.Errorf("wrong error.\nwant=%q\ngot =%q", expected, err.Error())
+	}
+}
+
+// 構文木を比較用の文字列にする
+func parse(t *testing.T, input string) string {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program.String()
+}
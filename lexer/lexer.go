@@ -1,40 +1,75 @@
 package lexer
 
 import (
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/kakts/monkey/token"
 )
 
 type Lexer struct {
 	input        string
-	position     int // 入力における現在の位置
-	readPosition int // これから読み込む位置（現在の文字の次)
-	ch           byte
+	position     int  // 入力における現在の位置
+	readPosition int  // これから読み込む位置（現在の文字の次)
+	ch           rune // 現在の文字 (UTF-8をデコードしたコードポイント)
+
+	filename string // 位置情報に含めるファイル名
+	line     int    // 現在の文字の行番号
+	column   int    // 現在の文字の列番号
+
+	comments []token.Token // 読み飛ばしたコメント
+
+	// 読んでいる途中の埋め込み式 (入れ子になるためスタックで持つ)
+	interpolations []interpolation
+}
+
+// 文字列の埋め込み式 ${...} の状態
+type interpolation struct {
+	quote token.Position // 埋め込み式を含む文字列の開始位置
+	depth int            // 埋め込み式の中で開いている { の数
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// ファイル名付きのLexerを生成する
+// トークンの位置情報にファイル名が含まれる
+func NewFile(filename, input string) *Lexer {
+	l := &Lexer{input: input, filename: filename, line: 1}
 	l.readChar()
 	return l
 }
 
 // 次の１文字をよんでinput文字列の現在位置を進める
-// ASCII文字だけに対応 unicode全体をカバーしていない
+// 入力はUTF-8として1文字ずつデコードする 位置はバイト単位、列は文字単位で数える
+// 不正なバイト列はutf8.RuneErrorになる
 func (l *Lexer) readChar() {
+	// 改行を読み終えたら次の行に移る
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
 	// 入力が終端に達したかどうか 
+	width := 1
 	if l.readPosition >= len(l.input) {
 		// 終端に達した場合 ０
 		// 0はASCIIコードのNUL文字に対応
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 
 	l.position = l.readPosition
-	l.readPosition++
+	l.readPosition += width
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// 識別子に使える文字 (Unicodeの文字とアンダースコア)
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -46,17 +81,47 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-// 簡単のため整数のみ対応
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func (l *Lexer) readNumber() string {
+// 数値リテラルを読み、整数か浮動小数点数かを返す
+// 小数点の後と指数の後には数字が続く必要がある (1. や 1e は整数の後に別のトークンが続くとみなす)
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
+	tokenType := token.TokenType(token.INT)
+
+	l.readDigits()
+
+	// 小数部
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	// 指数部
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if (next == '+' || next == '-') && isDigit(l.peekCharAt(2)) {
+			tokenType = token.FLOAT
+			l.readChar()
+			l.readChar()
+			l.readDigits()
+		} else if isDigit(next) {
+			tokenType = token.FLOAT
+			l.readChar()
+			l.readDigits()
+		}
+	}
+
+	return l.input[position:l.position], tokenType
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -67,55 +132,149 @@ func (l *Lexer) skipWhitespace() {
 }
 
 // l.position, l.readPositionをインクリメンテせず、次の文字列を先読みする
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0;
 	} else {
 		// 現在のインデックスの次の文字を取得
-		return l.input[l.readPosition]
+		ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return ch
+	}
+}
+
+// 現在の位置からnバイト先を先読みする
+// 間の文字がすべてASCIIであるとわかっている場合にだけ使う
+func (l *Lexer) peekCharAt(n int) rune {
+	if l.position+n >= len(l.input) {
+		return 0
 	}
+	return rune(l.input[l.position+n])
+}
+
+// 現在の文字の位置
+func (l *Lexer) curPosition() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+// 現在の文字の直後の位置
+func (l *Lexer) nextPosition() token.Position {
+	pos := l.curPosition()
+	pos.Offset = l.readPosition
+	if l.ch == '\n' {
+		pos.Line++
+		pos.Column = 1
+	} else {
+		pos.Column++
+	}
+	return pos
 }
 
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
-	// ホワイトスペースはスキップさせる
+	// ホワイトスペースとコメントはスキップさせる
 	l.skipWhitespace()
+	for l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*') {
+		start := l.curPosition()
+		if !l.readComment() {
+			// 閉じていないブロックコメントは入力の終端を越えたところで終わる
+			l.readChar()
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[start.Offset:], Pos: start, End: l.curPosition()}
+		}
+		l.comments = append(l.comments, token.Token{
+			Type:    token.COMMENT,
+			Literal: l.input[start.Offset:l.position],
+			Pos:     start,
+			End:     l.curPosition(),
+		})
+		l.skipWhitespace()
+	}
+
+	// トークンの開始位置
+	start := l.curPosition()
 
 	switch l.ch {
 	case '=':
 		// ==
 		if l.peekChar() == '=' {
-			// readCharを呼ぶ前に現在の文字を保持
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.EQ, Literal: literal}
+			tok = l.makeTwoCharToken(token.EQ)
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		// +=
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.PLUS_ASSIGN)
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		// -=
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.MINUS_ASSIGN)
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		// !=
 		if l.peekChar() == '=' {
-			// readCharを呼ぶ前に現在の文字を保持
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.NOT_EQ, Literal: literal}
+			tok = l.makeTwoCharToken(token.NOT_EQ)
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		// /=
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.SLASH_ASSIGN)
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		// *=
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.ASTERISK_ASSIGN)
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		// %=
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.PERCENT_ASSIGN)
+		} else {
+			tok = newToken(token.PERCENT, l.ch)
+		}
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		// <=
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.LT_EQ)
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		// >=
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.GT_EQ)
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		// && (単独の&は未知の文字)
+		if l.peekChar() == '&' {
+			tok = l.makeTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		// || (単独の|は未知の文字)
+		if l.peekChar() == '|' {
+			tok = l.makeTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case '(':
@@ -125,14 +284,26 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1].depth++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
+		n := len(l.interpolations)
+		if n > 0 && l.interpolations[n-1].depth == 0 {
+			// 埋め込み式の終わり 文字列の続きを読む
+			return l.readString(start)
+		}
+		if n > 0 {
+			l.interpolations[n-1].depth--
+		}
 		tok = newToken(token.RBRACE, l.ch)
 	case '"':
 		// 文字列
-		tok.Type = token.STRING
-		// 次の '"'が現れるまでトークンを進めてその間の文字列を読み取る
-		tok.Literal = l.readString()
+		return l.readString(start)
+	case '`':
+		// 生文字列 エスケープも埋め込み式も解釈せず、改行を含められる
+		return l.readRawString(start)
 	case 0:
 		// 終端として扱う
 		tok.Literal = ""
@@ -143,15 +314,18 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
 	default:
 		// l.chが認識された文字でないときに識別子かどうかを点検する
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos, tok.End = start, l.curPosition()
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos, tok.End = start, l.curPosition()
 			return tok
 		} else {
 			// 対象の文字をどのようにして扱えばいいかわからない場合
@@ -159,22 +333,216 @@ func (l *Lexer) NextToken() token.Token {
 		}
 	}
 
+	if tok.Type == token.EOF {
+		tok.Pos, tok.End = start, start
+		return tok
+	}
+
 	l.readChar()
+	tok.Pos, tok.End = start, l.curPosition()
 	return tok
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+// コメントを読み、コメントの直後まで進める
+// 行コメント // は改行の手前まで、ブロックコメント /* */ は入れ子にならない
+// ブロックコメントが閉じていない場合はfalseを返す
+func (l *Lexer) readComment() bool {
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		return true
+	}
+
+	// "/*" を読み飛ばす
+	l.readChar()
+	l.readChar()
+	for {
+		if l.ch == 0 {
+			return false
+		}
+		if l.ch == '*' && l.peekChar() == '/' {
+			l.readChar()
+			l.readChar()
+			return true
+		}
+		l.readChar()
+	}
+}
+
+// これまでに読み飛ばしたコメントを出現順に返す
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// 文字列を読む
+// '"' から始まる場合は文字列の先頭から、'}' から始まる場合は埋め込み式の直後から読む
+// 次の '"' または "${" までの文字列をエスケープを解釈しながら読み取る
+// 閉じていない文字列は入力の終端を越えたところで終わるILLEGALトークンになる
+// 不正なエスケープがあった場合は文字列の終わりまで読んだうえで、そのエスケープのILLEGALトークンを返す
+func (l *Lexer) readString(start token.Position) token.Token {
+	continued := l.ch == '}'
+
+	var out []rune
+	var bad *token.Token
+	for {
+		l.readChar()
+
+		switch {
+		case l.ch == 0:
+			// 閉じていない文字列は開始の '"' の位置から報告する
+			quote := start
+			if continued {
+				quote = l.popInterpolation().quote
+			}
+			l.readChar()
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[quote.Offset:], Pos: quote, End: l.curPosition()}
+		case l.ch == '\\':
+			escStart := l.curPosition()
+			r, ok := l.readEscape()
+			if !ok {
+				if bad == nil {
+					bad = &token.Token{
+						Type:    token.ILLEGAL,
+						Literal: l.input[escStart.Offset:l.readPosition],
+						Pos:     escStart,
+						End:     l.nextPosition(),
+					}
+				}
+				continue
+			}
+			out = append(out, r)
+		case l.ch == '"' || l.ch == '$' && l.peekChar() == '{':
+			tok := token.Token{Literal: string(out), Pos: start}
+			if l.ch == '"' {
+				tok.Type = token.STRING
+				if continued {
+					l.popInterpolation()
+					tok.Type = token.STRING_END
+				}
+			} else {
+				l.readChar()
+				tok.Type = token.STRING_MIDDLE
+				if !continued {
+					l.interpolations = append(l.interpolations, interpolation{quote: start})
+					tok.Type = token.STRING_START
+				}
+			}
+			l.readChar()
+			tok.End = l.curPosition()
+
+			if bad != nil {
+				return *bad
+			}
+			return tok
+		default:
+			out = append(out, l.ch)
+		}
+	}
+}
+
+func (l *Lexer) popInterpolation() interpolation {
+	n := len(l.interpolations)
+	top := l.interpolations[n-1]
+	l.interpolations = l.interpolations[:n-1]
+	return top
+}
+
+// エスケープシーケンスを読む
+// l.chが '\' の位置で呼び出し、エスケープの最後の文字まで進める
+// 不正なエスケープの場合はfalseを返す 入力の終端と文字列を閉じる '"' は読まずに残す
+func (l *Lexer) readEscape() (rune, bool) {
+	if l.peekChar() == 0 {
+		return 0, false
+	}
+
+	l.readChar()
+	switch l.ch {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case 'r':
+		return '\r', true
+	case '\\', '"', '$':
+		return l.ch, true
+	case 'u':
+		return l.readUnicodeEscape()
+	default:
+		return 0, false
+	}
+}
+
+// \u{...} 形式のエスケープを読む 16進数で1桁から6桁のコードポイントを指定する
+func (l *Lexer) readUnicodeEscape() (rune, bool) {
+	if l.peekChar() != '{' {
+		return 0, false
+	}
+	l.readChar()
+
+	var r rune
+	digits := 0
+	for isHexDigit(l.peekChar()) {
+		l.readChar()
+		if digits < 6 {
+			r = r*16 + hexValue(l.ch)
+		}
+		digits++
+	}
+
+	if l.peekChar() != '}' {
+		return 0, false
+	}
+	l.readChar()
+
+	if digits == 0 || digits > 6 || !utf8.ValidRune(r) {
+		return 0, false
+	}
+	return r, true
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func hexValue(ch rune) rune {
+	switch {
+	case isDigit(ch):
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
+
+// 生文字列を読む
+// 次の '`' までの文字列をそのまま読み取る
+func (l *Lexer) readRawString(start token.Position) token.Token {
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
+		if l.ch == '`' || l.ch == 0 {
 			break
 		}
 	}
 
-	return l.input[position:l.position]
+	tok := token.Token{Type: token.STRING, Literal: l.input[start.Offset+1 : l.position], Pos: start}
+	if l.ch == 0 {
+		tok = token.Token{Type: token.ILLEGAL, Literal: l.input[start.Offset:], Pos: start}
+	}
+	l.readChar()
+	tok.End = l.curPosition()
+	return tok
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
-}
\ No newline at end of file
+}
+// 現在の文字と次の文字からなる2文字のトークンを作る
+func (l *Lexer) makeTwoCharToken(tokenType token.TokenType) token.Token {
+	// readCharを呼ぶ前に現在の文字を保持
+	ch := l.ch
+	l.readChar()
+	literal := string(ch) + string(l.ch)
+	return token.Token{Type: tokenType, Literal: literal}
+}
@@ -12,7 +12,7 @@ func TestNextToken(t *testing.T) {
 			x + y;
 		};
 		let result = add(five, ten);
-		!-/*5;
+		!-/ *5;
 		5 < 10 > 5;
 
 		if (5 < 10) {
@@ -27,6 +27,11 @@ func TestNextToken(t *testing.T) {
 		"foo bar"
 		[1, 2];
 		{"foo": "bar"}
+		a <= b >= c % d;
+		a && b || c;
+		x += 1 -= 2 *= 3 /= 4 %= 5;
+		while for in break continue
+		import export lib.add 1.5.x
 		`
 
 	tests := []struct {
@@ -119,6 +124,45 @@ func TestNextToken(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "d"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "2"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "3"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "4"},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.IMPORT, "import"},
+		{token.EXPORT, "export"},
+		{token.IDENT, "lib"},
+		{token.DOT, "."},
+		{token.IDENT, "add"},
+		{token.FLOAT, "1.5"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
 		{token.EOF, ""},
 	}
 
@@ -137,4 +181,284 @@ func TestNextToken(t *testing.T) {
 				i, tt.expectedLiteral, tok.Literal)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// トークンの位置情報のテスト
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x == "ab";`
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{Filename: "test.mk", Offset: 0, Line: 1, Column: 1}, token.Position{Filename: "test.mk", Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 4, Line: 1, Column: 5}, token.Position{Filename: "test.mk", Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Filename: "test.mk", Offset: 6, Line: 1, Column: 7}, token.Position{Filename: "test.mk", Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Filename: "test.mk", Offset: 8, Line: 1, Column: 9}, token.Position{Filename: "test.mk", Offset: 9, Line: 1, Column: 10}},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 9, Line: 1, Column: 10}, token.Position{Filename: "test.mk", Offset: 10, Line: 1, Column: 11}},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 13, Line: 2, Column: 3}, token.Position{Filename: "test.mk", Offset: 14, Line: 2, Column: 4}},
+		{token.EQ, token.Position{Filename: "test.mk", Offset: 15, Line: 2, Column: 5}, token.Position{Filename: "test.mk", Offset: 17, Line: 2, Column: 7}},
+		{token.STRING, token.Position{Filename: "test.mk", Offset: 18, Line: 2, Column: 8}, token.Position{Filename: "test.mk", Offset: 22, Line: 2, Column: 12}},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 22, Line: 2, Column: 12}, token.Position{Filename: "test.mk", Offset: 23, Line: 2, Column: 13}},
+		{token.EOF, token.Position{Filename: "test.mk", Offset: 23, Line: 2, Column: 13}, token.Position{Filename: "test.mk", Offset: 23, Line: 2, Column: 13}},
+	}
+
+	l := NewFile("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+
+		if tok.End != tt.expectedEnd {
+			t.Errorf("tests[%d] - end wrong. expected=%+v, got=%+v",
+				i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// head
+let x = 5; // tail
+/* block
+   comment */ x /**/ / 2 /* a */ /`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SLASH, "/"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// 読み飛ばしたコメントは位置情報付きで残る
+	expectedComments := []struct {
+		literal string
+		pos     token.Position
+	}{
+		{"// head", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{"// tail", token.Position{Offset: 19, Line: 2, Column: 12}},
+		{"/* block\n   comment */", token.Position{Offset: 27, Line: 3, Column: 1}},
+		{"/**/", token.Position{Offset: 52, Line: 4, Column: 17}},
+		{"/* a */", token.Position{Offset: 61, Line: 4, Column: 26}},
+	}
+
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. want=%d, got=%d", len(expectedComments), len(comments))
+	}
+	for i, tt := range expectedComments {
+		if comments[i].Type != token.COMMENT {
+			t.Errorf("comments[%d] - tokentype wrong. expected=%q got=%q", i, token.COMMENT, comments[i].Type)
+		}
+		if comments[i].Literal != tt.literal {
+			t.Errorf("comments[%d] - literal wrong. expected=%q got=%q", i, tt.literal, comments[i].Literal)
+		}
+		if comments[i].Pos != tt.pos {
+			t.Errorf("comments[%d] - pos wrong. expected=%+v, got=%+v", i, tt.pos, comments[i].Pos)
+		}
+	}
+}
+
+// 閉じていないブロックコメントは入力の終端までを含むILLEGALトークンになる
+func TestUnterminatedComment(t *testing.T) {
+	input := "1 /* open"
+
+	l := New(input)
+	l.NextToken()
+
+	tok := l.NextToken()
+	if tok.Type != token.ILLEGAL {
+		t.Fatalf("tokentype wrong. expected=%q got=%q", token.ILLEGAL, tok.Type)
+	}
+	if tok.Literal != "/* open" {
+		t.Fatalf("literal wrong. expected=%q got=%q", "/* open", tok.Literal)
+	}
+	if tok.End.Offset <= len(input) {
+		t.Errorf("end should be past the input. got=%d", tok.End.Offset)
+	}
+
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Errorf("tokentype wrong. expected=%q got=%q", token.EOF, tok.Type)
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := `let 名前 = "日本語";
+名前 + "🐒" ≠`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{token.LET, "let", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.IDENT, "名前", token.Position{Offset: 4, Line: 1, Column: 5}},
+		{token.ASSIGN, "=", token.Position{Offset: 11, Line: 1, Column: 8}},
+		{token.STRING, "日本語", token.Position{Offset: 13, Line: 1, Column: 10}},
+		{token.SEMICOLON, ";", token.Position{Offset: 24, Line: 1, Column: 15}},
+		{token.IDENT, "名前", token.Position{Offset: 26, Line: 2, Column: 1}},
+		{token.PLUS, "+", token.Position{Offset: 33, Line: 2, Column: 4}},
+		{token.STRING, "🐒", token.Position{Offset: 35, Line: 2, Column: 6}},
+		{token.ILLEGAL, "≠", token.Position{Offset: 42, Line: 2, Column: 10}},
+		{token.EOF, "", token.Position{Offset: 45, Line: 2, Column: 11}},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	type tok struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}
+
+	tests := []struct {
+		input    string
+		expected []tok
+	}{
+		{`"a\"b"`, []tok{{token.STRING, `a"b`}}},
+		{`"\n\t\r\\\$"`, []tok{{token.STRING, "\n\t\r\\$"}}},
+		{`"\u{41}\u{65e5}\u{1F412}"`, []tok{{token.STRING, "A日🐒"}}},
+		{"\"two\nlines\"", []tok{{token.STRING, "two\nlines"}}},
+		{"`raw \\n ${x}\n\"`", []tok{{token.STRING, "raw \\n ${x}\n\""}}},
+		{`"$x {y}"`, []tok{{token.STRING, "$x {y}"}}},
+		{`"a ${x} b"`, []tok{
+			{token.STRING_START, "a "},
+			{token.IDENT, "x"},
+			{token.STRING_END, " b"},
+		}},
+		{`"${x}${ {1: 2}[1] }"`, []tok{
+			{token.STRING_START, ""},
+			{token.IDENT, "x"},
+			{token.STRING_MIDDLE, ""},
+			{token.LBRACE, "{"},
+			{token.INT, "1"},
+			{token.COLON, ":"},
+			{token.INT, "2"},
+			{token.RBRACE, "}"},
+			{token.LBRACKET, "["},
+			{token.INT, "1"},
+			{token.RBRACKET, "]"},
+			{token.STRING_END, ""},
+		}},
+		{`"a ${"b ${c}"} d" + e`, []tok{
+			{token.STRING_START, "a "},
+			{token.STRING_START, "b "},
+			{token.IDENT, "c"},
+			{token.STRING_END, ""},
+			{token.STRING_END, " d"},
+			{token.PLUS, "+"},
+			{token.IDENT, "e"},
+		}},
+		// 閉じていない文字列は開始位置からのILLEGALトークン
+		{`"abc`, []tok{{token.ILLEGAL, `"abc`}}},
+		{`"a ${x} b`, []tok{
+			{token.STRING_START, "a "},
+			{token.IDENT, "x"},
+			{token.ILLEGAL, `"a ${x} b`},
+		}},
+		{"`abc", []tok{{token.ILLEGAL, "`abc"}}},
+		{`"abc\"`, []tok{{token.ILLEGAL, `"abc\"`}}},
+		// 不正なエスケープは文字列を読み飛ばしたうえでILLEGALトークンになる
+		{`"a\qb" 1`, []tok{{token.ILLEGAL, `\q`}, {token.INT, "1"}}},
+		{`"\u{zz}" 1`, []tok{{token.ILLEGAL, `\u{`}, {token.INT, "1"}}},
+		{`"\u{110000}"`, []tok{{token.ILLEGAL, `\u{110000}`}}},
+		{`"\u{}"`, []tok{{token.ILLEGAL, `\u{}`}}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for i, expected := range append(tt.expected, tok{token.EOF, ""}) {
+			got := l.NextToken()
+			if got.Type != expected.expectedType {
+				t.Fatalf("%s: tokens[%d] - tokentype wrong. expected=%q got=%q",
+					tt.input, i, expected.expectedType, got.Type)
+			}
+			if got.Literal != expected.expectedLiteral {
+				t.Fatalf("%s: tokens[%d] - literal wrong. expected=%q got=%q",
+					tt.input, i, expected.expectedLiteral, got.Literal)
+			}
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"3.14", []token.Token{{Type: token.FLOAT, Literal: "3.14"}}},
+		{"1e-9", []token.Token{{Type: token.FLOAT, Literal: "1e-9"}}},
+		{"2.5E+10", []token.Token{{Type: token.FLOAT, Literal: "2.5E+10"}}},
+		{"6e3", []token.Token{{Type: token.FLOAT, Literal: "6e3"}}},
+		{"42", []token.Token{{Type: token.INT, Literal: "42"}}},
+		// 小数点や指数の後に数字が無ければ整数で終わる
+		{"1.", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.DOT, Literal: "."}}},
+		{"1e", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.IDENT, Literal: "e"}}},
+		{"1e+", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.IDENT, Literal: "e"}, {Type: token.PLUS, Literal: "+"}}},
+		{"1.5.5", []token.Token{{Type: token.FLOAT, Literal: "1.5"}, {Type: token.DOT, Literal: "."}, {Type: token.INT, Literal: "5"}}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Errorf("%q: token %d wrong. want=%s %q, got=%s %q",
+					tt.input, i, expected.Type, expected.Literal, tok.Type, tok.Literal)
+			}
+		}
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("%q: expected EOF, got=%s %q", tt.input, tok.Type, tok.Literal)
+		}
+	}
+}
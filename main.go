@@ -1,19 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"os"
-	"os/user"
-	"github.com/kakts/monkey/repl"
-)
-
-func main() {
-	user, err := user.Current()
-	if err != nil {
-		panic(err)
-	}
-
-	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
-	fmt.Printf("Feel free to type in commands \n")
-	repl.Start(os.Stdin, os.Stdout)
-}
\ No newline at end of file
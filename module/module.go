@@ -0,0 +1,101 @@
+// Package module はimport文で読み込むファイルを探して構文解析する
+// 読み込んだモジュールの評価とキャッシュはevaluatorとcompilerがそれぞれ行う
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/parser"
+)
+
+// importに書かれたパスを解決する
+// 相対パスはimportを書いたファイルのディレクトリから探す
+// ファイル名の無いソース (REPLや -e) ではカレントディレクトリから探す
+func Resolve(importer, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(filepath.Dir(importer), path)
+}
+
+// 同じファイルを指すパスに共通のキー モジュールのキャッシュに使う
+func Key(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// ファイルを読み込んで構文解析する
+func Parse(path string) (*ast.Program, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot import %s: %s", path, err)
+	}
+
+	p := parser.New(lexer.NewFile(path, string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		msgs := make([]string, len(p.Errors()))
+		for i, err := range p.Errors() {
+			msgs[i] = err.Error()
+		}
+		return nil, fmt.Errorf("cannot import %s: %s", path, strings.Join(msgs, "\n"))
+	}
+
+	// モジュールの値はexportした束縛だけなので、トップレベルのreturnは使えない
+	for _, stmt := range program.Statements {
+		if ret, ok := stmt.(*ast.ReturnStatement); ok {
+			return nil, fmt.Errorf("cannot import %s: %s: return outside function", path, ret.Pos())
+		}
+	}
+
+	return program, nil
+}
+
+// モジュールがexportする名前 (書かれた順)
+func Exports(program *ast.Program) []string {
+	names := []string{}
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			names = append(names, export.Statement.Name.Value)
+		}
+	}
+	return names
+}
+
+// 読み込み中のモジュールの一覧
+// 読み込みが終わる前に同じモジュールをimportした場合を循環として検出する
+type Stack struct {
+	paths []string
+	keys  []string
+}
+
+func (s *Stack) Push(path string) error {
+	key := Key(path)
+	for i, k := range s.keys {
+		if k == key {
+			cycle := append(append([]string{}, s.paths[i:]...), path)
+			return fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	s.paths = append(s.paths, path)
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+// 読み込み中のモジュールが無い場合は真
+func (s *Stack) Empty() bool {
+	return len(s.paths) == 0
+}
+
+func (s *Stack) Pop() {
+	s.paths = s.paths[:len(s.paths)-1]
+	s.keys = s.keys[:len(s.keys)-1]
+}
@@ -0,0 +1,69 @@
+package module
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/parser"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		importer string
+		path     string
+		expected string
+	}{
+		{"main.mk", "lib.mk", "lib.mk"},
+		{"src/main.mk", "lib.mk", "src/lib.mk"},
+		{"src/main.mk", "../lib/./math.mk", "lib/math.mk"},
+		{"src/main.mk", "/abs/lib.mk", "/abs/lib.mk"},
+		{"", "lib.mk", "lib.mk"},
+	}
+
+	for _, tt := range tests {
+		got := Resolve(tt.importer, tt.path)
+		if got != filepath.FromSlash(tt.expected) {
+			t.Errorf("Resolve(%q, %q) wrong. want=%q, got=%q", tt.importer, tt.path, tt.expected, got)
+		}
+	}
+}
+
+func TestExports(t *testing.T) {
+	input := `
+let a = 1;
+export let b = 2;
+export let c = fn() { a };
+`
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	exports := Exports(program)
+	if len(exports) != 2 || exports[0] != "b" || exports[1] != "c" {
+		t.Errorf("wrong exports. got=%v", exports)
+	}
+}
+
+func TestStack(t *testing.T) {
+	var s Stack
+	if !s.Empty() {
+		t.Fatalf("new stack is not empty")
+	}
+
+	if err := s.Push("a.mk"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := s.Push("lib/b.mk"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// 同じファイルを指す別のパスも循環として扱う
+	err := s.Push("lib/../a.mk")
+	if err == nil || err.Error() != "import cycle: a.mk -> lib/b.mk -> lib/../a.mk" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	s.Pop()
+	if err := s.Push("lib/b.mk"); err != nil {
+		t.Errorf("unexpected error after Pop: %s", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Package monkey はGoのプログラムにMonkeyのインタプリタを組み込むためのAPIを提供する
+//
+//	in := monkey.New()
+//	in.Set("greet", func(name string) string { return "Hello " + name })
+//	v, err := in.Eval(`greet("Monkey")`)
+package monkey
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kakts/monkey/evaluator"
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/object"
+	"github.com/kakts/monkey/parser"
+)
+
+// Monkeyのインタプリタ
+// Evalをまたいで束縛を保持する
+type Interpreter struct {
+	env       *object.Environment
+	evaluator *evaluator.Evaluator
+}
+
+func New() *Interpreter {
+	return &Interpreter{
+		env:       object.NewEnvironment(),
+		evaluator: evaluator.New(),
+	}
+}
+
+// 評価に課す制限
+// 0の項目は制限しない
+type Limits = evaluator.Limits
+
+// 評価に課す制限を設定する
+// Newの直後は関数呼び出しの深さだけが制限されている
+func (i *Interpreter) SetLimits(limits Limits) {
+	i.evaluator.Limits = limits
+}
+
+// 整数演算の結果がint64に収まらない場合の扱い
+type OverflowPolicy = object.OverflowPolicy
+
+const (
+	OverflowWrap    = object.OverflowWrap    // 2の補数で折り返す (既定)
+	OverflowError   = object.OverflowError   // エラーにする
+	OverflowPromote = object.OverflowPromote // 任意精度の整数 (*big.Int) にする
+)
+
+// 整数演算の桁あふれの扱いを設定する
+func (i *Interpreter) SetOverflowPolicy(policy OverflowPolicy) {
+	i.evaluator.Overflow = policy
+}
+
+// 構文エラーの一覧
+type ParseErrors []*parser.ParseError
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ソースを評価し、最後の式の値をGoの値にして返す
+// 構文エラーはParseErrors、実行時エラーは*object.Errorとして返す
+func (i *Interpreter) Eval(src string) (interface{}, error) {
+	return i.EvalContext(context.Background(), src)
+}
+
+// ctxが取り消されると評価を中断してエラーを返す
+func (i *Interpreter) EvalContext(ctx context.Context, src string) (interface{}, error) {
+	obj, err := i.EvalObjectContext(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	return i.ToGo(obj)
+}
+
+// ソースを評価し、最後の式の値をそのまま返す
+func (i *Interpreter) EvalObject(src string) (object.Object, error) {
+	return i.EvalObjectContext(context.Background(), src)
+}
+
+func (i *Interpreter) EvalObjectContext(ctx context.Context, src string) (object.Object, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, ParseErrors(p.Errors())
+	}
+
+	result := i.evaluator.EvalContext(ctx, program, i.env)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, errObj
+	}
+	if result == nil {
+		return evaluator.NULL, nil
+	}
+	return result, nil
+}
+
+// Goの値をMonkeyの値に変換して名前に束縛する
+func (i *Interpreter) Set(name string, value interface{}) error {
+	obj, err := i.ToObject(value)
+	if err != nil {
+		return err
+	}
+	i.env.Set(name, obj)
+	return nil
+}
+
+// 名前に束縛された値をGoの値にして返す
+func (i *Interpreter) Get(name string) (interface{}, error) {
+	obj, ok := i.env.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("identifier not found: %s", name)
+	}
+	return i.ToGo(obj)
+}
+
+// 名前に束縛された関数 (または組み込み関数) を呼び出し、結果をGoの値にして返す
+func (i *Interpreter) Call(fnName string, args ...interface{}) (interface{}, error) {
+	fn, ok := i.env.Get(fnName)
+	if !ok {
+		builtin := object.GetBuiltinByName(fnName)
+		if builtin == nil {
+			return nil, fmt.Errorf("identifier not found: %s", fnName)
+		}
+		fn = builtin
+	}
+
+	result, err := i.call(fn, args)
+	if err != nil {
+		return nil, err
+	}
+	return i.ToGo(result)
+}
+
+// Goの値を引数にしてMonkeyの関数を呼び出す
+func (i *Interpreter) call(fn object.Object, args []interface{}) (object.Object, error) {
+	objs := make([]object.Object, len(args))
+	for n, arg := range args {
+		obj, err := i.ToObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %s", n, err)
+		}
+		objs[n] = obj
+	}
+
+	result := i.evaluator.Apply(fn, objs)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, errObj
+	}
+	return result, nil
+}
@@ -0,0 +1,442 @@
+package monkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kakts/monkey/object"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 + 2", int64(3)},
+		{`"foo" + "bar"`, "foobar"},
+		{"1 < 2", true},
+		{"let x = 5;", nil},
+		{"if (false) { 1 }", nil},
+		{"[1, [true, \"a\"]]", []interface{}{int64(1), []interface{}{true, "a"}}},
+		{`{"a": 1, 2: false}`, map[interface{}]interface{}{"a": int64(1), int64(2): false}},
+	}
+
+	for _, tt := range tests {
+		got, err := New().Eval(tt.input)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", tt.input, err)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: wrong result. want=%#v, got=%#v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalKeepsBindings(t *testing.T) {
+	in := New()
+	if _, err := in.Eval("let a = 10;"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := in.Eval("a * 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != int64(20) {
+		t.Errorf("wrong result. got=%#v", got)
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	_, err := New().Eval("let = 1;")
+	parseErrs, ok := err.(ParseErrors)
+	if !ok {
+		t.Fatalf("err is not ParseErrors. got=%T (%v)", err, err)
+	}
+	if len(parseErrs) != 1 || !strings.HasPrefix(parseErrs.Error(), "1:5:") {
+		t.Errorf("wrong parse errors. got=%q", parseErrs.Error())
+	}
+
+	_, err = New().Eval("1 + true")
+	errObj, ok := err.(*object.Error)
+	if !ok {
+		t.Fatalf("err is not *object.Error. got=%T (%v)", err, err)
+	}
+	if errObj.Message != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{42, int64(42)},
+		{int8(-3), int64(-3)},
+		{uint16(7), int64(7)},
+		{"monkey", "monkey"},
+		{2.5, 2.5},
+		{float32(0.5), 0.5},
+		{[]int{1, 2}, []interface{}{int64(1), int64(2)}},
+		{[2]string{"a", "b"}, []interface{}{"a", "b"}},
+		{map[string]int{"one": 1}, map[interface{}]interface{}{"one": int64(1)}},
+		{[]interface{}{1, "a", nil}, []interface{}{int64(1), "a", nil}},
+	}
+
+	for _, tt := range tests {
+		in := New()
+		if err := in.Set("v", tt.value); err != nil {
+			t.Fatalf("Set(%#v) failed: %s", tt.value, err)
+		}
+
+		got, err := in.Get("v")
+		if err != nil {
+			t.Fatalf("Get(%#v) failed: %s", tt.value, err)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrong value for %#v. want=%#v, got=%#v", tt.value, tt.expected, got)
+		}
+	}
+
+	if _, err := New().Get("missing"); err == nil || err.Error() != "identifier not found: missing" {
+		t.Errorf("Get should report missing bindings. got=%v", err)
+	}
+}
+
+func TestSetUnsupported(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{struct{}{}, "unsupported Go type: struct {}"},
+		{complex(1, 2), "unsupported Go type: complex128"},
+		{[]chan int{nil}, "unsupported Go type: chan int"},
+		{map[bool][]int{true: {1}, false: {2}}, ""},
+		{uint64(1 << 63), "integer overflow: 9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		err := New().Set("v", tt.value)
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("Set(%#v) failed: %s", tt.value, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Set(%#v) wrong error. want=%q, got=%v", tt.value, tt.expected, err)
+		}
+	}
+}
+
+func TestBindingsAreUsableFromMonkey(t *testing.T) {
+	in := New()
+	in.Set("flag", true)
+	in.Set("nums", []int{1, 2, 3})
+
+	got, err := in.Eval("if (flag == true) { len(nums) + nums[2] }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != int64(6) {
+		t.Errorf("wrong result. got=%#v", got)
+	}
+}
+
+// Goのマップはキーを昇順に並べたハッシュになる
+func TestMapKeysAreSorted(t *testing.T) {
+	in := New()
+	in.Set("m", map[string]int{"c": 3, "a": 1, "b": 2, "d": 4})
+	in.Set("n", map[int]bool{10: true, -1: false, 2: true})
+
+	got, err := in.Eval(`str(m) + " " + str(keys(n))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "{a: 1, b: 2, c: 3, d: 4} [-1, 2, 10]" {
+		t.Errorf("wrong result. got=%#v", got)
+	}
+}
+
+func TestGoFunctions(t *testing.T) {
+	in := New()
+	in.Set("add", func(a, b int) int { return a + b })
+	in.Set("greet", func(name string) string { return "Hello " + name })
+	in.Set("join", func(sep string, parts ...string) string { return strings.Join(parts, sep) })
+	in.Set("sum", func(nums []int64) int64 {
+		var total int64
+		for _, n := range nums {
+			total += n
+		}
+		return total
+	})
+	in.Set("keys", func(m map[string]bool) int { return len(m) })
+	in.Set("fail", func() (int, error) { return 0, errors.New("boom") })
+	in.Set("nothing", func() {})
+	in.Set("pair", func() (int, string) { return 1, "a" })
+	in.Set("small", func(n int8) int8 { return n })
+	in.Set("half", func(f float64) float64 { return f / 2 })
+	in.Set("crash", func() int { panic("crashed") })
+	in.Set("at", func(nums []int, n int) int { return nums[n] })
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"add(1, 2)", int64(3)},
+		{`greet("Monkey")`, "Hello Monkey"},
+		{`join("-", "a", "b", "c")`, "a-b-c"},
+		{`join(",")`, ""},
+		{"sum([1, 2, 3])", int64(6)},
+		{`keys({"a": true, "b": false})`, int64(2)},
+		{"nothing()", nil},
+		{"pair()", []interface{}{int64(1), "a"}},
+		{"half(3)", 1.5},
+		{"half(1.5)", 0.75},
+	}
+
+	for _, tt := range tests {
+		got, err := in.Eval(tt.input)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", tt.input, err)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: wrong result. want=%#v, got=%#v", tt.input, tt.expected, got)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"fail()", "boom"},
+		{"add(1)", "wrong number of arguments: want=2, got=1"},
+		{`add(1, "2")`, "argument 1: cannot convert STRING to int"},
+		{"small(1000)", "argument 0: integer 1000 overflows int8"},
+		{`join()`, "wrong number of arguments: want at least 1, got=0"},
+		{"crash()", "panic in Go function: crashed"},
+		{"at([1], 5)", "panic in Go function: runtime error: index out of range [5] with length 1"},
+	}
+
+	for _, tt := range errorTests {
+		_, err := in.Eval(tt.input)
+		errObj, ok := err.(*object.Error)
+		if !ok {
+			t.Fatalf("%q: err is not *object.Error. got=%T (%v)", tt.input, err, err)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+		if !errObj.Pos.IsValid() {
+			t.Errorf("%q: error has no position", tt.input)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	in := New()
+	if _, err := in.Eval("let mul = fn(a, b) { a * b };"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := in.Call("mul", 6, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != int64(42) {
+		t.Errorf("wrong result. got=%#v", got)
+	}
+
+	got, err = in.Call("len", "four")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != int64(4) {
+		t.Errorf("wrong result for builtin. got=%#v", got)
+	}
+
+	if _, err := in.Call("mul", 1); err == nil {
+		t.Errorf("expected an error for a wrong number of arguments")
+	}
+	if _, err := in.Call("nope"); err == nil || err.Error() != "identifier not found: nope" {
+		t.Errorf("wrong error for a missing function. got=%v", err)
+	}
+}
+
+// 自分自身を含む配列やハッシュはGoの値にできないのでエラーになる
+func TestSelfReferencingValues(t *testing.T) {
+	in := New()
+	in.Set("show", func(v interface{}) string { return fmt.Sprint(v) })
+	_, err := in.Eval(`let a = [1]; a[0] = a; let h = {}; h["h"] = [h]; let self = fn() { a }; let b = [1]; let shared = [b, b];`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	arrayErr := "cannot convert ARRAY containing itself"
+	if _, err := in.Eval("a"); err == nil || err.Error() != arrayErr {
+		t.Errorf("wrong error from Eval. got=%v", err)
+	}
+	if _, err := in.Get("h"); err == nil || err.Error() != "cannot convert HASH containing itself" {
+		t.Errorf("wrong error from Get. got=%v", err)
+	}
+	if _, err := in.Call("self"); err == nil || err.Error() != arrayErr {
+		t.Errorf("wrong error from Call. got=%v", err)
+	}
+	if _, err := in.Eval("show(a)"); err == nil || err.Error() != "1:1: argument 0: "+arrayErr {
+		t.Errorf("wrong error from a Go function. got=%v", err)
+	}
+
+	// 同じ配列を2回含むだけなら変換できる
+	got, err := in.Get("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{[]interface{}{int64(1)}, []interface{}{int64(1)}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong value. want=%#v, got=%#v", expected, got)
+	}
+}
+
+// 自分自身を含むGoのスライスやマップはMonkeyの値にできないのでエラーになる
+func TestSelfReferencingGoValues(t *testing.T) {
+	s := []interface{}{1, nil}
+	s[1] = s
+	m := map[string]interface{}{"a": 1}
+	m["m"] = []interface{}{m}
+
+	in := New()
+	if err := in.Set("s", s); err == nil || err.Error() != "cannot convert []interface {} containing itself" {
+		t.Errorf("wrong error for a slice. got=%v", err)
+	}
+	if err := in.Set("m", m); err == nil || err.Error() != "cannot convert map[string]interface {} containing itself" {
+		t.Errorf("wrong error for a map. got=%v", err)
+	}
+
+	in.Set("loop", func() []interface{} { return s })
+	_, err := in.Eval("loop()")
+	if err == nil || err.Error() != "1:1: cannot convert []interface {} containing itself" {
+		t.Errorf("wrong error from a Go function. got=%v", err)
+	}
+
+	// 同じスライスを2回含むだけなら変換できる
+	shared := []int{1}
+	if err := in.Set("shared", [][]int{shared, shared, shared[:0]}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := in.Eval("str(shared)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "[[1], [1], []]" {
+		t.Errorf("wrong result. got=%#v", got)
+	}
+}
+
+func TestMonkeyFunctionsAsGoValues(t *testing.T) {
+	in := New()
+	in.Set("apply", func(f func(int) int, n int) int { return f(n) })
+	in.Set("applyErr", func(f func() (int, error)) string {
+		if _, err := f(); err != nil {
+			return err.Error()
+		}
+		return "ok"
+	})
+
+	got, err := in.Eval("apply(fn(x) { x * 10 }, 4)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != int64(40) {
+		t.Errorf("wrong result. got=%#v", got)
+	}
+
+	got, err = in.Eval(`applyErr(fn() { 1 + true })`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "1:17: type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error from callback. got=%#v", got)
+	}
+
+	v, err := in.Eval("fn(a, b) { a + b }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn, ok := v.(func(...interface{}) (interface{}, error))
+	if !ok {
+		t.Fatalf("function was not converted to a Go func. got=%T", v)
+	}
+	sum, err := fn("a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != "ab" {
+		t.Errorf("wrong result. got=%#v", sum)
+	}
+}
+
+func TestLimitsAndContext(t *testing.T) {
+	in := New()
+	_, err := in.Eval("let f = fn(x) { f(x) }; f(1)")
+	if err == nil || err.(*object.Error).Message != "stack overflow" {
+		t.Errorf("unbounded recursion was not stopped. got=%v", err)
+	}
+
+	in.SetLimits(Limits{MaxSteps: 100})
+	_, err = in.Call("f", 1)
+	if err == nil || err.(*object.Error).Message != "step limit exceeded: 100" {
+		t.Errorf("Call did not apply the limits. got=%v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = in.EvalContext(ctx, "1")
+	if err == nil || err.(*object.Error).Message != "execution canceled: context canceled" {
+		t.Errorf("canceled context was ignored. got=%v", err)
+	}
+
+	// 制限は評価ごとに数え直す
+	if _, err := in.Eval("1 + 1"); err != nil {
+		t.Errorf("unexpected error after a failed evaluation: %s", err)
+	}
+}
+
+func TestOverflowPolicyAndBigInts(t *testing.T) {
+	in := New()
+	in.SetOverflowPolicy(OverflowPromote)
+
+	got, err := in.Eval("9223372036854775807 * 4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, ok := got.(*big.Int)
+	if !ok || n.String() != "36893488147419103228" {
+		t.Errorf("wrong result. got=%T (%v)", got, got)
+	}
+
+	huge, _ := new(big.Int).SetString("100000000000000000000", 10)
+	in.Set("huge", huge)
+	in.Set("small", big.NewInt(5))
+	in.Set("digits", func(n *big.Int) int { return len(n.String()) })
+
+	got, err = in.Eval("[small + 1, digits(huge * huge)]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []interface{}{int64(6), int64(41)}) {
+		t.Errorf("wrong result. got=%#v", got)
+	}
+
+	in.SetOverflowPolicy(OverflowError)
+	_, err = in.Eval("9223372036854775807 + 1")
+	if err == nil || err.(*object.Error).Message != "integer overflow: 9223372036854775807 + 1" {
+		t.Errorf("expected an overflow error. got=%v", err)
+	}
+}
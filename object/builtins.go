@@ -0,0 +1,328 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// putsの出力先
+var Stdout io.Writer = os.Stdout
+
+// 組み込み関数の一覧
+// evaluatorとvmの両方から参照するため、名前とインデックスの対応が変わらないようスライスで定義する
+// 戻り値が無い組み込み関数はnilを返し、呼び出し側でNULLに変換する
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{
+		"len",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *String:
+				return &Integer{Value: int64(arg.Len())}
+			case *Array:
+				return &Integer{Value: int64(len(arg.Elements))}
+			case *Hash:
+				return &Integer{Value: int64(len(arg.Pairs))}
+			case *Range:
+				return &Integer{Value: arg.Len()}
+			default:
+				return newError("argument to `len` not supported, got %s", args[0].Type())
+			}
+		},
+		},
+	},
+	{
+		"puts",
+		&Builtin{Fn: func(args ...Object) Object {
+			for _, arg := range args {
+				fmt.Fprintln(Stdout, arg.Inspect())
+			}
+
+			return nil
+		},
+		},
+	},
+	{
+		"first",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			if len(arr.Elements) > 0 {
+				return arr.Elements[0]
+			}
+
+			return nil
+		},
+		},
+	},
+	{
+		"last",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `last` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				return arr.Elements[length-1]
+			}
+
+			return nil
+		},
+		},
+	},
+	{
+		"rest",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				newElements := make([]Object, length-1, length-1)
+				copy(newElements, arr.Elements[1:length])
+				return &Array{Elements: newElements}
+			}
+			return nil
+		},
+		},
+	},
+	{
+		"push",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 2 {
+				return newError("wrong nubmer of arguments. got=%d, want=2", len(args))
+			}
+			if args[0].Type() != ARRAY_OBJ {
+				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+
+			newElements := make([]Object, length+1, length+1)
+			copy(newElements, arr.Elements)
+			newElements[length] = args[1]
+
+			return &Array{Elements: newElements}
+		},
+		},
+	},
+	{
+		"floor",
+		&Builtin{Fn: func(args ...Object) Object {
+			return roundToInteger("floor", math.Floor, args)
+		},
+		},
+	},
+	{
+		"ceil",
+		&Builtin{Fn: func(args ...Object) Object {
+			return roundToInteger("ceil", math.Ceil, args)
+		},
+		},
+	},
+	{
+		"round",
+		&Builtin{Fn: func(args ...Object) Object {
+			// 0.5は0から遠い方に丸める
+			return roundToInteger("round", math.Round, args)
+		},
+		},
+	},
+	{
+		"sqrt",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			x, ok := toFloat(args[0])
+			if !ok {
+				return newError("argument to `sqrt` must be INTEGER or FLOAT, got %s", args[0].Type())
+			}
+
+			return &Float{Value: math.Sqrt(x)}
+		},
+		},
+	},
+	{
+		"pow",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			// 整数の0以上の累乗は整数のまま正確に計算する
+			// int64に収まらない結果はBigIntで返し、呼び出し側でOverflowPolicyに従って扱う
+			exp, expIsInt := args[1].(*Integer)
+			if IsInteger(args[0]) && expIsInt && exp.Value >= 0 {
+				base := toBig(args[0])
+				if base.CmpAbs(big.NewInt(1)) > 0 && exp.Value > maxPowBits/int64(base.BitLen()) {
+					return newError("result of `pow` too large: %s ** %d", base, exp.Value)
+				}
+				return NormalizeInteger(new(big.Int).Exp(base, big.NewInt(exp.Value), nil), OverflowPromote)
+			}
+
+			x, ok := toFloat(args[0])
+			if !ok {
+				return newError("argument to `pow` must be INTEGER or FLOAT, got %s", args[0].Type())
+			}
+			y, ok := toFloat(args[1])
+			if !ok {
+				return newError("argument to `pow` must be INTEGER or FLOAT, got %s", args[1].Type())
+			}
+
+			return &Float{Value: math.Pow(x, y)}
+		},
+		},
+	},
+	{
+		// range(end), range(start, end), range(start, end, step)
+		"range",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("wrong number of arguments. got=%d, want=1..3", len(args))
+			}
+
+			values := make([]int64, len(args))
+			for i, arg := range args {
+				if !IsInteger(arg) {
+					return newError("argument to `range` must be INTEGER, got %s", arg.Type())
+				}
+				v, ok := Int64(arg)
+				if !ok {
+					return newError("argument to `range` out of range: %s", arg.Inspect())
+				}
+				values[i] = v
+			}
+
+			r := &Range{Start: 0, Step: 1}
+			switch len(values) {
+			case 1:
+				r.End = values[0]
+			case 2:
+				r.Start, r.End = values[0], values[1]
+			case 3:
+				r.Start, r.End, r.Step = values[0], values[1], values[2]
+			}
+			if r.Step == 0 {
+				return newError("`range` step must not be zero")
+			}
+
+			return r
+		},
+		},
+	},
+	// 文字列
+	{"split", &Builtin{Fn: builtinSplit}},
+	{"join", &Builtin{Fn: builtinJoin, Size: joinSize}},
+	{"trim", &Builtin{Fn: builtinTrim}},
+	{"upper", &Builtin{Fn: stringMapper("upper", strings.ToUpper)}},
+	{"lower", &Builtin{Fn: stringMapper("lower", strings.ToLower)}},
+	{"contains", &Builtin{Fn: stringPredicate("contains", strings.Contains)}},
+	{"index_of", &Builtin{Fn: builtinIndexOf}},
+	{"replace", &Builtin{Fn: builtinReplace, Size: replaceSize}},
+	{"starts_with", &Builtin{Fn: stringPredicate("starts_with", strings.HasPrefix)}},
+	{"ends_with", &Builtin{Fn: stringPredicate("ends_with", strings.HasSuffix)}},
+	{"substr", &Builtin{Fn: builtinSubstr}},
+	{"repeat", &Builtin{Fn: builtinRepeat, Size: repeatSize}},
+	{"format", &Builtin{Fn: builtinFormat}},
+	{"str", &Builtin{Fn: builtinStr}},
+	{"int", &Builtin{Fn: builtinInt}},
+	{"parse_int", &Builtin{Fn: builtinParseInt}},
+	// コレクション (rangeは上で定義している)
+	{"map", &Builtin{HigherOrder: builtinMap}},
+	{"filter", &Builtin{HigherOrder: builtinFilter}},
+	{"reduce", &Builtin{HigherOrder: builtinReduce}},
+	{"each", &Builtin{HigherOrder: builtinEach}},
+	{"sort", &Builtin{HigherOrder: builtinSort, Size: rangeSize}},
+	{"reverse", &Builtin{Fn: builtinReverse, Size: rangeSize}},
+	{"zip", &Builtin{Fn: builtinZip}},
+	{"flatten", &Builtin{Fn: builtinFlatten}},
+	{"any", &Builtin{HigherOrder: quantifier("any", true)}},
+	{"all", &Builtin{HigherOrder: quantifier("all", false)}},
+	// ハッシュ
+	{"keys", &Builtin{Fn: builtinKeys}},
+	{"values", &Builtin{Fn: builtinValues}},
+	{"entries", &Builtin{Fn: builtinEntries}},
+	{"has", &Builtin{Fn: builtinHas}},
+	{"delete", &Builtin{Fn: builtinDelete}},
+	{"merge", &Builtin{Fn: builtinMerge}},
+	{"set", &Builtin{Fn: builtinSet}},
+}
+
+// 整数の累乗の結果のビット数の上限
+const maxPowBits = 1 << 20
+
+// 数値を浮動小数点数として取り出す
+func toFloat(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *Float:
+		return obj.Value, true
+	case *BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// 浮動小数点数を丸めて整数にする 整数はそのまま返す
+func roundToInteger(name string, round func(float64) float64, args []Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *Integer, *BigInt:
+		return arg
+	case *Float:
+		v := round(arg.Value)
+		if math.IsNaN(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return newError("argument to `%s` out of range: %s", name, arg.Inspect())
+		}
+		return &Integer{Value: int64(v)}
+	default:
+		return newError("argument to `%s` must be INTEGER or FLOAT, got %s", name, args[0].Type())
+	}
+}
+
+// 名前から組み込み関数を取得する 見つからない場合はnil
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+	return nil
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
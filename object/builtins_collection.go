@@ -0,0 +1,354 @@
+package object
+
+import (
+	"sort"
+)
+
+// 配列などのコレクションを扱う組み込み関数
+// map, filter, reduce, each, any, all はfor文と同じく配列、文字列、ハッシュ (キー)、rangeを受け取る
+
+// map(iterable, fn)
+func builtinMap(call CallFunction, args ...Object) Object {
+	it, fn, err := iterableAndFunction("map", args)
+	if err != nil {
+		return err
+	}
+
+	elements := []Object{}
+	for {
+		el, ok := it.Next()
+		if !ok {
+			break
+		}
+		result := call(fn, el)
+		if isError(result) {
+			return result
+		}
+		elements = append(elements, result)
+	}
+	return &Array{Elements: elements}
+}
+
+// filter(iterable, fn) fnの結果が真になる要素を集める
+func builtinFilter(call CallFunction, args ...Object) Object {
+	it, fn, err := iterableAndFunction("filter", args)
+	if err != nil {
+		return err
+	}
+
+	elements := []Object{}
+	for {
+		el, ok := it.Next()
+		if !ok {
+			break
+		}
+		result := call(fn, el)
+		if isError(result) {
+			return result
+		}
+		if IsTruthy(result) {
+			elements = append(elements, el)
+		}
+	}
+	return &Array{Elements: elements}
+}
+
+// reduce(iterable, fn), reduce(iterable, fn, initial)
+// fnは (累積値, 要素) を受け取る 初期値を省略した場合は最初の要素を使う
+func builtinReduce(call CallFunction, args ...Object) Object {
+	if err := checkArity(args, 2, 3); err != nil {
+		return err
+	}
+	it, fn, err := iterableAndFunction("reduce", args[:2])
+	if err != nil {
+		return err
+	}
+
+	var acc Object
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		first, ok := it.Next()
+		if !ok {
+			return newError("`reduce` of empty %s with no initial value", args[0].Type())
+		}
+		acc = first
+	}
+
+	for {
+		el, ok := it.Next()
+		if !ok {
+			break
+		}
+		acc = call(fn, acc, el)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// each(iterable, fn) 各要素についてfnを呼び出す 戻り値は無い
+func builtinEach(call CallFunction, args ...Object) Object {
+	it, fn, err := iterableAndFunction("each", args)
+	if err != nil {
+		return err
+	}
+
+	for {
+		el, ok := it.Next()
+		if !ok {
+			break
+		}
+		if result := call(fn, el); isError(result) {
+			return result
+		}
+	}
+	return nil
+}
+
+// any(iterable, fn), all(iterable, fn)
+// 結果が決まった時点で残りの要素にはfnを呼び出さない
+func quantifier(name string, want bool) HigherOrderFunction {
+	return func(call CallFunction, args ...Object) Object {
+		it, fn, err := iterableAndFunction(name, args)
+		if err != nil {
+			return err
+		}
+
+		for {
+			el, ok := it.Next()
+			if !ok {
+				break
+			}
+			result := call(fn, el)
+			if isError(result) {
+				return result
+			}
+			if IsTruthy(result) == want {
+				return NativeBoolToBoolean(want)
+			}
+		}
+		return NativeBoolToBoolean(!want)
+	}
+}
+
+// sort(arr), sort(arr, less)
+// lessを省略した場合は数値どうし、文字列どうしを昇順に並べる
+// less(a, b) はaをbより前に置く場合に真を返す 並べ替えは安定で、元の配列は変更しない
+func builtinSort(call CallFunction, args ...Object) Object {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	var arr *Array
+	switch arg := args[0].(type) {
+	case *Array:
+		arr = arg
+	case *Range:
+		var err *Error
+		if arr, err = arg.toArray("sort"); err != nil {
+			return err
+		}
+	default:
+		return newError("argument to `sort` must be ARRAY or RANGE, got %s", args[0].Type())
+	}
+
+	var less func(a, b Object) (bool, *Error)
+	if len(args) == 2 {
+		if !isCallable(args[1]) {
+			return newError("argument to `sort` must be FUNCTION, got %s", args[1].Type())
+		}
+		less = func(a, b Object) (bool, *Error) {
+			result := call(args[1], a, b)
+			if err, ok := result.(*Error); ok {
+				return false, err
+			}
+			return IsTruthy(result), nil
+		}
+	} else {
+		less = defaultLess
+	}
+
+	elements := make([]Object, len(arr.Elements))
+	copy(elements, arr.Elements)
+
+	// 比較でエラーが起きたら以降の比較を打ち切る
+	var sortErr *Error
+	sort.SliceStable(elements, func(i, j int) bool {
+		if sortErr != nil {
+			return false
+		}
+		result, err := less(elements[i], elements[j])
+		if err != nil {
+			sortErr = err
+		}
+		return result
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+	return &Array{Elements: elements}
+}
+
+func defaultLess(a, b Object) (bool, *Error) {
+	if IsInteger(a) && IsInteger(b) {
+		return toBig(a).Cmp(toBig(b)) < 0, nil
+	}
+	x, xok := toFloat(a)
+	y, yok := toFloat(b)
+	if xok && yok {
+		return x < y, nil
+	}
+
+	as, aok := a.(*String)
+	bs, bok := b.(*String)
+	if aok && bok {
+		return as.Value < bs.Value, nil
+	}
+	return false, newError("`sort` cannot compare %s and %s", a.Type(), b.Type())
+}
+
+// reverse(arr), reverse(s)
+func builtinReverse(args ...Object) Object {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *Array:
+		n := len(arg.Elements)
+		elements := make([]Object, n)
+		for i, el := range arg.Elements {
+			elements[n-1-i] = el
+		}
+		return &Array{Elements: elements}
+	case *String:
+		runes := []rune(arg.Value)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return &String{Value: string(runes)}
+	case *Range:
+		arr, err := arg.toArray("reverse")
+		if err != nil {
+			return err
+		}
+		return builtinReverse(arr)
+	default:
+		return newError("argument to `reverse` must be ARRAY, STRING or RANGE, got %s", args[0].Type())
+	}
+}
+
+// zip(arr1, arr2, ...) 各配列の同じ位置の要素を組にする 長さは最も短い配列に合わせる
+func builtinZip(args ...Object) Object {
+	if len(args) < 2 {
+		return newError("wrong number of arguments. got=%d, want=2 or more", len(args))
+	}
+
+	arrays := make([]*Array, len(args))
+	length := -1
+	for i, arg := range args {
+		arr, ok := arg.(*Array)
+		if !ok {
+			return newError("argument to `zip` must be ARRAY, got %s", arg.Type())
+		}
+		arrays[i] = arr
+		if length < 0 || len(arr.Elements) < length {
+			length = len(arr.Elements)
+		}
+	}
+
+	elements := make([]Object, length)
+	for i := range elements {
+		tuple := make([]Object, len(arrays))
+		for j, arr := range arrays {
+			tuple[j] = arr.Elements[i]
+		}
+		elements[i] = &Array{Elements: tuple}
+	}
+	return &Array{Elements: elements}
+}
+
+// flatten(arr), flatten(arr, depth)
+// 入れ子の配列をdepth段 (省略した場合は1段) 展開する
+func builtinFlatten(args ...Object) Object {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument to `flatten` must be ARRAY, got %s", args[0].Type())
+	}
+
+	depth := int64(1)
+	if len(args) == 2 {
+		d, ok := args[1].(*Integer)
+		if !ok {
+			return newError("argument to `flatten` must be INTEGER, got %s", args[1].Type())
+		}
+		if d.Value < 0 {
+			return newError("`flatten` depth must not be negative, got %d", d.Value)
+		}
+		depth = d.Value
+	}
+
+	return &Array{Elements: flatten([]Object{}, arr, depth, map[*Array]bool{})}
+}
+
+// openは展開中の配列 自分自身を含む配列はそれ以上展開しない
+func flatten(out []Object, arr *Array, depth int64, open map[*Array]bool) []Object {
+	open[arr] = true
+	defer delete(open, arr)
+
+	for _, el := range arr.Elements {
+		if inner, ok := el.(*Array); ok && depth > 0 && !open[inner] {
+			out = flatten(out, inner, depth-1, open)
+			continue
+		}
+		out = append(out, el)
+	}
+	return out
+}
+
+// 1つ目の引数の反復子と2つ目の引数の関数を取り出す
+func iterableAndFunction(name string, args []Object) (Iterator, Object, *Error) {
+	if err := checkArity(args, 2, 2); err != nil {
+		return nil, nil, err
+	}
+
+	it, err := Iterate(args[0])
+	if err != nil {
+		return nil, nil, newError("argument to `%s` must be iterable, got %s", name, args[0].Type())
+	}
+	if !isCallable(args[1]) {
+		return nil, nil, newError("argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+	return it, args[1], nil
+}
+
+// 呼び出せる値 (evaluatorの関数、vmのクロージャ、組み込み関数)
+func isCallable(obj Object) bool {
+	switch obj.Type() {
+	case FUNCTION_OBJ, BUILTIN_OBJ:
+		return true
+	default:
+		return false
+	}
+}
+
+func isError(obj Object) bool {
+	_, ok := obj.(*Error)
+	return ok
+}
+
+// 真偽値として評価する nullとfalse以外は真
+func IsTruthy(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,108 @@
+package object
+
+import "testing"
+
+// テスト用の呼び出し方法 組み込み関数だけを呼び出せる
+func callBuiltin(fn Object, args ...Object) Object {
+	builtin, ok := fn.(*Builtin)
+	if !ok {
+		return newError("not a function: %s", fn.Type())
+	}
+	return builtin.Call(callBuiltin, args...)
+}
+
+func fnOf(f func(args ...Object) Object) *Builtin { return &Builtin{Fn: f} }
+
+func array(elements ...Object) *Array { return &Array{Elements: elements} }
+
+func TestCollectionBuiltins(t *testing.T) {
+	double := fnOf(func(args ...Object) Object {
+		return integer(args[0].(*Integer).Value * 2)
+	})
+	isEven := fnOf(func(args ...Object) Object {
+		return NativeBoolToBoolean(args[0].(*Integer).Value%2 == 0)
+	})
+	add := fnOf(func(args ...Object) Object {
+		return integer(args[0].(*Integer).Value + args[1].(*Integer).Value)
+	})
+	greater := fnOf(func(args ...Object) Object {
+		return NativeBoolToBoolean(args[0].(*Integer).Value > args[1].(*Integer).Value)
+	})
+	fail := fnOf(func(args ...Object) Object {
+		return newError("boom")
+	})
+
+	tests := []struct {
+		name     string
+		args     []Object
+		expected string // 結果のInspect エラーの場合は "ERROR: " とメッセージ
+	}{
+		{"map", []Object{array(integer(1), integer(2)), double}, "[2, 4]"},
+		{"map", []Object{&Range{Start: 0, End: 3, Step: 1}, double}, "[0, 2, 4]"},
+		{"map", []Object{str("ab"), GetBuiltinByName("upper")}, "[A, B]"},
+		{"map", []Object{array(), double}, "[]"},
+		{"map", []Object{integer(1), double}, "ERROR: argument to `map` must be iterable, got INTEGER"},
+		{"map", []Object{array(), integer(1)}, "ERROR: argument to `map` must be FUNCTION, got INTEGER"},
+		{"map", []Object{array(integer(1)), fail}, "ERROR: boom"},
+		{"filter", []Object{&Range{Start: 0, End: 6, Step: 1}, isEven}, "[0, 2, 4]"},
+		{"reduce", []Object{array(integer(1), integer(2), integer(3)), add}, "6"},
+		{"reduce", []Object{array(integer(1), integer(2)), add, integer(10)}, "13"},
+		{"reduce", []Object{array(), add, integer(10)}, "10"},
+		{"reduce", []Object{array(), add}, "ERROR: `reduce` of empty ARRAY with no initial value"},
+		{"reduce", []Object{array()}, "ERROR: wrong number of arguments. got=1, want=2..3"},
+		{"each", []Object{array(integer(1)), double}, "null"},
+		{"each", []Object{array(integer(1)), fail}, "ERROR: boom"},
+		{"any", []Object{array(integer(1), integer(2)), isEven}, "true"},
+		{"any", []Object{array(), isEven}, "false"},
+		{"all", []Object{array(integer(1), integer(2)), isEven}, "false"},
+		{"all", []Object{array(), isEven}, "true"},
+		// 結果が決まったら残りの要素は調べない
+		{"any", []Object{array(integer(2), str("x")), isEven}, "true"},
+		{"sort", []Object{array(integer(3), &Float{Value: 1.5}, integer(-1))}, "[-1, 1.5, 3]"},
+		{"sort", []Object{array(str("b"), str("a"))}, "[a, b]"},
+		{"sort", []Object{array(integer(1), integer(3), integer(2)), greater}, "[3, 2, 1]"},
+		{"sort", []Object{array(integer(1), str("a"))}, "ERROR: `sort` cannot compare STRING and INTEGER"},
+		{"sort", []Object{array(integer(1), integer(2)), fail}, "ERROR: boom"},
+		{"reverse", []Object{array(integer(1), integer(2))}, "[2, 1]"},
+		{"reverse", []Object{str("日本")}, "本日"},
+		{"reverse", []Object{integer(1)}, "ERROR: argument to `reverse` must be ARRAY, STRING or RANGE, got INTEGER"},
+		{"reverse", []Object{&Range{Start: 0, End: 3, Step: 1}}, "[2, 1, 0]"},
+		{"reverse", []Object{&Range{Start: 0, End: 1 << 40, Step: 1}}, "ERROR: argument to `reverse` too large: range(0, 1099511627776)"},
+		{"sort", []Object{&Range{Start: 3, End: 0, Step: -1}}, "[1, 2, 3]"},
+		{"sort", []Object{&Range{Start: 0, End: 10, Step: 4}, greater}, "[8, 4, 0]"},
+		{"sort", []Object{integer(1)}, "ERROR: argument to `sort` must be ARRAY or RANGE, got INTEGER"},
+		{"zip", []Object{array(integer(1), integer(2)), array(str("a"))}, "[[1, a]]"},
+		{"zip", []Object{array()}, "ERROR: wrong number of arguments. got=1, want=2 or more"},
+		{"flatten", []Object{array(integer(1), array(integer(2), array(integer(3))))}, "[1, 2, [3]]"},
+		{"flatten", []Object{array(array(array(integer(1)))), integer(0)}, "[[[1]]]"},
+		{"flatten", []Object{array(array(array(integer(1)))), integer(5)}, "[1]"},
+		{"flatten", []Object{array(), integer(-1)}, "ERROR: `flatten` depth must not be negative, got -1"},
+	}
+
+	for _, tt := range tests {
+		result := callBuiltin(GetBuiltinByName(tt.name), tt.args...)
+
+		got := "null"
+		switch result := result.(type) {
+		case nil:
+		case *Error:
+			got = "ERROR: " + result.Message
+		default:
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s(%v) wrong. want=%q, got=%q", tt.name, tt.args, tt.expected, got)
+		}
+	}
+}
+
+// sortは元の配列を変更しない
+func TestSortDoesNotModifyArgument(t *testing.T) {
+	arr := array(integer(2), integer(1))
+	callBuiltin(GetBuiltinByName("sort"), arr)
+
+	if arr.Inspect() != "[2, 1]" {
+		t.Errorf("argument was modified. got=%s", arr.Inspect())
+	}
+}
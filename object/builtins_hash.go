@@ -0,0 +1,151 @@
+package object
+
+// ハッシュを扱う組み込み関数
+// ハッシュを書き換える関数 (set, delete, merge) は引数のハッシュを変更せず新しいハッシュを返す
+// キーの順序はfor文と同じく追加した順
+
+// keys(h)
+func builtinKeys(args ...Object) Object {
+	h, err := singleHashArg("keys", args)
+	if err != nil {
+		return err
+	}
+	return &Array{Elements: h.Keys()}
+}
+
+// values(h)
+func builtinValues(args ...Object) Object {
+	h, err := singleHashArg("values", args)
+	if err != nil {
+		return err
+	}
+
+	entries := h.Entries()
+	elements := make([]Object, len(entries))
+	for i, pair := range entries {
+		elements[i] = pair.Value
+	}
+	return &Array{Elements: elements}
+}
+
+// entries(h) [キー, 値] の配列を返す
+func builtinEntries(args ...Object) Object {
+	h, err := singleHashArg("entries", args)
+	if err != nil {
+		return err
+	}
+
+	entries := h.Entries()
+	elements := make([]Object, len(entries))
+	for i, pair := range entries {
+		elements[i] = &Array{Elements: []Object{pair.Key, pair.Value}}
+	}
+	return &Array{Elements: elements}
+}
+
+// has(h, key)
+func builtinHas(args ...Object) Object {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	h, err := hashArg("has", args, 0)
+	if err != nil {
+		return err
+	}
+	key, err := hashKeyOf(args[1])
+	if err != nil {
+		return err
+	}
+
+	_, ok := h.Pairs[key]
+	return NativeBoolToBoolean(ok)
+}
+
+// set(h, key, value)
+func builtinSet(args ...Object) Object {
+	if err := checkArity(args, 3, 3); err != nil {
+		return err
+	}
+	h, err := hashArg("set", args, 0)
+	if err != nil {
+		return err
+	}
+	key, err := hashKeyOf(args[1])
+	if err != nil {
+		return err
+	}
+
+	result := copyHash(h)
+	result.Set(key, HashPair{Key: args[1], Value: args[2]})
+	return result
+}
+
+// delete(h, key) キーが無い場合は同じ内容のハッシュを返す
+func builtinDelete(args ...Object) Object {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	h, err := hashArg("delete", args, 0)
+	if err != nil {
+		return err
+	}
+	key, err := hashKeyOf(args[1])
+	if err != nil {
+		return err
+	}
+
+	result := copyHash(h)
+	result.Delete(key)
+	return result
+}
+
+// merge(h1, h2, ...) 同じキーは後のハッシュの値を使う
+func builtinMerge(args ...Object) Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want=1 or more", len(args))
+	}
+
+	result := NewHash()
+	for i := range args {
+		h, err := hashArg("merge", args, i)
+		if err != nil {
+			return err
+		}
+		for _, pair := range h.Entries() {
+			result.Set(pair.Key.(Hashable).HashKey(), pair)
+		}
+	}
+	return result
+}
+
+func copyHash(h *Hash) *Hash {
+	result := &Hash{Pairs: make(map[HashKey]HashPair, len(h.Pairs))}
+	for _, key := range h.Order {
+		result.Set(key, h.Pairs[key])
+	}
+	return result
+}
+
+func singleHashArg(name string, args []Object) (*Hash, *Error) {
+	if err := checkArity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return hashArg(name, args, 0)
+}
+
+// i番目の引数をハッシュとして取り出す
+func hashArg(name string, args []Object, i int) (*Hash, *Error) {
+	h, ok := args[i].(*Hash)
+	if !ok {
+		return nil, newError("argument to `%s` must be HASH, got %s", name, args[i].Type())
+	}
+	return h, nil
+}
+
+func hashKeyOf(obj Object) (HashKey, *Error) {
+	key, ok := obj.(Hashable)
+	if !ok {
+		return HashKey{}, newError("unusable as hash key: %s", obj.Type())
+	}
+	return key.HashKey(), nil
+}
@@ -0,0 +1,66 @@
+package object
+
+import "testing"
+
+func hash(pairs ...Object) *Hash {
+	h := NewHash()
+	for i := 0; i < len(pairs); i += 2 {
+		h.Set(pairs[i].(Hashable).HashKey(), HashPair{Key: pairs[i], Value: pairs[i+1]})
+	}
+	return h
+}
+
+func TestHashBuiltins(t *testing.T) {
+	h := hash(str("a"), integer(1))
+
+	tests := []struct {
+		name     string
+		args     []Object
+		expected string // 結果のInspect エラーの場合は "ERROR: " とメッセージ
+	}{
+		{"keys", []Object{h}, "[a]"},
+		{"keys", []Object{hash(integer(2), TRUE, integer(1), FALSE)}, "[2, 1]"},
+		{"keys", []Object{hash()}, "[]"},
+		{"keys", []Object{array()}, "ERROR: argument to `keys` must be HASH, got ARRAY"},
+		{"values", []Object{h}, "[1]"},
+		{"values", []Object{hash(integer(2), TRUE, integer(1), FALSE)}, "[true, false]"},
+		{"entries", []Object{h}, "[[a, 1]]"},
+		{"has", []Object{h, str("a")}, "true"},
+		{"has", []Object{h, str("b")}, "false"},
+		{"has", []Object{h, &Float{Value: 1.0}}, "false"},
+		{"has", []Object{hash(integer(1), TRUE), &Float{Value: 1.0}}, "true"},
+		{"has", []Object{h, array()}, "ERROR: unusable as hash key: ARRAY"},
+		{"has", []Object{h}, "ERROR: wrong number of arguments. got=1, want=2"},
+		{"set", []Object{h, str("a"), integer(2)}, "{a: 2}"},
+		{"set", []Object{hash(), str("b"), integer(2)}, "{b: 2}"},
+		{"delete", []Object{h, str("a")}, "{}"},
+		{"delete", []Object{h, str("x")}, "{a: 1}"},
+		{"merge", []Object{h, hash(str("a"), integer(9))}, "{a: 9}"},
+		{"merge", []Object{hash(), h}, "{a: 1}"},
+		{"merge", []Object{h, integer(1)}, "ERROR: argument to `merge` must be HASH, got INTEGER"},
+		{"merge", []Object{}, "ERROR: wrong number of arguments. got=0, want=1 or more"},
+		{"len", []Object{hash(integer(1), TRUE, integer(2), TRUE)}, "2"},
+	}
+
+	for _, tt := range tests {
+		result := GetBuiltinByName(tt.name).Fn(tt.args...)
+
+		got := "null"
+		switch result := result.(type) {
+		case nil:
+		case *Error:
+			got = "ERROR: " + result.Message
+		default:
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s(%v) wrong. want=%q, got=%q", tt.name, tt.args, tt.expected, got)
+		}
+	}
+
+	// 引数のハッシュは変更されない
+	if h.Inspect() != "{a: 1}" {
+		t.Errorf("argument was modified. got=%s", h.Inspect())
+	}
+}
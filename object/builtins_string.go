@@ -0,0 +1,515 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 文字列を扱う組み込み関数
+// 位置と長さはlenや添字と同じくコードポイント単位で数える
+
+// repeatなどで作る文字列のバイト数の上限
+const maxStringBytes = 1 << 26
+
+// formatの幅と精度の上限
+const maxFormatWidth = 1 << 16
+
+// split(s), split(s, sep)
+// sepを省略した場合は空白文字で区切る sepが空文字列の場合は1文字ずつに分ける
+func builtinSplit(args ...Object) Object {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	s, err := stringArg("split", args, 0)
+	if err != nil {
+		return err
+	}
+
+	var parts []string
+	if len(args) == 1 {
+		parts = strings.Fields(s)
+	} else {
+		sep, err := stringArg("split", args, 1)
+		if err != nil {
+			return err
+		}
+		parts = strings.Split(s, sep)
+	}
+
+	elements := make([]Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &String{Value: part}
+	}
+	return &Array{Elements: elements}
+}
+
+// join(arr), join(arr, sep)
+func builtinJoin(args ...Object) Object {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument to `join` must be ARRAY, got %s", args[0].Type())
+	}
+
+	sep := ""
+	if len(args) == 2 {
+		var err *Error
+		if sep, err = stringArg("join", args, 1); err != nil {
+			return err
+		}
+	}
+
+	parts := make([]string, len(arr.Elements))
+	for i, el := range arr.Elements {
+		str, ok := el.(*String)
+		if !ok {
+			return newError("elements of `join` must be STRING, got %s", el.Type())
+		}
+		parts[i] = str.Value
+	}
+	if joinSize(args...) > maxStringBytes {
+		return newError("result of `join` too large")
+	}
+	return &String{Value: strings.Join(parts, sep)}
+}
+
+// joinの結果のバイト数
+func joinSize(args ...Object) int64 {
+	if len(args) == 0 {
+		return 0
+	}
+	arr, ok := args[0].(*Array)
+	if !ok || len(arr.Elements) == 0 {
+		return 0
+	}
+
+	var size int64
+	for _, el := range arr.Elements {
+		if str, ok := el.(*String); ok {
+			size += int64(len(str.Value))
+		}
+	}
+	if len(args) == 2 {
+		if sep, ok := args[1].(*String); ok {
+			size += int64(len(arr.Elements)-1) * int64(len(sep.Value))
+		}
+	}
+	return size
+}
+
+// trim(s), trim(s, cutset)
+// cutsetを省略した場合は前後の空白文字を取り除く
+func builtinTrim(args ...Object) Object {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	s, err := stringArg("trim", args, 0)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		return &String{Value: strings.TrimSpace(s)}
+	}
+	cutset, err := stringArg("trim", args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: strings.Trim(s, cutset)}
+}
+
+// 文字列を受け取り文字列を返す組み込み関数
+func stringMapper(name string, f func(string) string) func(args ...Object) Object {
+	return func(args ...Object) Object {
+		if err := checkArity(args, 1, 1); err != nil {
+			return err
+		}
+		s, err := stringArg(name, args, 0)
+		if err != nil {
+			return err
+		}
+		return &String{Value: f(s)}
+	}
+}
+
+// 2つの文字列を受け取り真偽値を返す組み込み関数
+func stringPredicate(name string, f func(string, string) bool) func(args ...Object) Object {
+	return func(args ...Object) Object {
+		s, sub, err := twoStringArgs(name, args)
+		if err != nil {
+			return err
+		}
+		return NativeBoolToBoolean(f(s, sub))
+	}
+}
+
+// index_of(s, sub) 見つからない場合は-1
+func builtinIndexOf(args ...Object) Object {
+	s, sub, err := twoStringArgs("index_of", args)
+	if err != nil {
+		return err
+	}
+
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return &Integer{Value: -1}
+	}
+	return &Integer{Value: int64(utf8.RuneCountInString(s[:i]))}
+}
+
+// replace(s, old, new) すべての出現を置き換える
+func builtinReplace(args ...Object) Object {
+	if err := checkArity(args, 3, 3); err != nil {
+		return err
+	}
+
+	var values [3]string
+	for i := range values {
+		v, err := stringArg("replace", args, i)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+
+	if values[1] == "" {
+		return newError("`replace` old string must not be empty")
+	}
+
+	if replaceSize(args...) > maxStringBytes {
+		return newError("result of `replace` too large")
+	}
+	return &String{Value: strings.ReplaceAll(values[0], values[1], values[2])}
+}
+
+// replaceの結果のバイト数
+func replaceSize(args ...Object) int64 {
+	if len(args) != 3 {
+		return 0
+	}
+	s, ok1 := args[0].(*String)
+	old, ok2 := args[1].(*String)
+	new, ok3 := args[2].(*String)
+	if !ok1 || !ok2 || !ok3 || old.Value == "" {
+		return 0
+	}
+
+	n := int64(strings.Count(s.Value, old.Value))
+	return int64(len(s.Value)) + n*(int64(len(new.Value))-int64(len(old.Value)))
+}
+
+// substr(s, start), substr(s, start, end)
+// endは含まない 範囲外の位置は文字列の両端に切り詰める
+func builtinSubstr(args ...Object) Object {
+	if err := checkArity(args, 2, 3); err != nil {
+		return err
+	}
+	s, err := stringArg("substr", args, 0)
+	if err != nil {
+		return err
+	}
+
+	runes := []rune(s)
+	bounds := []int64{0, int64(len(runes))}
+	for i := 1; i < len(args); i++ {
+		n, ok := args[i].(*Integer)
+		if !ok {
+			return newError("argument to `substr` must be INTEGER, got %s", args[i].Type())
+		}
+		bounds[i-1] = clamp(n.Value, 0, int64(len(runes)))
+	}
+
+	start, end := bounds[0], bounds[1]
+	if end < start {
+		end = start
+	}
+	return &String{Value: string(runes[start:end])}
+}
+
+// repeat(s, n)
+func builtinRepeat(args ...Object) Object {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	s, err := stringArg("repeat", args, 0)
+	if err != nil {
+		return err
+	}
+	n, ok := args[1].(*Integer)
+	if !ok {
+		return newError("argument to `repeat` must be INTEGER, got %s", args[1].Type())
+	}
+
+	if n.Value < 0 {
+		return newError("`repeat` count must not be negative, got %d", n.Value)
+	}
+	if repeatSize(args...) > maxStringBytes {
+		return newError("result of `repeat` too large")
+	}
+	return &String{Value: strings.Repeat(s, int(n.Value))}
+}
+
+// repeatの結果のバイト数 maxStringBytesを超える場合はmaxStringBytes+1にする
+func repeatSize(args ...Object) int64 {
+	if len(args) != 2 {
+		return 0
+	}
+	s, ok1 := args[0].(*String)
+	n, ok2 := args[1].(*Integer)
+	if !ok1 || !ok2 || n.Value <= 0 || len(s.Value) == 0 {
+		return 0
+	}
+
+	if n.Value > maxStringBytes/int64(len(s.Value)) {
+		return maxStringBytes + 1
+	}
+	return int64(len(s.Value)) * n.Value
+}
+
+// format(fmt, args...)
+// 書式指定はprintfと同じ形で %d %f %e %g %x %s %q %v %% を使える
+// %s と %v は値をputsと同じ表現で埋め込む
+func builtinFormat(args ...Object) Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want=1 or more", len(args))
+	}
+	format, err := stringArg("format", args, 0)
+	if err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	values := args[1:]
+	next := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		// フラグ、幅、精度を読んで変換指定子を探す
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		var width, precision int
+		width, i = readWidth(format, i)
+		if i < len(format) && format[i] == '.' {
+			precision, i = readWidth(format, i+1)
+		}
+		if i == len(format) {
+			return newError("`format` verb missing at end of format string")
+		}
+
+		verb := format[i]
+		spec := format[start : i+1]
+		if width > maxFormatWidth || precision > maxFormatWidth {
+			return newError("`format` width or precision too large in %s", spec)
+		}
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+
+		if next == len(values) {
+			return newError("not enough arguments to `format` for %s", spec)
+		}
+		s, err := formatValue(spec, verb, values[next])
+		if err != nil {
+			return err
+		}
+		if out.Len()+len(s) > maxStringBytes {
+			return newError("result of `format` too large")
+		}
+		out.WriteString(s)
+		next++
+	}
+
+	if next < len(values) {
+		return newError("too many arguments to `format`. got=%d, used=%d", len(values), next)
+	}
+	return &String{Value: out.String()}
+}
+
+// format[i:]の先頭の数字を読み、値と数字の後ろの位置を返す
+// maxFormatWidthを超える値はmaxFormatWidth+1にする
+func readWidth(format string, i int) (int, int) {
+	n := 0
+	for ; i < len(format) && '0' <= format[i] && format[i] <= '9'; i++ {
+		if n <= maxFormatWidth {
+			n = n*10 + int(format[i]-'0')
+		}
+	}
+	if n > maxFormatWidth {
+		n = maxFormatWidth + 1
+	}
+	return n, i
+}
+
+func formatValue(spec string, verb byte, value Object) (string, *Error) {
+	switch verb {
+	case 'd':
+		if !IsInteger(value) {
+			return "", newError("`format` %s requires INTEGER, got %s", spec, value.Type())
+		}
+		return fmt.Sprintf(spec, toBig(value)), nil
+	case 'x', 'X':
+		if IsInteger(value) {
+			return fmt.Sprintf(spec, toBig(value)), nil
+		}
+		if str, ok := value.(*String); ok {
+			return fmt.Sprintf(spec, str.Value), nil
+		}
+		return "", newError("`format` %s requires INTEGER or STRING, got %s", spec, value.Type())
+	case 'f', 'e', 'E', 'g', 'G':
+		f, ok := toFloat(value)
+		if !ok {
+			return "", newError("`format` %s requires INTEGER or FLOAT, got %s", spec, value.Type())
+		}
+		return fmt.Sprintf(spec, f), nil
+	case 's', 'v':
+		return fmt.Sprintf(spec[:len(spec)-1]+"s", value.Inspect()), nil
+	case 'q':
+		return fmt.Sprintf(spec, value.Inspect()), nil
+	default:
+		return "", newError("unknown `format` verb %s", spec)
+	}
+}
+
+// str(x) 値をputsと同じ表現の文字列にする
+func builtinStr(args ...Object) Object {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	if str, ok := args[0].(*String); ok {
+		return str
+	}
+	return &String{Value: args[0].Inspect()}
+}
+
+// int(x) 整数、浮動小数点数 (0に向かって切り捨て)、真偽値、10進数の文字列を整数にする
+func builtinInt(args ...Object) Object {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *Integer, *BigInt:
+		return arg
+	case *Float:
+		v := math.Trunc(arg.Value)
+		if math.IsNaN(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return newError("argument to `int` out of range: %s", arg.Inspect())
+		}
+		return &Integer{Value: int64(v)}
+	case *Boolean:
+		if arg.Value {
+			return &Integer{Value: 1}
+		}
+		return &Integer{Value: 0}
+	case *String:
+		v, outOfRange := parseInteger(arg.Value, 10)
+		if outOfRange {
+			return newError("argument to `int` out of range: %q", arg.Value)
+		}
+		if v == nil {
+			return newError("could not convert %q to INTEGER", arg.Value)
+		}
+		return v
+	default:
+		return newError("argument to `int` not supported, got %s", arg.Type())
+	}
+}
+
+// parse_int(s), parse_int(s, base)
+// 整数として読めない文字列やint64に収まらない場合はnullを返す
+func builtinParseInt(args ...Object) Object {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	s, err := stringArg("parse_int", args, 0)
+	if err != nil {
+		return err
+	}
+
+	base := int64(10)
+	if len(args) == 2 {
+		b, ok := args[1].(*Integer)
+		if !ok {
+			return newError("argument to `parse_int` must be INTEGER, got %s", args[1].Type())
+		}
+		if b.Value < 2 || b.Value > 36 {
+			return newError("`parse_int` base must be between 2 and 36, got %d", b.Value)
+		}
+		base = b.Value
+	}
+
+	if v, _ := parseInteger(s, int(base)); v != nil {
+		return v
+	}
+	return nil
+}
+
+// 前後の空白を除いた文字列を整数として読む 読めない場合はnil
+// int64に収まらない場合はoutOfRangeが真になる
+// OverflowPolicyは演算の結果に対するもので、文字列の読み取りには使わない
+func parseInteger(s string, base int) (v Object, outOfRange bool) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), base, 64)
+	if err != nil {
+		return nil, errors.Is(err, strconv.ErrRange)
+	}
+	return &Integer{Value: n}, false
+}
+
+// 引数の数を確かめる 正しい場合はnil
+func checkArity(args []Object, min, max int) *Error {
+	if len(args) >= min && len(args) <= max {
+		return nil
+	}
+	if min == max {
+		return newError("wrong number of arguments. got=%d, want=%d", len(args), min)
+	}
+	return newError("wrong number of arguments. got=%d, want=%d..%d", len(args), min, max)
+}
+
+// i番目の引数を文字列として取り出す
+func stringArg(name string, args []Object, i int) (string, *Error) {
+	str, ok := args[i].(*String)
+	if !ok {
+		return "", newError("argument to `%s` must be STRING, got %s", name, args[i].Type())
+	}
+	return str.Value, nil
+}
+
+func twoStringArgs(name string, args []Object) (string, string, *Error) {
+	if err := checkArity(args, 2, 2); err != nil {
+		return "", "", err
+	}
+	a, err := stringArg(name, args, 0)
+	if err != nil {
+		return "", "", err
+	}
+	b, err := stringArg(name, args, 1)
+	if err != nil {
+		return "", "", err
+	}
+	return a, b, nil
+}
+
+func clamp(v, min, max int64) int64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
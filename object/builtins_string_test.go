@@ -0,0 +1,125 @@
+package object
+
+import (
+	"strings"
+	"testing"
+)
+
+func str(s string) *String { return &String{Value: s} }
+
+func integer(v int64) *Integer { return &Integer{Value: v} }
+
+func TestStringBuiltins(t *testing.T) {
+	// 結果がmaxStringBytesを超える引数
+	empties := &Array{Elements: make([]Object, 1<<11)}
+	for i := range empties.Elements {
+		empties.Elements[i] = str("")
+	}
+	manyArgs := []Object{str(strings.Repeat("%s", 1<<10+1))}
+	for i := 0; i < 1<<10+1; i++ {
+		manyArgs = append(manyArgs, str(strings.Repeat("a", 1<<16)))
+	}
+
+	tests := []struct {
+		name     string
+		args     []Object
+		expected string // 結果のInspect エラーの場合は "ERROR: " とメッセージ
+	}{
+		{"split", []Object{str("a,b,,c"), str(",")}, "[a, b, , c]"},
+		{"split", []Object{str("  a b\tc ")}, "[a, b, c]"},
+		{"split", []Object{str("日本"), str("")}, "[日, 本]"},
+		{"split", []Object{integer(1)}, "ERROR: argument to `split` must be STRING, got INTEGER"},
+		{"join", []Object{&Array{Elements: []Object{str("a"), str("b")}}, str("-")}, "a-b"},
+		{"join", []Object{&Array{Elements: []Object{str("a"), str("b")}}}, "ab"},
+		{"join", []Object{&Array{Elements: []Object{integer(1)}}}, "ERROR: elements of `join` must be STRING, got INTEGER"},
+		{"join", []Object{str("a")}, "ERROR: argument to `join` must be ARRAY, got STRING"},
+		{"join", []Object{empties, str(strings.Repeat("-", 1<<16))}, "ERROR: result of `join` too large"},
+		{"trim", []Object{str(" \t x \n")}, "x"},
+		{"trim", []Object{str("--x--"), str("-")}, "x"},
+		{"upper", []Object{str("abcé")}, "ABCÉ"},
+		{"lower", []Object{str("ÀB")}, "àb"},
+		{"lower", []Object{}, "ERROR: wrong number of arguments. got=0, want=1"},
+		{"contains", []Object{str("hello"), str("ell")}, "true"},
+		{"contains", []Object{str("hello"), str("x")}, "false"},
+		{"contains", []Object{str("hello")}, "ERROR: wrong number of arguments. got=1, want=2"},
+		{"index_of", []Object{str("日本語"), str("語")}, "2"},
+		{"index_of", []Object{str("abc"), str("z")}, "-1"},
+		{"replace", []Object{str("a-b-c"), str("-"), str("+")}, "a+b+c"},
+		{"replace", []Object{str("abc"), str(""), str("+")}, "ERROR: `replace` old string must not be empty"},
+		{"replace", []Object{str(strings.Repeat("a", 1<<13)), str("a"), str(strings.Repeat("a", 1<<14))}, "ERROR: result of `replace` too large"},
+		{"replace", []Object{str(strings.Repeat("ab", 1<<13)), str("ab"), str("")}, ""},
+		{"starts_with", []Object{str("monkey"), str("mon")}, "true"},
+		{"ends_with", []Object{str("monkey"), str("mon")}, "false"},
+		{"substr", []Object{str("日本語です"), integer(1), integer(3)}, "本語"},
+		{"substr", []Object{str("abc"), integer(1)}, "bc"},
+		{"substr", []Object{str("abc"), integer(-5), integer(10)}, "abc"},
+		{"substr", []Object{str("abc"), integer(2), integer(1)}, ""},
+		{"substr", []Object{str("abc"), str("1")}, "ERROR: argument to `substr` must be INTEGER, got STRING"},
+		{"repeat", []Object{str("ab"), integer(3)}, "ababab"},
+		{"repeat", []Object{str("ab"), integer(0)}, ""},
+		{"repeat", []Object{str("ab"), integer(-1)}, "ERROR: `repeat` count must not be negative, got -1"},
+		{"repeat", []Object{str("ab"), integer(1 << 40)}, "ERROR: result of `repeat` too large"},
+		{"format", []Object{str("%d|%5.2f|%s|%-3v|%q|%x|%%"), integer(42), &Float{Value: 3.14159}, str("s"), TRUE, str("q"), integer(255)}, `42| 3.14|s|true|"q"|ff|%`},
+		{"format", []Object{str("%03d"), integer(7)}, "007"},
+		{"format", []Object{str("%.1f"), integer(2)}, "2.0"},
+		{"format", []Object{str("%d"), str("x")}, "ERROR: `format` %d requires INTEGER, got STRING"},
+		{"format", []Object{str("%d %d"), integer(1)}, "ERROR: not enough arguments to `format` for %d"},
+		{"format", []Object{str("%d"), integer(1), integer(2)}, "ERROR: too many arguments to `format`. got=2, used=1"},
+		{"format", []Object{str("%z"), integer(1)}, "ERROR: unknown `format` verb %z"},
+		{"format", []Object{str("100%")}, "ERROR: `format` verb missing at end of format string"},
+		{"format", []Object{str("%0900000000d"), integer(1)}, "ERROR: `format` width or precision too large in %0900000000d"},
+		{"format", []Object{str("%.70000f"), integer(1)}, "ERROR: `format` width or precision too large in %.70000f"},
+		{"format", []Object{str("%5-d"), integer(1)}, "ERROR: unknown `format` verb %5-"},
+		{"format", []Object{str("%1.2.3d"), integer(1)}, "ERROR: unknown `format` verb %1.2."},
+		{"format", []Object{str("%-6d|%+.3e"), integer(42), &Float{Value: 1500}}, "42    |+1.500e+03"},
+		{"format", manyArgs, "ERROR: result of `format` too large"},
+		{"format", []Object{}, "ERROR: wrong number of arguments. got=0, want=1 or more"},
+		{"str", []Object{integer(12)}, "12"},
+		{"str", []Object{&Array{Elements: []Object{integer(1), str("a")}}}, "[1, a]"},
+		{"int", []Object{str(" -42 ")}, "-42"},
+		{"int", []Object{&Float{Value: -3.9}}, "-3"},
+		{"int", []Object{TRUE}, "1"},
+		{"int", []Object{str("9223372036854775807")}, "9223372036854775807"},
+		{"int", []Object{str("99999999999999999999")}, "ERROR: argument to `int` out of range: \"99999999999999999999\""},
+		{"int", []Object{str("-9223372036854775809")}, "ERROR: argument to `int` out of range: \"-9223372036854775809\""},
+		{"int", []Object{str("4x")}, `ERROR: could not convert "4x" to INTEGER`},
+		{"int", []Object{str("--4")}, `ERROR: could not convert "--4" to INTEGER`},
+		{"int", []Object{&Float{Value: 1e300}}, "ERROR: argument to `int` out of range: 1e+300"},
+		{"int", []Object{&Array{}}, "ERROR: argument to `int` not supported, got ARRAY"},
+		{"parse_int", []Object{str("ff"), integer(16)}, "255"},
+		{"parse_int", []Object{str("-101"), integer(2)}, "-5"},
+		{"parse_int", []Object{str("12")}, "12"},
+		{"parse_int", []Object{str("1_000")}, "null"},
+		{"parse_int", []Object{str("")}, "null"},
+		{"parse_int", []Object{str("+7")}, "7"},
+		{"parse_int", []Object{str("ffffffffffffffffff"), integer(16)}, "null"},
+		{"parse_int", []Object{str("1"), integer(37)}, "ERROR: `parse_int` base must be between 2 and 36, got 37"},
+	}
+
+	for _, tt := range tests {
+		result := GetBuiltinByName(tt.name).Fn(tt.args...)
+
+		got := "null"
+		switch result := result.(type) {
+		case nil:
+		case *Error:
+			got = "ERROR: " + result.Message
+		default:
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s(%v) wrong. want=%q, got=%q", tt.name, tt.args, tt.expected, got)
+		}
+	}
+}
+
+// 真偽値を返す組み込み関数は共有の真偽値オブジェクトを返す
+func TestStringPredicatesReturnSharedBooleans(t *testing.T) {
+	if GetBuiltinByName("contains").Fn(str("ab"), str("a")) != TRUE {
+		t.Errorf("contains did not return TRUE")
+	}
+	if GetBuiltinByName("starts_with").Fn(str("ab"), str("b")) != FALSE {
+		t.Errorf("starts_with did not return FALSE")
+	}
+}
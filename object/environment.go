@@ -27,8 +27,31 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// 既存の束縛を書き換える
+// 束縛が定義された環境まで外側を辿って書き換え、見つからなければokがfalseになる
+func (e *Environment) Assign(name string, val Object) (Object, bool) {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return val, true
+		}
+	}
+	return nil, false
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
 	return env
-}
\ No newline at end of file
+}
+
+// 環境とその外側の環境で束縛されている名前の一覧
+func (e *Environment) Names() []string {
+	names := []string{}
+	for env := e; env != nil; env = env.outer {
+		for name := range env.store {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,128 @@
+package object
+
+// ==, != で使う値の比較
+//
+// 数値は型が違っても値で比べ (1 == 1.0)、文字列、配列、ハッシュは中身で比べる
+// それ以外で型が違う値は等しくない 関数などの中身を比べられない値は同じオブジェクトの場合だけ等しい
+
+// 中身で比べられるオブジェクト
+type Equatable interface {
+	Equals(other Object) bool
+}
+
+// 深く入れ子になった配列やハッシュでもGoのスタックを使い切らないよう、
+// 比べる値の組を明示的なスタックに積んで比べる
+func Equals(a, b Object) bool {
+	seen := map[visit]bool{}
+	stack := []visit{{a, b}}
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		var equal bool
+		if stack, equal = compare(v.a, v.b, stack, seen); !equal {
+			return false
+		}
+	}
+	return true
+}
+
+// 比較中の配列やハッシュの組
+// 自分自身を含む配列やハッシュは、比較中の組にもう一度出会った場合に等しいとみなす
+type visit struct {
+	a, b Object
+}
+
+// aとbを比べる 配列とハッシュは長さとキーだけを比べ、要素の組をstackに積んで返す
+func compare(a, b Object, stack []visit, seen map[visit]bool) ([]visit, bool) {
+	// NaNは自分自身とも等しくないので、同一かどうかより先に数値として比べる
+	if IsInteger(a) && IsInteger(b) {
+		return stack, CompareIntegers(a, b) == 0
+	}
+	x, xok := toFloat(a)
+	y, yok := toFloat(b)
+	if xok && yok {
+		return stack, x == y
+	}
+
+	if a == b {
+		return stack, true
+	}
+	switch a := a.(type) {
+	case *Array:
+		o, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(o.Elements) {
+			return stack, false
+		}
+		if seen[visit{a, o}] {
+			return stack, true
+		}
+		seen[visit{a, o}] = true
+
+		// 先頭の要素から比べるよう逆順に積む
+		for i := len(a.Elements) - 1; i >= 0; i-- {
+			stack = append(stack, visit{a.Elements[i], o.Elements[i]})
+		}
+		return stack, true
+	case *Hash:
+		o, ok := b.(*Hash)
+		if !ok || len(a.Pairs) != len(o.Pairs) {
+			return stack, false
+		}
+		if seen[visit{a, o}] {
+			return stack, true
+		}
+		seen[visit{a, o}] = true
+
+		for key, pair := range a.Pairs {
+			otherPair, ok := o.Pairs[key]
+			if !ok {
+				return stack, false
+			}
+			stack = append(stack, visit{pair.Value, otherPair.Value})
+		}
+		return stack, true
+	}
+	if eq, ok := a.(Equatable); ok {
+		return stack, eq.Equals(b)
+	}
+	return stack, false
+}
+
+func (s *String) Equals(other Object) bool {
+	o, ok := other.(*String)
+	return ok && s.Value == o.Value
+}
+
+func (b *Boolean) Equals(other Object) bool {
+	o, ok := other.(*Boolean)
+	return ok && b.Value == o.Value
+}
+
+func (n *Null) Equals(other Object) bool {
+	_, ok := other.(*Null)
+	return ok
+}
+
+// 同じ整数を同じ順に返す範囲は等しい
+func (r *Range) Equals(other Object) bool {
+	o, ok := other.(*Range)
+	if !ok {
+		return false
+	}
+	n := r.Len()
+	if n != o.Len() {
+		return false
+	}
+	return n == 0 || r.Start == o.Start && (n == 1 || r.Step == o.Step)
+}
+
+// 同じ長さで、同じ位置の要素がすべて等しい場合に等しい
+func (ao *Array) Equals(other Object) bool {
+	return Equals(ao, other)
+}
+
+// 同じキーの組を持ち、それぞれの値が等しい場合に等しい キーの順序は問わない
+func (h *Hash) Equals(other Object) bool {
+	return Equals(h, other)
+}
@@ -0,0 +1,95 @@
+package object
+
+import (
+	"math"
+	"math/big"
+	"runtime/debug"
+	"testing"
+)
+
+func TestEquals(t *testing.T) {
+	arr := array(integer(1))
+	builtin := GetBuiltinByName("len")
+	null := &Null{}
+
+	// 自分自身を含む配列とハッシュ
+	selfArray := func(first Object) *Array {
+		a := array(first, integer(0))
+		a.Elements[1] = a
+		return a
+	}
+	selfHash := func() *Hash {
+		h := hash(str("h"), integer(0))
+		key := str("h")
+		h.Set(key.HashKey(), HashPair{Key: key, Value: h})
+		return h
+	}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{integer(1), integer(1), true},
+		{integer(1), &Float{Value: 1.0}, true},
+		{&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}, &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}, true},
+		{&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}, integer(1), false},
+		{&Float{Value: math.NaN()}, &Float{Value: math.NaN()}, false},
+		{str("a"), str("a"), true},
+		{str("a"), str("b"), false},
+		{str("1"), integer(1), false},
+		{TRUE, &Boolean{Value: true}, true},
+		{TRUE, FALSE, false},
+		{null, &Null{}, true},
+		{null, FALSE, false},
+		{array(integer(1), str("a")), array(&Float{Value: 1}, str("a")), true},
+		{array(integer(1)), array(integer(1), integer(2)), false},
+		{array(array()), array(array()), true},
+		{arr, arr, true},
+		{hash(str("a"), integer(1), str("b"), integer(2)), hash(str("b"), integer(2), str("a"), integer(1)), true},
+		{hash(str("a"), integer(1)), hash(str("a"), integer(2)), false},
+		{hash(str("a"), integer(1)), hash(str("a"), integer(1), str("b"), integer(1)), false},
+		{hash(), array(), false},
+		{builtin, builtin, true},
+		{builtin, GetBuiltinByName("first"), false},
+		{selfArray(integer(1)), selfArray(integer(1)), true},
+		{selfArray(integer(1)), selfArray(integer(2)), false},
+		{selfArray(integer(1)), array(integer(1), array(integer(1))), false},
+		{selfHash(), selfHash(), true},
+		{array(selfHash()), array(selfArray(integer(1))), false},
+		{&Range{Start: 0, End: 3, Step: 1}, &Range{Start: 0, End: 3, Step: 1}, true},
+		{&Range{Start: 0, End: 10, Step: 3}, &Range{Start: 0, End: 11, Step: 3}, true},
+		{&Range{Start: 5, End: 5, Step: 1}, &Range{Start: 0, End: -1, Step: 2}, true},
+		{&Range{Start: 0, End: 3, Step: 1}, &Range{Start: 0, End: 3, Step: 2}, false},
+		{&Range{Start: 0, End: 3, Step: 1}, array(integer(0), integer(1), integer(2)), false},
+	}
+
+	for _, tt := range tests {
+		if got := Equals(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Equals(%s, %s) wrong. want=%t, got=%t", tt.a.Inspect(), tt.b.Inspect(), tt.expected, got)
+		}
+		// 比較は対称
+		if got := Equals(tt.b, tt.a); got != tt.expected {
+			t.Errorf("Equals(%s, %s) wrong. want=%t, got=%t", tt.b.Inspect(), tt.a.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+// 深く入れ子になった配列の比較でGoのスタックを使い切らない
+func TestEqualsDeeplyNested(t *testing.T) {
+	defer debug.SetMaxStack(debug.SetMaxStack(16 << 20))
+
+	nest := func(depth int, last Object) Object {
+		obj := last
+		for i := 0; i < depth; i++ {
+			obj = array(obj)
+		}
+		return obj
+	}
+
+	if !Equals(nest(1<<20, integer(1)), nest(1<<20, integer(1))) {
+		t.Errorf("equal nested arrays are not equal")
+	}
+	if Equals(nest(1<<20, integer(1)), nest(1<<20, integer(2))) {
+		t.Errorf("different nested arrays are equal")
+	}
+}
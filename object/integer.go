@@ -0,0 +1,251 @@
+package object
+
+import (
+	"hash/fnv"
+	"math"
+	"math/big"
+)
+
+// int64に収まらない整数
+// OverflowPromoteのときに演算の結果として現れる
+// int64に収まる値は常にIntegerで表す
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() ObjectType {
+	return BIGINT_OBJ
+}
+
+func (b *BigInt) Inspect() string {
+	return b.Value.String()
+}
+
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write(b.Value.Bytes())
+	if b.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+// 整数演算の結果がint64に収まらない場合の扱い
+type OverflowPolicy int
+
+const (
+	OverflowWrap    OverflowPolicy = iota // 2の補数で折り返す (Goのint64と同じ)
+	OverflowError                         // エラーにする
+	OverflowPromote                       // BigIntにする
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowWrap:
+		return "wrap"
+	case OverflowError:
+		return "error"
+	case OverflowPromote:
+		return "promote"
+	default:
+		return "OverflowPolicy(?)"
+	}
+}
+
+// 名前からOverflowPolicyを得る
+func ParseOverflowPolicy(name string) (OverflowPolicy, bool) {
+	for _, p := range []OverflowPolicy{OverflowWrap, OverflowError, OverflowPromote} {
+		if p.String() == name {
+			return p, true
+		}
+	}
+	return OverflowWrap, false
+}
+
+// INTEGERまたはBIGINT
+func IsInteger(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInt:
+		return true
+	}
+	return false
+}
+
+// 整数同士の + - * / %
+// ゼロ除算はエラーになり、int64に収まらない結果はpolicyに従って扱う
+func IntegerArithmetic(operator string, left, right Object, policy OverflowPolicy) Object {
+	l, lok := left.(*Integer)
+	r, rok := right.(*Integer)
+	if lok && rok {
+		if result, ok := int64Arithmetic(operator, l.Value, r.Value, policy); ok {
+			return result
+		}
+	}
+
+	x, y := toBig(left), toBig(right)
+	z := new(big.Int)
+
+	switch operator {
+	case "+":
+		z.Add(x, y)
+	case "-":
+		z.Sub(x, y)
+	case "*":
+		z.Mul(x, y)
+	case "/":
+		if y.Sign() == 0 {
+			return newError("division by zero")
+		}
+		// Goの整数除算と同じく0の方向に切り捨てる
+		z.Quo(x, y)
+	case "%":
+		if y.Sign() == 0 {
+			return newError("division by zero")
+		}
+		// 余りの符号は左辺と同じになる
+		z.Rem(x, y)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	if !z.IsInt64() && policy == OverflowError {
+		return newError("integer overflow: %s %s %s", left.Inspect(), operator, right.Inspect())
+	}
+	return NormalizeInteger(z, policy)
+}
+
+// int64のまま計算できる場合はその結果を返す
+// 桁あふれする場合はokがfalseになる (OverflowWrapでは常に計算する)
+func int64Arithmetic(operator string, a, b int64, policy OverflowPolicy) (Object, bool) {
+	wrap := policy == OverflowWrap
+
+	switch operator {
+	case "+":
+		c := a + b
+		if wrap || (c > a) == (b > 0) {
+			return &Integer{Value: c}, true
+		}
+	case "-":
+		c := a - b
+		if wrap || (c < a) == (b > 0) {
+			return &Integer{Value: c}, true
+		}
+	case "*":
+		if a == 0 || b == 0 {
+			return &Integer{Value: 0}, true
+		}
+		c := a * b
+		if wrap || (c/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)) {
+			return &Integer{Value: c}, true
+		}
+	case "/":
+		if b == 0 {
+			return newError("division by zero"), true
+		}
+		if wrap || !(a == math.MinInt64 && b == -1) {
+			return &Integer{Value: a / b}, true
+		}
+	case "%":
+		if b == 0 {
+			return newError("division by zero"), true
+		}
+		// 余りは桁あふれしない (math.MinInt64 % -1 も0になる)
+		return &Integer{Value: a % b}, true
+	}
+	return nil, false
+}
+
+// 整数の符号を反転する
+func NegateInteger(obj Object, policy OverflowPolicy) Object {
+	if i, ok := obj.(*Integer); ok && (i.Value != math.MinInt64 || policy == OverflowWrap) {
+		return &Integer{Value: -i.Value}
+	}
+
+	z := new(big.Int).Neg(toBig(obj))
+	if !z.IsInt64() && policy == OverflowError {
+		return newError("integer overflow: -(%s)", obj.Inspect())
+	}
+	return NormalizeInteger(z, policy)
+}
+
+// 整数同士を比較し、left < right なら-1、等しければ0、left > right なら1を返す
+func CompareIntegers(left, right Object) int {
+	l, lok := left.(*Integer)
+	r, rok := right.(*Integer)
+	if lok && rok {
+		switch {
+		case l.Value < r.Value:
+			return -1
+		case l.Value > r.Value:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return toBig(left).Cmp(toBig(right))
+}
+
+// 任意精度の整数をpolicyに従ってIntegerかBigIntにする
+// int64に収まる値は常にIntegerになる
+func NormalizeInteger(v *big.Int, policy OverflowPolicy) Object {
+	if v.IsInt64() {
+		return &Integer{Value: v.Int64()}
+	}
+
+	switch policy {
+	case OverflowPromote:
+		return &BigInt{Value: v}
+	case OverflowError:
+		return newError("integer overflow: %s", v.String())
+	default:
+		// 下位64ビットを2の補数として解釈する
+		low := new(big.Int).And(v, new(big.Int).SetUint64(math.MaxUint64))
+		return &Integer{Value: int64(low.Uint64())}
+	}
+}
+
+// 組み込み関数などが返したBigIntをpolicyに従って扱う
+// BigInt以外はそのまま返す
+func ApplyOverflowPolicy(obj Object, policy OverflowPolicy) Object {
+	if b, ok := obj.(*BigInt); ok {
+		return NormalizeInteger(b.Value, policy)
+	}
+	return obj
+}
+
+// 整数をint64として得る
+// int64に収まらないBigIntと整数以外の値ではokがfalseになる
+func Int64(obj Object) (v int64, ok bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value, true
+	case *BigInt:
+		if obj.Value.IsInt64() {
+			return obj.Value.Int64(), true
+		}
+	}
+	return 0, false
+}
+
+// 配列と文字列の添字に使うBigIntをIntegerにする
+// int64に収まらないBigIntは範囲外のエラーになり、BigInt以外はそのまま返す
+func IndexInteger(index Object) Object {
+	if _, ok := index.(*BigInt); !ok {
+		return index
+	}
+	v, ok := Int64(index)
+	if !ok {
+		return newError("index out of range: %s", index.Inspect())
+	}
+	return &Integer{Value: v}
+}
+
+func toBig(obj Object) *big.Int {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value)
+	case *BigInt:
+		return obj.Value
+	}
+	return new(big.Int)
+}
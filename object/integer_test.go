@@ -0,0 +1,146 @@
+package object
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestIntegerArithmetic(t *testing.T) {
+	max := &Integer{Value: math.MaxInt64}
+	min := &Integer{Value: math.MinInt64}
+	one := &Integer{Value: 1}
+	minusOne := &Integer{Value: -1}
+	two := &Integer{Value: 2}
+	zero := &Integer{Value: 0}
+
+	tests := []struct {
+		operator    string
+		left, right Object
+		policy      OverflowPolicy
+		expected    string
+	}{
+		{"+", one, two, OverflowError, "3"},
+		{"-", one, two, OverflowError, "-1"},
+		{"*", two, two, OverflowError, "4"},
+		{"/", &Integer{Value: 7}, two, OverflowError, "3"},
+		{"/", &Integer{Value: -7}, two, OverflowError, "-3"},
+		{"%", &Integer{Value: 7}, two, OverflowError, "1"},
+		{"%", &Integer{Value: -7}, two, OverflowError, "-1"},
+		{"%", min, minusOne, OverflowError, "0"},
+		{"%", one, zero, OverflowPromote, "ERROR: division by zero"},
+		{"%", &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}, &Integer{Value: 10}, OverflowPromote, "6"},
+
+		{"+", max, one, OverflowWrap, "-9223372036854775808"},
+		{"+", max, one, OverflowError, "ERROR: integer overflow: 9223372036854775807 + 1"},
+		{"+", max, one, OverflowPromote, "9223372036854775808"},
+		{"-", min, one, OverflowWrap, "9223372036854775807"},
+		{"-", min, one, OverflowError, "ERROR: integer overflow: -9223372036854775808 - 1"},
+		{"-", min, one, OverflowPromote, "-9223372036854775809"},
+		{"*", max, two, OverflowWrap, "-2"},
+		{"*", max, two, OverflowError, "ERROR: integer overflow: 9223372036854775807 * 2"},
+		{"*", max, two, OverflowPromote, "18446744073709551614"},
+		{"*", min, minusOne, OverflowError, "ERROR: integer overflow: -9223372036854775808 * -1"},
+		{"*", minusOne, min, OverflowPromote, "9223372036854775808"},
+		{"/", min, minusOne, OverflowWrap, "-9223372036854775808"},
+		{"/", min, minusOne, OverflowError, "ERROR: integer overflow: -9223372036854775808 / -1"},
+		{"/", min, minusOne, OverflowPromote, "9223372036854775808"},
+		{"-", min, minusOne, OverflowError, "-9223372036854775807"},
+		{"+", min, max, OverflowError, "-1"},
+
+		{"/", one, zero, OverflowWrap, "ERROR: division by zero"},
+		{"/", one, zero, OverflowPromote, "ERROR: division by zero"},
+	}
+
+	for _, tt := range tests {
+		result := IntegerArithmetic(tt.operator, tt.left, tt.right, tt.policy)
+		if got := result.Inspect(); got != tt.expected {
+			t.Errorf("%s %s %s (%s): want=%s, got=%s",
+				tt.left.Inspect(), tt.operator, tt.right.Inspect(), tt.policy, tt.expected, got)
+		}
+	}
+}
+
+func TestBigIntResultsAreNormalized(t *testing.T) {
+	big := IntegerArithmetic("+", &Integer{Value: math.MaxInt64}, &Integer{Value: 1}, OverflowPromote)
+	if _, ok := big.(*BigInt); !ok {
+		t.Fatalf("result is not BigInt. got=%T (%+v)", big, big)
+	}
+
+	// int64に戻った結果はIntegerになる
+	back := IntegerArithmetic("-", big, &Integer{Value: 1}, OverflowPromote)
+	integer, ok := back.(*Integer)
+	if !ok || integer.Value != math.MaxInt64 {
+		t.Errorf("result is not Integer %d. got=%T (%+v)", int64(math.MaxInt64), back, back)
+	}
+
+	if CompareIntegers(big, back) != 1 || CompareIntegers(back, big) != -1 || CompareIntegers(big, big) != 0 {
+		t.Errorf("CompareIntegers gave wrong results")
+	}
+
+	other := IntegerArithmetic("+", &Integer{Value: math.MaxInt64}, &Integer{Value: 1}, OverflowPromote)
+	if big.(*BigInt).HashKey() != other.(*BigInt).HashKey() {
+		t.Errorf("equal BigInts have different hash keys")
+	}
+	neg := NegateInteger(big, OverflowPromote)
+	if neg.(Hashable).HashKey() == big.(*BigInt).HashKey() {
+		t.Errorf("BigInts with different signs have the same hash key")
+	}
+}
+
+func TestNegateInteger(t *testing.T) {
+	min := &Integer{Value: math.MinInt64}
+
+	tests := []struct {
+		policy   OverflowPolicy
+		expected string
+	}{
+		{OverflowWrap, "-9223372036854775808"},
+		{OverflowError, "ERROR: integer overflow: -(-9223372036854775808)"},
+		{OverflowPromote, "9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		if got := NegateInteger(min, tt.policy).Inspect(); got != tt.expected {
+			t.Errorf("-MinInt64 (%s): want=%s, got=%s", tt.policy, tt.expected, got)
+		}
+	}
+}
+
+func TestInt64(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		expected int64
+		ok       bool
+	}{
+		{&Integer{Value: 5}, 5, true},
+		// ホストが作ったint64に収まるBigInt
+		{&BigInt{Value: big.NewInt(-3)}, -3, true},
+		{&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}, 0, false},
+		{&String{Value: "5"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		v, ok := Int64(tt.obj)
+		if v != tt.expected || ok != tt.ok {
+			t.Errorf("Int64(%s): want=(%d, %t), got=(%d, %t)", tt.obj.Inspect(), tt.expected, tt.ok, v, ok)
+		}
+	}
+
+
+	others := []struct {
+		call     string
+		result   Object
+		expected string
+	}{
+		{"range(BigInt 3)", GetBuiltinByName("range").Fn(&BigInt{Value: big.NewInt(3)}), "range(0, 3)"},
+		{"IndexInteger(BigInt 1)", IndexInteger(&BigInt{Value: big.NewInt(1)}), "1"},
+		{"IndexInteger(2 ** 64)", IndexInteger(&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}), "ERROR: index out of range: 18446744073709551616"},
+	}
+
+	for _, tt := range others {
+		if got := tt.result.Inspect(); got != tt.expected {
+			t.Errorf("%s: want=%s, got=%s", tt.call, tt.expected, got)
+		}
+	}
+}
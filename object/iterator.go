@@ -0,0 +1,178 @@
+package object
+
+import (
+	"fmt"
+	"math"
+)
+
+// range(start, end, step) が返す整数の範囲
+// 要素を配列として確保せず、for-inで1つずつ取り出す
+type Range struct {
+	Start, End, Step int64
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+
+func (r *Range) Inspect() string {
+	if r.Step == 1 {
+		return fmt.Sprintf("range(%d, %d)", r.Start, r.End)
+	}
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+// 配列にできる範囲の要素の数の上限
+const maxRangeElements = 1 << 24
+
+// 範囲の要素の数 int64に収まらない場合はmath.MaxInt64にする
+func (r *Range) Len() int64 {
+	var n uint64
+	switch {
+	case r.Step > 0 && r.Start < r.End:
+		n = (uint64(r.End)-uint64(r.Start)-1)/uint64(r.Step) + 1
+	case r.Step < 0 && r.Start > r.End:
+		n = (uint64(r.Start)-uint64(r.End)-1)/uint64(-r.Step) + 1
+	}
+	if n > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(n)
+}
+
+// i番目の要素 負の添字は末尾から数え、範囲外の場合はfalseを返す
+func (r *Range) At(i int64) (Object, bool) {
+	i, ok := NormalizeIndex(i, r.Len())
+	if !ok {
+		return nil, false
+	}
+	return &Integer{Value: r.Start + i*r.Step}, true
+}
+
+// 要素を並べた配列 nameは要素が多すぎる場合のエラーメッセージに使う組み込み関数の名前
+func (r *Range) toArray(name string) (*Array, *Error) {
+	n := r.Len()
+	if n > maxRangeElements {
+		return nil, newError("argument to `%s` too large: %s", name, r.Inspect())
+	}
+
+	elements := make([]Object, n)
+	for i := range elements {
+		elements[i] = &Integer{Value: r.Start + int64(i)*r.Step}
+	}
+	return &Array{Elements: elements}, nil
+}
+
+// sortとreverseに範囲を渡した場合に作る配列の要素の数
+func rangeSize(args ...Object) int64 {
+	if len(args) == 0 {
+		return 0
+	}
+	r, ok := args[0].(*Range)
+	if !ok {
+		return 0
+	}
+	if n := r.Len(); n <= maxRangeElements {
+		return n
+	}
+	return maxRangeElements + 1
+}
+
+// for-inで値を順に取り出す
+// Nextは値が無くなるとfalseを返す
+type Iterator interface {
+	Next() (Object, bool)
+}
+
+// for-inで反復できるオブジェクトのIteratorを返す
+// 配列は要素、文字列は1文字ずつの文字列、ハッシュはキー、Rangeは整数を順に返す
+// 反復できないオブジェクトの場合はエラーを返す
+func Iterate(obj Object) (Iterator, *Error) {
+	switch obj := obj.(type) {
+	case *Array:
+		return &arrayIterator{array: obj}, nil
+	case *String:
+		chars := []Object{}
+		for _, r := range obj.Value {
+			chars = append(chars, &String{Value: string(r)})
+		}
+		return &sliceIterator{elements: chars}, nil
+	case *Hash:
+		return &sliceIterator{elements: obj.Keys()}, nil
+	case *Range:
+		return &rangeIterator{next: obj.Start, r: obj}, nil
+	default:
+		return nil, newError("not iterable: %s", obj.Type())
+	}
+}
+
+// 配列の要素を添字の順に返す
+// ループ中に書き換えられた要素はその値が返る
+type arrayIterator struct {
+	array *Array
+	index int
+}
+
+func (it *arrayIterator) Next() (Object, bool) {
+	if it.index >= len(it.array.Elements) {
+		return nil, false
+	}
+	elem := it.array.Elements[it.index]
+	it.index++
+	return elem, true
+}
+
+type sliceIterator struct {
+	elements []Object
+	index    int
+}
+
+func (it *sliceIterator) Next() (Object, bool) {
+	if it.index >= len(it.elements) {
+		return nil, false
+	}
+	elem := it.elements[it.index]
+	it.index++
+	return elem, true
+}
+
+type rangeIterator struct {
+	next int64
+	r    *Range
+	done bool
+}
+
+func (it *rangeIterator) Next() (Object, bool) {
+	if it.done {
+		return nil, false
+	}
+	if (it.r.Step > 0 && it.next >= it.r.End) || (it.r.Step < 0 && it.next <= it.r.End) {
+		return nil, false
+	}
+
+	value := it.next
+	// 桁あふれする場合はそこで終わる
+	if (it.r.Step > 0 && value > math.MaxInt64-it.r.Step) || (it.r.Step < 0 && value < math.MinInt64-it.r.Step) {
+		it.done = true
+	} else {
+		it.next += it.r.Step
+	}
+	return &Integer{Value: value}, true
+}
+
+// ハッシュのキーと値の組の一覧 キーを追加した順に並べる
+func (h *Hash) Entries() []HashPair {
+	result := make([]HashPair, len(h.Order))
+	for i, key := range h.Order {
+		result[i] = h.Pairs[key]
+	}
+	return result
+}
+
+// ハッシュのキーの一覧 順序はEntriesと同じ
+func (h *Hash) Keys() []Object {
+	entries := h.Entries()
+	result := make([]Object, len(entries))
+	for i, pair := range entries {
+		result[i] = pair.Key
+	}
+	return result
+}
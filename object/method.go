@@ -0,0 +1,51 @@
+package object
+
+// 型ごとのメソッド表
+// メソッドはレシーバーを最初の引数として受け取る組み込み関数で、"abc".upper() は upper("abc") と同じ
+var methods = map[ObjectType]map[string]*Builtin{}
+
+// 型にメソッドを登録する 同じ名前のメソッドは置き換える
+func RegisterMethod(t ObjectType, name string, method *Builtin) {
+	if methods[t] == nil {
+		methods[t] = map[string]*Builtin{}
+	}
+	methods[t][name] = method
+}
+
+// 値のメソッドをレシーバーに束縛した関数として返す 無い場合はnil
+func GetMethod(receiver Object, name string) *Builtin {
+	method, ok := methods[receiver.Type()][name]
+	if !ok {
+		return nil
+	}
+
+	return &Builtin{HigherOrder: func(call CallFunction, args ...Object) Object {
+		return method.Call(call, append([]Object{receiver}, args...)...)
+	}}
+}
+
+// 組み込み関数をそのままメソッドにする
+func init() {
+	tables := []struct {
+		t     ObjectType
+		names []string
+	}{
+		{STRING_OBJ, []string{
+			"len", "split", "trim", "upper", "lower", "contains", "index_of", "replace",
+			"starts_with", "ends_with", "substr", "repeat", "format", "reverse", "int", "parse_int",
+		}},
+		{ARRAY_OBJ, []string{
+			"len", "first", "last", "rest", "push", "join", "map", "filter", "reduce",
+			"each", "any", "all", "sort", "reverse", "zip", "flatten",
+		}},
+		{HASH_OBJ, []string{
+			"len", "keys", "values", "entries", "has", "delete", "merge", "set",
+		}},
+	}
+
+	for _, table := range tables {
+		for _, name := range table.names {
+			RegisterMethod(table.t, name, GetBuiltinByName(name))
+		}
+	}
+}
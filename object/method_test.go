@@ -0,0 +1,67 @@
+package object
+
+import "testing"
+
+func TestMember(t *testing.T) {
+	h := NewHash()
+	for _, key := range []string{"name", "keys"} {
+		k := str(key)
+		h.Set(k.HashKey(), HashPair{Key: k, Value: str(key + "!")})
+	}
+	mod := &Module{Name: "lib.mk", Exports: map[string]Object{"pi": integer(3)}}
+
+	tests := []struct {
+		obj      Object
+		name     string
+		args     []Object // nilでなければメンバーを関数として呼び出す
+		expected string   // 結果のInspect エラーの場合は "ERROR: " とメッセージ
+	}{
+		{h, "name", nil, "name!"},
+		// ハッシュのキーはメソッドより優先する
+		{h, "keys", nil, "keys!"},
+		{h, "values", []Object{}, "[name!, keys!]"},
+		{h, "missing", nil, "null"},
+		{str("abc"), "upper", []Object{}, "ABC"},
+		{str("a-b"), "split", []Object{str("-")}, "[a, b]"},
+		{str("abc"), "len", []Object{}, "3"},
+		{str("abc"), "foo", nil, "ERROR: unknown method foo for STRING"},
+		{array(integer(1)), "push", []Object{integer(2)}, "[1, 2]"},
+		{array(integer(2), integer(1)), "sort", []Object{}, "[1, 2]"},
+		{array(), "upper", nil, "ERROR: unknown method upper for ARRAY"},
+		{integer(1), "x", nil, "ERROR: member access not supported: INTEGER"},
+		{mod, "pi", nil, "3"},
+		{mod, "e", nil, "ERROR: module lib.mk does not export e"},
+	}
+
+	for _, tt := range tests {
+		result := Member(tt.obj, tt.name)
+		if tt.args != nil {
+			result = callBuiltin(result, tt.args...)
+		}
+
+		got := "null"
+		switch result := result.(type) {
+		case nil:
+		case *Error:
+			got = "ERROR: " + result.Message
+		default:
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s.%s wrong. want=%q, got=%q", tt.obj.Inspect(), tt.name, tt.expected, got)
+		}
+	}
+}
+
+// 登録したメソッドはレシーバーを最初の引数として受け取る
+func TestRegisterMethod(t *testing.T) {
+	RegisterMethod(BOOLEAN_OBJ, "not", fnOf(func(args ...Object) Object {
+		return NativeBoolToBoolean(!args[0].(*Boolean).Value)
+	}))
+	defer delete(methods, BOOLEAN_OBJ)
+
+	if result := callBuiltin(Member(TRUE, "not")); result != FALSE {
+		t.Errorf("wrong result. got=%v", result)
+	}
+}
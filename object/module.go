@@ -0,0 +1,47 @@
+package object
+
+import "fmt"
+
+// importで読み込んだモジュール
+// Nameは読み込んだファイルのパス、Exportsはexportされた束縛
+type Module struct {
+	Name    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+
+func (m *Module) Inspect() string {
+	return fmt.Sprintf("module(%s)", m.Name)
+}
+
+// obj.name の値
+// モジュールはexportされた束縛、ハッシュは文字列nameのキーの値を返す
+// それ以外はレシーバーに束縛したメソッドを返す ハッシュのキーはメソッドより優先する
+func Member(obj Object, name string) Object {
+	switch obj := obj.(type) {
+	case *Module:
+		if value, ok := obj.Exports[name]; ok {
+			return value
+		}
+		return newError("module %s does not export %s", obj.Name, name)
+	case *Hash:
+		key := &String{Value: name}
+		if pair, ok := obj.Pairs[key.HashKey()]; ok {
+			return pair.Value
+		}
+		if method := GetMethod(obj, name); method != nil {
+			return method
+		}
+		// h["name"] と同じく、無いキーはnull
+		return nil
+	}
+
+	if method := GetMethod(obj, name); method != nil {
+		return method
+	}
+	if _, ok := methods[obj.Type()]; ok {
+		return newError("unknown method %s for %s", name, obj.Type())
+	}
+	return newError("member access not supported: %s", obj.Type())
+}
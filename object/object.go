@@ -4,7 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/code"
+	"github.com/kakts/monkey/token"
+	"math"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"hash/fnv"
 )
@@ -13,6 +18,8 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ = "INTEGER"
+	FLOAT_OBJ = "FLOAT"
+	BIGINT_OBJ = "BIGINT"
 	BOOLEAN_OBJ = "BOOLEAN"
 	NULL_OBJ = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
@@ -22,6 +29,11 @@ const (
 	BUILTIN_OBJ = "BUILTIN"
 	ARRAY_OBJ = "ARRAY"
 	HASH_OBJ = "HASH"
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	BREAK_OBJ = "BREAK"
+	CONTINUE_OBJ = "CONTINUE"
+	RANGE_OBJ = "RANGE"
+	MODULE_OBJ = "MODULE"
 )
 type Object interface {
 	Type() ObjectType
@@ -40,6 +52,23 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+// 整数と区別できるよう、整数値の場合も小数点を付けて表示する
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -52,6 +81,20 @@ func (b *Boolean) Inspect() string {
 	return fmt.Sprintf("%t", b.Value)
 }
 
+// 真偽値は値ごとに1つのオブジェクトを共有する
+// evaluatorとvmは真偽値をポインタで比べるため、組み込み関数も必ずこれらを返す
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
+func NativeBoolToBoolean(input bool) *Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
 // なんの値もラップしていない
 type Null struct {
 }
@@ -76,8 +119,30 @@ func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
 
+// ループの制御 ReturnValueと同様にブロックの評価を中断してループまで伝わる
+type Break struct{}
+
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
 type Error struct {
 	Message string
+	Pos token.Position // エラーが発生したノードの位置
 }
 
 func (e *Error) Type() ObjectType {
@@ -85,7 +150,15 @@ func (e *Error) Type() ObjectType {
 }
 
 func (e *Error) Inspect() string {
-	return "ERROR: " + e.Message
+	return "ERROR: " + e.Error()
+}
+
+// errorインタフェースを満たし、Goのエラーとしても扱えるようにする
+func (e *Error) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Message
+	}
+	return e.Message
 }
 
 // monkeyの関数はその関数独自の環境を保つため、object.Environmentへのポインタも持つ
@@ -131,11 +204,90 @@ func (s *String) Inspect() string {
 	return s.Value
 }
 
+// 文字列の長さ (バイト数ではなくコードポイントの数)
+func (s *String) Len() int {
+	return utf8.RuneCountInString(s.Value)
+}
+
+// i番目 (0始まり、コードポイント単位) の文字 負の場合は末尾から数える
+// 範囲外の場合はfalseを返す
+func (s *String) CharAt(i int64) (*String, bool) {
+	if i < 0 {
+		i += int64(s.Len())
+		if i < 0 {
+			return nil, false
+		}
+	}
+
+	n := int64(0)
+	for _, r := range s.Value {
+		if n == i {
+			return &String{Value: string(r)}, true
+		}
+		n++
+	}
+	return nil, false
+}
+
+// コンパイル済みの関数
+// NumLocalsはローカル束縛の数 (引数を含む)
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	Positions     code.SourceMap // 命令とソースコード上の位置の対応 実行時エラーの位置に使う
+	NumLocals     int
+	NumParameters int
+}
+
+func (cf *CompiledFunction) Type() ObjectType {
+	return COMPILED_FUNCTION_OBJ
+}
+
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// vmにおけるクロージャ
+// コンパイル済みの関数と、生成時に捕捉した自由変数を持つ
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+// evaluatorの関数と同じ型名にし、エラーメッセージを揃える
+func (c *Closure) Type() ObjectType {
+	return FUNCTION_OBJ
+}
+
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
 // 組み込み関数
 type BuiltinFunction func(args ...Object) Object
 
+// 引数で受け取った関数を呼び出す組み込み関数 (mapなど)
+// callはevaluatorとvmがそれぞれ用意する
+type HigherOrderFunction func(call CallFunction, args ...Object) Object
+
+// 関数 (ユーザー定義の関数または組み込み関数) を呼び出し、戻り値を返す
+// 実行時エラーは*Errorとして返る
+type CallFunction func(fn Object, args ...Object) Object
+
+// FnとHigherOrderのどちらか一方を設定する
+// Sizeは大きな値を作る組み込み関数 (repeatなど) が設定し、作る前に結果の大きさ (文字列はバイト数、配列は要素の数) を返す
+// 評価器はこの大きさを割り当ての制限と比べてから呼び出す 引数が不正な場合は0を返す
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn          BuiltinFunction
+	HigherOrder HigherOrderFunction
+	Size        func(args ...Object) int64
+}
+
+// 組み込み関数を呼び出す
+func (b *Builtin) Call(call CallFunction, args ...Object) Object {
+	if b.HigherOrder != nil {
+		return b.HigherOrder(call, args...)
+	}
+	return b.Fn(args...)
 }
 
 func (b *Builtin) Type() ObjectType {
@@ -155,18 +307,7 @@ func (ao *Array) Type() ObjectType {
 }
 
 func (ao *Array) Inspect() string {
-	var out bytes.Buffer
-
-	elements := []string{}
-	for _, e := range ao.Elements {
-		elements = append(elements, e.Inspect())
-	}
-
-	out.WriteString("[")
-	out.WriteString(strings.Join(elements, ", "))
-	out.WriteString("]")
-
-	return out.String()
+	return inspect(ao, map[Object]bool{})
 }
 
 type HashKey struct {
@@ -189,6 +330,18 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// 1 == 1.0 のように整数と等しい浮動小数点数は、その整数と同じキーになる
+// -0.0は0.0と、NaNはすべて同じキーとして扱う
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+	}
+	if math.IsNaN(f.Value) {
+		return HashKey{Type: f.Type(), Value: math.Float64bits(math.NaN())}
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -203,8 +356,36 @@ type HashPair struct {
 	Value Object
 }
 
+// PairsはHashKeyで引くためのもの、Orderはキーを追加した順序を保持する
+// 要素を追加・削除する場合はPairsを直接書き換えずSetとDeleteを使う
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Order []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// 既にあるキーの場合は値だけを置き換え、順序は変えない
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Order = append(h.Order, key)
+	}
+	h.Pairs[key] = pair
+}
+
+func (h *Hash) Delete(key HashKey) {
+	if _, ok := h.Pairs[key]; !ok {
+		return
+	}
+	delete(h.Pairs, key)
+	for i, k := range h.Order {
+		if k == key {
+			h.Order = append(h.Order[:i:i], h.Order[i+1:]...)
+			break
+		}
+	}
 }
 
 func (h *Hash) Type() ObjectType {
@@ -212,17 +393,51 @@ func (h *Hash) Type() ObjectType {
 }
 
 func (h *Hash) Inspect() string {
+	return inspect(h, map[Object]bool{})
+}
+
+// 配列とハッシュの文字列表現
+// seenは表示中の配列とハッシュで、自分自身を含む場合はその位置を [...] や {...} と表示する
+func inspect(obj Object, seen map[Object]bool) string {
 	var out bytes.Buffer
 
-	pairs := []string{}
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	switch obj := obj.(type) {
+	case *Array:
+		if seen[obj] {
+			return "[...]"
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		elements := []string{}
+		for _, e := range obj.Elements {
+			elements = append(elements, inspect(e, seen))
+		}
+
+		out.WriteString("[")
+		out.WriteString(strings.Join(elements, ", "))
+		out.WriteString("]")
+
+	case *Hash:
+		if seen[obj] {
+			return "{...}"
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		pairs := []string{}
+		for _, pair := range obj.Entries() {
+			pairs = append(pairs, fmt.Sprintf("%s: %s", inspect(pair.Key, seen), inspect(pair.Value, seen)))
+		}
+
+		out.WriteString("{")
+		out.WriteString(strings.Join(pairs, ", "))
+		out.WriteString("}")
+
+	default:
+		return obj.Inspect()
 	}
 
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
-
 	return out.String()
 }
 
@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,4 +23,202 @@ func TestStringHashKey(t *testing.T) {
 	if hello1.HashKey() == diff1.HashKey() {
 		t.Errorf("strings with different content have same hash keys")
 	}
-}
\ No newline at end of file
+}
+
+func TestFloatHashKey(t *testing.T) {
+	tests := []struct {
+		a, b  Hashable
+		equal bool
+	}{
+		{&Float{Value: 1.5}, &Float{Value: 1.5}, true},
+		{&Float{Value: 1.5}, &Float{Value: 2.5}, false},
+		// 整数と等しい浮動小数点数は整数と同じキー
+		{&Float{Value: 2.0}, &Integer{Value: 2}, true},
+		{&Float{Value: 0.0}, &Float{Value: math.Copysign(0, -1)}, true},
+		{&Float{Value: math.NaN()}, &Float{Value: math.NaN()}, true},
+		{&Float{Value: 1e300}, &Float{Value: 1e300}, true},
+		{&Float{Value: 0.5}, &Integer{Value: 0}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.HashKey() == tt.b.HashKey(); got != tt.equal {
+			t.Errorf("%+v and %+v: hash keys equal=%t, want=%t", tt.a, tt.b, got, tt.equal)
+		}
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{2, "2.0"},
+		{-0.5, "-0.5"},
+		{1e21, "1e+21"},
+		{1e-9, "1e-09"},
+		{math.Inf(1), "+Inf"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, tt := range tests {
+		if got := (&Float{Value: tt.value}).Inspect(); got != tt.expected {
+			t.Errorf("Inspect() wrong. want=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+// 自分自身を含む配列とハッシュは循環する位置を [...] や {...} と表示する
+func TestInspectCycles(t *testing.T) {
+	arr := &Array{Elements: []Object{&Integer{Value: 1}}}
+	arr.Elements = append(arr.Elements, arr)
+
+	hash := NewHash()
+	key := &String{Value: "self"}
+	hash.Set(key.HashKey(), HashPair{Key: key, Value: hash})
+
+	// 同じ配列を2回含むだけなら循環ではない
+	inner := &Array{Elements: []Object{&Integer{Value: 2}}}
+	twice := &Array{Elements: []Object{inner, inner}}
+
+	nested := &Array{Elements: []Object{hash}}
+	hash2 := NewHash()
+	hash2.Set(key.HashKey(), HashPair{Key: key, Value: nested})
+	nested.Elements = append(nested.Elements, hash2)
+
+	tests := []struct {
+		obj      Object
+		expected string
+	}{
+		{arr, "[1, [...]]"},
+		{hash, "{self: {...}}"},
+		{twice, "[[2], [2]]"},
+		{nested, "[{self: {...}}, {self: [...]}]"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.obj.Inspect(); got != tt.expected {
+			t.Errorf("Inspect() wrong. want=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+
+	// 外側で定義された束縛を書き換える
+	if _, ok := inner.Assign("x", &Integer{Value: 2}); !ok {
+		t.Fatalf("assign to outer binding failed")
+	}
+	if _, ok := inner.store["x"]; ok {
+		t.Errorf("assign created a new binding in the inner environment")
+	}
+	if x, _ := outer.Get("x"); x.(*Integer).Value != 2 {
+		t.Errorf("outer binding not updated. got=%s", x.Inspect())
+	}
+
+	if _, ok := inner.Assign("y", &Integer{Value: 3}); ok {
+		t.Errorf("assign to undefined binding succeeded")
+	}
+}
+
+func TestIterate(t *testing.T) {
+	tests := []struct {
+		iterable Object
+		expected []string
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}, []string{"1", "a"}},
+		{&String{Value: "héllo"}, []string{"h", "é", "l", "l", "o"}},
+		{&Range{Start: 0, End: 3, Step: 1}, []string{"0", "1", "2"}},
+		{&Range{Start: 5, End: 0, Step: -2}, []string{"5", "3", "1"}},
+		{&Range{Start: 3, End: 3, Step: 1}, []string{}},
+		{&Range{Start: math.MaxInt64 - 1, End: math.MaxInt64, Step: 5}, []string{"9223372036854775806"}},
+	}
+
+	for _, tt := range tests {
+		it, err := Iterate(tt.iterable)
+		if err != nil {
+			t.Fatalf("Iterate(%s) returned error: %s", tt.iterable.Inspect(), err.Message)
+		}
+
+		got := []string{}
+		for {
+			obj, ok := it.Next()
+			if !ok {
+				break
+			}
+			got = append(got, obj.Inspect())
+		}
+
+		if len(got) != len(tt.expected) {
+			t.Fatalf("wrong number of elements for %s. want=%v, got=%v", tt.iterable.Inspect(), tt.expected, got)
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("element %d wrong. want=%q, got=%q", i, tt.expected[i], got[i])
+			}
+		}
+	}
+
+	if _, err := Iterate(&Integer{Value: 1}); err == nil || err.Message != "not iterable: INTEGER" {
+		t.Errorf("expected not iterable error. got=%v", err)
+	}
+}
+
+func TestStringLenAndCharAt(t *testing.T) {
+	s := &String{Value: "a日🐒"}
+
+	if s.Len() != 3 {
+		t.Errorf("wrong length. want=3, got=%d", s.Len())
+	}
+
+	expected := []string{"a", "日", "🐒"}
+	for i, want := range expected {
+		ch, ok := s.CharAt(int64(i))
+		if !ok || ch.Value != want {
+			t.Errorf("CharAt(%d) wrong. want=%q, got=%v", i, want, ch)
+		}
+	}
+
+	if ch, ok := s.CharAt(-1); !ok || ch.Value != "🐒" {
+		t.Errorf("CharAt(-1) wrong. got=%v", ch)
+	}
+
+	for _, i := range []int64{-4, 3} {
+		if _, ok := s.CharAt(i); ok {
+			t.Errorf("CharAt(%d) should be out of range", i)
+		}
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	h := NewHash()
+	for _, k := range []string{"b", "a", "c"} {
+		key := &String{Value: k}
+		h.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: 1}})
+	}
+
+	// 既にあるキーの値を置き換えても順序は変わらない
+	a := &String{Value: "a"}
+	h.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}})
+	if h.Inspect() != "{b: 1, a: 2, c: 1}" {
+		t.Errorf("wrong order after Set. got=%s", h.Inspect())
+	}
+
+	h.Delete(a.HashKey())
+	h.Delete((&String{Value: "x"}).HashKey())
+	if h.Inspect() != "{b: 1, c: 1}" {
+		t.Errorf("wrong order after Delete. got=%s", h.Inspect())
+	}
+
+	// 削除したキーを追加し直すと最後に並ぶ
+	h.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 3}})
+	if h.Inspect() != "{b: 1, c: 1, a: 3}" {
+		t.Errorf("wrong order after re-adding. got=%s", h.Inspect())
+	}
+	if len(h.Pairs) != 3 || len(h.Order) != 3 {
+		t.Errorf("Pairs and Order out of sync. pairs=%d, order=%d", len(h.Pairs), len(h.Order))
+	}
+}
@@ -0,0 +1,67 @@
+package object
+
+// 添字と範囲 (a[i], a[start:end])
+// 負の添字は末尾から数える (-1は最後の要素)
+
+// 添字を先頭から数えた位置にする 範囲外の場合はfalse
+func NormalizeIndex(i, length int64) (int64, bool) {
+	if i < 0 {
+		i += length
+	}
+	return i, i >= 0 && i < length
+}
+
+// left[start:end] endの位置は含まない
+// 配列は新しい配列を、文字列はコードポイント単位の部分文字列を返す
+// startとendがnull (省略) の場合は先頭と末尾とし、範囲外の位置は両端に切り詰める
+func Slice(left, start, end Object) Object {
+	switch left := left.(type) {
+	case *Array:
+		from, to, err := sliceBounds(start, end, int64(len(left.Elements)))
+		if err != nil {
+			return err
+		}
+		elements := make([]Object, to-from)
+		copy(elements, left.Elements[from:to])
+		return &Array{Elements: elements}
+	case *String:
+		runes := []rune(left.Value)
+		from, to, err := sliceBounds(start, end, int64(len(runes)))
+		if err != nil {
+			return err
+		}
+		return &String{Value: string(runes[from:to])}
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+func sliceBounds(start, end Object, length int64) (int64, int64, *Error) {
+	from, err := sliceBound(start, 0, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := sliceBound(end, length, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	if to < from {
+		to = from
+	}
+	return from, to, nil
+}
+
+func sliceBound(obj Object, omitted, length int64) (int64, *Error) {
+	switch obj := obj.(type) {
+	case nil, *Null:
+		return omitted, nil
+	case *Integer:
+		i := obj.Value
+		if i < 0 {
+			i += length
+		}
+		return clamp(i, 0, length), nil
+	default:
+		return 0, newError("slice index must be INTEGER, got %s", obj.Type())
+	}
+}
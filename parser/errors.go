@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/kakts/monkey/token"
+)
+
+// 構文エラーの種類
+type ErrorKind int
+
+const (
+	UnexpectedToken         ErrorKind = iota // 期待したトークンと異なるトークンが現れた
+	NoPrefixParseFn                          // 式の先頭に置けないトークンが現れた
+	InvalidInteger                           // 整数リテラルとして解釈できない
+	IllegalToken                             // 字句解析器が認識できなかった文字
+	InvalidFloat                             // 浮動小数点数リテラルとして解釈できない
+	InvalidAssignmentTarget                  // 代入できない式への代入
+	LoopControlOutsideLoop                   // ループの外の break または continue
+	UnterminatedComment                      // 閉じていないブロックコメント
+	UnterminatedString                       // 閉じていない文字列
+	InvalidEscape                            // 文字列中の不正なエスケープシーケンス
+	NotTopLevel                              // ブロックの中の import または export
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case UnexpectedToken:
+		return "unexpected token"
+	case NoPrefixParseFn:
+		return "no prefix parse function"
+	case InvalidInteger:
+		return "invalid integer"
+	case IllegalToken:
+		return "illegal token"
+	case InvalidFloat:
+		return "invalid float"
+	case InvalidAssignmentTarget:
+		return "invalid assignment target"
+	case LoopControlOutsideLoop:
+		return "loop control outside loop"
+	case UnterminatedComment:
+		return "unterminated comment"
+	case UnterminatedString:
+		return "unterminated string"
+	case InvalidEscape:
+		return "invalid escape"
+	case NotTopLevel:
+		return "not at top level"
+	default:
+		return fmt.Sprintf("ErrorKind(%d)", int(k))
+	}
+}
+
+// 構文エラー
+// ParseProgramは回復しながら解析を続けるため、1回の呼び出しで複数のエラーが記録される
+type ParseError struct {
+	Kind     ErrorKind
+	Expected token.TokenType // UnexpectedTokenの場合に期待していたトークン
+	Actual   token.Token     // 実際に現れたトークン
+	Pos      token.Position  // エラーの発生位置
+	Msg      string
+	Hint     string // 修正のヒント 無い場合は空
+}
+
+func (e *ParseError) Error() string {
+	s := e.Pos.String() + ": " + e.Msg
+	if e.Hint != "" {
+		s += " (hint: " + e.Hint + ")"
+	}
+	return s
+}
+
+// 期待していたトークンに応じたヒントを返す
+func expectedTokenHint(expected token.TokenType, actual token.Token) string {
+	if actual.Type == token.EOF {
+		return "unexpected end of input"
+	}
+
+	switch expected {
+	case token.RPAREN, token.RBRACE, token.RBRACKET:
+		return fmt.Sprintf("missing closing '%s'?", expected)
+	case token.ASSIGN:
+		return "let statements have the form 'let <name> = <expression>;'"
+	case token.IN:
+		return "for loops have the form 'for (<name> in <expression>) { ... }'"
+	case token.STRING_END:
+		return "interpolations have the form '${<expression>}'"
+	case token.LET:
+		return "only let statements can be exported"
+	case token.IDENT:
+		return fmt.Sprintf("'%s' cannot be used as a name", actual.Literal)
+	default:
+		return ""
+	}
+}
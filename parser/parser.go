@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/kakts/monkey/ast"
 	"github.com/kakts/monkey/lexer"
@@ -12,28 +13,52 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // = += -= など (右結合)
+	OR          // ||
+	AND         // &&
 	EQUALS
 	LESSGREATER
 	SUM
 	PRODUCT
 	PREFIX
 	CALL
-	INDEX // array[index]
+	INDEX // array[index], object.member
 )
 
 // 演算子の優先順位テーブル
 // 上の定数の値が大きい方ほど優先度が高い
 var precedences = map[token.TokenType]int {
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.PERCENT_ASSIGN:  ASSIGN,
+	token.OR:       OR,
+	token.AND:      AND,
 	token.EQ: 			EQUALS,
 	token.NOT_EQ: 	EQUALS,
 	token.LT: 			LESSGREATER,
 	token.GT: 			LESSGREATER,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.PERCENT:  PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
+}
+
+// 中置演算子の優先順位 中置演算子でないトークンはLOWEST
+// 整形時に必要な括弧を決めるためにも使う
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
 }
 
 // 前置(prefix)と中置(infix)で異なる構文解析を定義する
@@ -48,7 +73,17 @@ type Parser struct {
 
 	curToken token.Token
 	peekToken token.Token
-	errors []string
+	errors []*ParseError
+
+	// エラー発生後、回復するまで後続のエラーを記録しない
+	panicking bool
+
+	// 解析中のループの深さ break と continue はループの中でのみ使える
+	// 関数リテラルの中では0から数え直す
+	loopDepth int
+
+	// 解析中のブロックの深さ import と export はトップレベル (0) でのみ使える
+	blockDepth int
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns map[token.TokenType]infixParseFn
@@ -57,13 +92,14 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l: l,
-		errors: []string{},
+		errors: []*ParseError{},
 	}
 
 	// ParserのprefixParseFnsマップを初期化し、構文解析関数を登録する
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 
@@ -76,11 +112,16 @@ func New(l *lexer.Lexer) *Parser {
 	// if/else文
 	p.registerPrefix(token.IF, p.parseIfExpression)
 
+	// ループ
+	p.registerPrefix(token.WHILE, p.parseWhileExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+
 	// function
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 
 	// 文字列
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.STRING_START, p.parseInterpolatedString)
 
 	// 配列
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
@@ -96,6 +137,17 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
 
 	// 関数の呼び出し式　add(2, 3)として　LPARENに対するinfixParseFnを登録する
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
@@ -103,6 +155,9 @@ func New(l *lexer.Lexer) *Parser {
 	// 配列のインデックス"[" を中置演算子として扱う
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
+	// メンバーアクセス lib.name
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+
 	// 2つのトークンを読み込む
 	p.nextToken()
 	p.nextToken()
@@ -136,15 +191,61 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	return exp
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*ParseError {
 	return p.errors
 }
 
+// エラーを記録する
+// 1つの文で最初のエラーだけを記録し、連鎖して起きるエラーは回復するまで無視する
+func (p *Parser) addError(err *ParseError) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+	p.errors = append(p.errors, err)
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("Expected next token to be %s, got %s instead",
-										 t,
-										 p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(&ParseError{
+		Kind:     UnexpectedToken,
+		Expected: t,
+		Actual:   p.peekToken,
+		Pos:      p.peekToken.Pos,
+		Msg:      fmt.Sprintf("Expected next token to be %s, got %s instead", t, p.peekToken.Type),
+		Hint:     expectedTokenHint(t, p.peekToken),
+	})
+}
+
+// エラーからの回復
+// 壊れた文の残りを読み飛ばし、次の文を解析できる位置までトークンを進める
+// 現在のトークンが ';' か対応の無い '}' になるか、次のトークンが文の始まりかブロックの終わりになったら止まる
+func (p *Parser) synchronize() {
+	defer func() { p.panicking = false }()
+
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+
+		if depth == 0 {
+			if p.curTokenIs(token.SEMICOLON) {
+				return
+			}
+			switch p.peekToken.Type {
+			case token.RBRACE, token.LET, token.RETURN, token.BREAK, token.CONTINUE, token.IMPORT, token.EXPORT:
+				return
+			}
+		}
+
+		p.nextToken()
+	}
 }
 
 func (p *Parser) nextToken() {
@@ -159,14 +260,23 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 	for p.curToken.Type != token.EOF {
 		// 構文解析する
+		errCount := len(p.errors)
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize()
+		}
+		// エラーのあった文は捨てる
+		if stmt != nil && len(p.errors) == errCount {
 			program.Statements = append(program.Statements, stmt)
 		}
 
 		p.nextToken()
 	}
 
+	for _, tok := range p.l.Comments() {
+		program.Comments = append(program.Comments, &ast.Comment{Token: tok})
+	}
+
 	return program
 }
 
@@ -176,6 +286,12 @@ func (p *Parser) parseStatement() ast.Statement {
 			return p.parseLetStatement()
 	case token.RETURN:
 			return p.parseReturnStatement()
+	case token.BREAK, token.CONTINUE:
+			return p.parseLoopControlStatement()
+	case token.IMPORT:
+			return p.parseImportStatement()
+	case token.EXPORT:
+			return p.parseExportStatement()
 	default:
 			// 式文として評価
 			return p.parseExpressionStatement()
@@ -199,7 +315,12 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
-	if !p.curTokenIs(token.SEMICOLON) {
+	// 関数に束縛先の名前を持たせる (コンパイラが再帰呼び出しに利用する)
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fl.Name = stmt.Name.Value
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -232,13 +353,121 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	if !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// break; と continue; のパース
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	tok := p.curToken
+
+	if p.loopDepth == 0 {
+		p.addError(&ParseError{
+			Kind:   LoopControlOutsideLoop,
+			Actual: tok,
+			Pos:    tok.Pos,
+			Msg:    fmt.Sprintf("%s outside loop", tok.Literal),
+		})
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok}
+	}
+	return &ast.ContinueStatement{Token: tok}
+}
+
+// import "path" as name; と import { a, b } from "path"; のパース
+// as と from はこの位置でのみ意味を持つので、キーワードにせず識別子として読む
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+	p.checkTopLevel(stmt.Token)
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		for {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.RBRACE) || !p.expectWord("from") || !p.expectPeek(token.STRING) {
+			return nil
+		}
+		stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	} else {
+		if !p.expectPeek(token.STRING) {
+			return nil
+		}
+		stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectWord("as") || !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
 	return stmt
 }
 
+// export let name = value; のパース
+func (p *Parser) parseExportStatement() ast.Statement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+	p.checkTopLevel(stmt.Token)
+
+	if !p.expectPeek(token.LET) {
+		return nil
+	}
+	stmt.Statement = p.parseLetStatement()
+	if stmt.Statement == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) checkTopLevel(tok token.Token) {
+	if p.blockDepth > 0 {
+		p.addError(&ParseError{
+			Kind:   NotTopLevel,
+			Actual: tok,
+			Pos:    tok.Pos,
+			Msg:    fmt.Sprintf("%s is only allowed at the top level", tok.Literal),
+		})
+	}
+}
+
+// 次のトークンが識別子wordであれば進める (import文の as と from)
+func (p *Parser) expectWord(word string) bool {
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == word {
+		p.nextToken()
+		return true
+	}
+
+	p.addError(&ParseError{
+		Kind:     UnexpectedToken,
+		Expected: token.IDENT,
+		Actual:   p.peekToken,
+		Pos:      p.peekToken.Pos,
+		Msg:      fmt.Sprintf("Expected next token to be '%s', got %s instead", word, p.peekToken.Type),
+		Hint:     "imports have the form 'import \"<path>\" as <name>;' or 'import { <names> } from \"<path>\";'",
+	})
+	return false
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{
 		Token: p.curToken,
@@ -290,21 +519,99 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseWhileExpression() ast.Expression {
+	expression := &ast.WhileExpression{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseLoopBody()
+
+	return expression
+}
+
+func (p *Parser) parseForExpression() ast.Expression {
+	expression := &ast.ForExpression{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	// ループ変数
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expression.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseLoopBody()
+
+	return expression
+}
+
+// ループ本体のブロックのパース 中では break と continue が使える
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	return p.parseBlockStatement()
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{
 		Token: p.curToken,
 	}
 	block.Statements = []ast.Statement{}
 
+	p.blockDepth++
+	defer func() { p.blockDepth-- }()
+
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		errCount := len(p.errors)
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize()
+			// 回復した結果ブロックの終わりに達している場合
+			if p.curTokenIs(token.RBRACE) {
+				break
+			}
+		}
+		if stmt != nil && len(p.errors) == errCount {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	block.Rbrace = p.curToken
 
 	return block
 }
@@ -324,9 +631,60 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 }
 
 
+// 字句解析器が返したILLEGALトークンを構文エラーとして記録する
+// 閉じていないコメントや文字列、不正なエスケープはトークンの先頭の文字で見分ける
+func (p *Parser) illegalTokenError() {
+	tok := p.curToken
+	err := &ParseError{Actual: tok, Pos: tok.Pos}
+
+	switch {
+	case strings.HasPrefix(tok.Literal, "/*"):
+		err.Kind = UnterminatedComment
+		err.Msg = "unterminated block comment"
+		err.Hint = "block comments are closed with '*/'"
+	case strings.HasPrefix(tok.Literal, `"`):
+		err.Kind = UnterminatedString
+		err.Msg = "unterminated string"
+		err.Hint = `strings are closed with '"'`
+	case strings.HasPrefix(tok.Literal, "`"):
+		err.Kind = UnterminatedString
+		err.Msg = "unterminated raw string"
+		err.Hint = "raw strings are closed with '`'"
+	case len(tok.Literal) > 1 && strings.HasPrefix(tok.Literal, `\`):
+		err.Kind = InvalidEscape
+		err.Msg = fmt.Sprintf("invalid escape sequence %q", tok.Literal)
+		err.Hint = `valid escapes are \n \t \r \\ \" \$ and \u{<hex>}`
+	default:
+		err.Kind = IllegalToken
+		err.Msg = fmt.Sprintf("illegal token %q", tok.Literal)
+	}
+
+	p.addError(err)
+}
+
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	if t == token.ILLEGAL {
+		p.illegalTokenError()
+		return
+	}
+
+	err := &ParseError{
+		Kind:   NoPrefixParseFn,
+		Actual: p.curToken,
+		Pos:    p.curToken.Pos,
+		Msg:    fmt.Sprintf("no prefix parse function for %s found", t),
+	}
+	switch t {
+	case token.EOF:
+		err.Hint = "unexpected end of input"
+	case token.RPAREN, token.RBRACE, token.RBRACKET:
+		err.Hint = fmt.Sprintf("unbalanced '%s'", t)
+	case token.SEMICOLON:
+		err.Hint = "missing expression"
+	case token.STRING_MIDDLE, token.STRING_END:
+		err.Hint = "missing expression in '${}'"
+	}
+	p.addError(err)
 }
 
 /**
@@ -375,8 +733,32 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	// intに変換
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(&ParseError{
+			Kind:   InvalidInteger,
+			Actual: p.curToken,
+			Pos:    p.curToken.Pos,
+			Msg:    fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+		})
+		return nil
+	}
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{
+		Token: p.curToken,
+	}
+	// float64に変換 範囲外の値もエラーにする
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.addError(&ParseError{
+			Kind:   InvalidFloat,
+			Actual: p.curToken,
+			Pos:    p.curToken.Pos,
+			Msg:    fmt.Sprintf("could not parse %q as float", p.curToken.Literal),
+		})
 		return nil
 	}
 	lit.Value = value
@@ -430,6 +812,38 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// 代入式のパース
+// a = b = c は a = (b = c) になるよう、右辺は1段低い優先順位で解析する
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token: p.curToken,
+		Operator: p.curToken.Literal,
+		Target: target,
+	}
+
+	precedence := p.curPrecedence()
+
+	p.nextToken()
+	expression.Value = p.parseExpression(precedence - 1)
+
+	// 右辺まで読み進めてから代入先を検査する
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+		return expression
+	case nil:
+		return nil
+	default:
+		p.addError(&ParseError{
+			Kind:   InvalidAssignmentTarget,
+			Actual: expression.Token,
+			Pos:    target.Pos(),
+			Msg:    fmt.Sprintf("cannot assign to %s", target.String()),
+			Hint:   "only variables and index expressions can be assigned",
+		})
+		return nil
+	}
+}
+
 // functionリテラルのパース
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
@@ -444,7 +858,11 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
+	// 関数の外側のループを break で抜けることはできない
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
 
 	return lit
 }
@@ -486,6 +904,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Rparen = p.curToken
 
 	return exp
 }
@@ -522,11 +941,52 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// 埋め込み式を含む文字列のパース
+// STRING_START 式 (STRING_MIDDLE 式)* STRING_END の順にトークンが並ぶ
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	str.Parts = appendStringPart(str.Parts, p.curToken)
+
+	for {
+		p.nextToken()
+		str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+
+		// 文字列の続きに閉じていない文字列や不正なエスケープがある
+		if p.peekTokenIs(token.ILLEGAL) {
+			p.nextToken()
+			p.illegalTokenError()
+			return nil
+		}
+
+		if p.peekTokenIs(token.STRING_MIDDLE) {
+			p.nextToken()
+			str.Parts = appendStringPart(str.Parts, p.curToken)
+			continue
+		}
+
+		if !p.expectPeek(token.STRING_END) {
+			return nil
+		}
+		str.Parts = appendStringPart(str.Parts, p.curToken)
+		str.EndToken = p.curToken
+		return str
+	}
+}
+
+// 文字列部分を追加する 空の文字列は追加しない
+func appendStringPart(parts []ast.Expression, tok token.Token) []ast.Expression {
+	if tok.Literal == "" {
+		return parts
+	}
+	return append(parts, &ast.StringLiteral{Token: tok, Value: tok.Literal})
+}
+
 // 配列リテラルのパース
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Rbracket = p.curToken
 
 	return array
 }
@@ -557,15 +1017,56 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+// 添字アクセス left[index] とスライス left[low:high]
+// スライスの位置はどちらも省略できる (a[:n], a[n:], a[:])
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	lbracket := p.curToken
+
+	var index ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(lbracket, left, index)
+	}
+
+	exp := &ast.IndexExpression{Token: lbracket, Left: left, Index: index}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	exp.Rbracket = p.curToken
+
+	return exp
+}
 
+func (p *Parser) parseSliceExpression(lbracket token.Token, left, low ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: lbracket, Left: left, Low: low}
+
+	// ':' に進む
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.High = p.parseExpression(LOWEST)
+	}
 
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
+	exp.Rbracket = p.curToken
+
+	return exp
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 	return exp
 }
@@ -587,6 +1088,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 		// ハッシュに詰める
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -596,6 +1098,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	hash.Rbrace = p.curToken
 
 	return hash
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 	"fmt"
+	"strings"
 
 	"github.com/kakts/monkey/ast"
 	"github.com/kakts/monkey/lexer"
@@ -181,6 +182,47 @@ func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
 }
 
 // 前置式のテスト
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"1e-9;", 1e-9},
+		{"0.5e2;", 50},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestInvalidFloatLiteral(t *testing.T) {
+	l := lexer.New("1e999;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. want=1, got=%d", len(errors))
+	}
+	if errors[0].Kind != InvalidFloat {
+		t.Errorf("wrong error kind. want=%s, got=%s", InvalidFloat, errors[0].Kind)
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input string
@@ -237,6 +279,11 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"true && false", true, "&&", false},
+		{"true || false", true, "||", false},
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
@@ -372,6 +419,46 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"a <= b == b >= a",
+			"((a <= b) == (b >= a))",
+		},
+		{
+			"a < b && b < c",
+			"((a < b) && (b < c))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"!a && b == c || d",
+			"(((!a) && (b == c)) || d)",
+		},
+		{
+			"a = b + c * d",
+			"(a = (b + (c * d)))",
+		},
+		{
+			"a = b = c",
+			"(a = (b = c))",
+		},
+		{
+			"a[i] += b || c",
+			"((a[i]) += (b || c))",
+		},
+		{
+			"x = fn(y) { y = 1 }",
+			"(x = fn(y) (y = 1))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -733,6 +820,226 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedTarget   string
+		expectedOperator string
+		expectedValue    interface{}
+	}{
+		{"x = 5;", "x", "=", 5},
+		{"x += 1;", "x", "+=", 1},
+		{"x -= y;", "x", "-=", "y"},
+		{"x *= 2;", "x", "*=", 2},
+		{"x /= 2;", "x", "/=", 2},
+		{"x %= 2;", "x", "%=", 2},
+		{"arr[0] = true;", "(arr[0])", "=", true},
+		{"h[k] += 1;", "(h[k])", "+=", 1},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf("exp is not ast.AssignExpression. got=%T", stmt.Expression)
+		}
+
+		if exp.Target.String() != tt.expectedTarget {
+			t.Errorf("exp.Target is not %s. got=%s", tt.expectedTarget, exp.Target.String())
+		}
+		if exp.Operator != tt.expectedOperator {
+			t.Errorf("exp.Operator is not %s. got=%s", tt.expectedOperator, exp.Operator)
+		}
+		if !testLiteralExpression(t, exp.Value, tt.expectedValue) {
+			return
+		}
+	}
+}
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = 2;", "1:1: cannot assign to 1"},
+		{"let x = 1; f(x) += 1;", "1:12: cannot assign to f(x)"},
+		{"x + y = 3;", "1:1: cannot assign to (x + y)"},
+		{"a[1:] = [];", "1:1: cannot assign to (a[1:])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. want=1, got=%d", tt.input, len(errors))
+		}
+		if errors[0].Kind != InvalidAssignmentTarget {
+			t.Errorf("wrong error kind. want=%s, got=%s", InvalidAssignmentTarget, errors[0].Kind)
+		}
+		if errors[0].Pos.String()+": "+errors[0].Msg != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0].Pos.String()+": "+errors[0].Msg)
+		}
+	}
+}
+
+func TestWhileExpression(t *testing.T) {
+	input := `while (x < 10) { x += 1; continue; break; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.WhileExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.WhileExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", 10) {
+		return
+	}
+
+	if len(exp.Body.Statements) != 3 {
+		t.Fatalf("body is not 3 statements. got=%d", len(exp.Body.Statements))
+	}
+	if _, ok := exp.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("body.Statements[1] is not ast.ContinueStatement. got=%T", exp.Body.Statements[1])
+	}
+	if _, ok := exp.Body.Statements[2].(*ast.BreakStatement); !ok {
+		t.Errorf("body.Statements[2] is not ast.BreakStatement. got=%T", exp.Body.Statements[2])
+	}
+}
+
+func TestForExpression(t *testing.T) {
+	input := `for (item in items) { puts(item) }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.ForExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.ForExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Variable, "item") {
+		return
+	}
+	if !testIdentifier(t, exp.Iterable, "items") {
+		return
+	}
+	if exp.Body.String() != "puts(item)" {
+		t.Errorf("body is not %q. got=%q", "puts(item)", exp.Body.String())
+	}
+}
+
+// break と continue はループの中でのみ使える
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break outside loop"},
+		{"if (true) { continue; }", "1:13: continue outside loop"},
+		{"while (true) { let f = fn() { break; }; }", "1:31: break outside loop"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. want=1, got=%d", tt.input, len(errors))
+		}
+		if errors[0].Kind != LoopControlOutsideLoop {
+			t.Errorf("wrong error kind. want=%s, got=%s", LoopControlOutsideLoop, errors[0].Kind)
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// add two numbers
+let add = fn(a, b) { a + b /* sum */ };
+add(1, 2); // 3`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	expected := []string{"// add two numbers", "/* sum */", "// 3"}
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("program.Comments does not contain %d comments. got=%d", len(expected), len(program.Comments))
+	}
+	for i, c := range program.Comments {
+		if c.String() != expected[i] {
+			t.Errorf("program.Comments[%d] wrong. want=%q, got=%q", i, expected[i], c.String())
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	l := lexer.New("let x = 1; /* oops\nlet y = 2;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. want=1, got=%d", len(errors))
+	}
+	if errors[0].Kind != UnterminatedComment {
+		t.Errorf("wrong error kind. want=%s, got=%s", UnterminatedComment, errors[0].Kind)
+	}
+	expected := "1:12: unterminated block comment (hint: block comments are closed with '*/')"
+	if errors[0].Error() != expected {
+		t.Errorf("wrong error. want=%q, got=%q", expected, errors[0].Error())
+	}
+}
+
 // 文字列リテラルのテスト
 func TestStringLiteralExpression(t *testing.T) {
 	input := `"hello world"`
@@ -754,6 +1061,83 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestInterpolatedString(t *testing.T) {
+	tests := []struct {
+		input    string
+		parts    []string
+		expected string
+	}{
+		{`"hello ${name}!"`, []string{"hello ", "name", "!"}, "hello ${name}!"},
+		{`"${a + b}"`, []string{"(a + b)"}, "${(a + b)}"},
+		{`"${x}, ${y}"`, []string{"x", ", ", "y"}, "${x}, ${y}"},
+		{`"a ${"b ${c}"}"`, []string{"a ", "b ${c}"}, "a ${b ${c}}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		str, ok := stmt.Expression.(*ast.InterpolatedString)
+		if !ok {
+			t.Fatalf("exp not *ast.InterpolatedString. got = %T", stmt.Expression)
+		}
+
+		if len(str.Parts) != len(tt.parts) {
+			t.Fatalf("wrong number of parts. want=%d, got=%d", len(tt.parts), len(str.Parts))
+		}
+		for i, part := range str.Parts {
+			if part.String() != tt.parts[i] {
+				t.Errorf("parts[%d] wrong. want=%q, got=%q", i, tt.parts[i], part.String())
+			}
+		}
+
+		if str.String() != tt.expected {
+			t.Errorf("str.String() wrong. want=%q, got=%q", tt.expected, str.String())
+		}
+		if str.End().Offset != len(tt.input) {
+			t.Errorf("str.End() wrong. want=%d, got=%d", len(tt.input), str.End().Offset)
+		}
+	}
+}
+
+// 字句解析器が見つけた文字列の誤り
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     ErrorKind
+		expected string
+	}{
+		{`let s = "abc`, UnterminatedString, `1:9: unterminated string (hint: strings are closed with '"')`},
+		{`"a ${1} b`, UnterminatedString, `1:1: unterminated string (hint: strings are closed with '"')`},
+		{"`abc", UnterminatedString, "1:1: unterminated raw string (hint: raw strings are closed with '`')"},
+		{`"a\qb"`, InvalidEscape, `1:3: invalid escape sequence "\\q" (hint: valid escapes are \n \t \r \\ \" \$ and \u{<hex>})`},
+		{`"${}"`, NoPrefixParseFn, "1:4: no prefix parse function for STRING_END found (hint: missing expression in '${}')"},
+		{`"${1 2}"`, UnexpectedToken, "1:6: Expected next token to be STRING_END, got INT instead (hint: interpolations have the form '${<expression>}')"},
+		{`@`, IllegalToken, `1:1: illegal token "@"`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. want=1, got=%d (%v)", tt.input, len(errors), errors)
+		}
+		if errors[0].Kind != tt.kind {
+			t.Errorf("wrong error kind for %q. want=%s, got=%s", tt.input, tt.kind, errors[0].Kind)
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0].Error())
+		}
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -801,6 +1185,197 @@ func TestParsingIndexExpression(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:2]", "(a[1:2])"},
+		{"a[:n]", "(a[:n])"},
+		{"a[n:]", "(a[n:])"},
+		{"a[:]", "(a[:])"},
+		{"a[-1:len(a) - 1]", "(a[(-1):(len(a) - 1)])"},
+		{"a[1:][0]", "((a[1:])[0])"},
+		{"f(x)[i + 1:]", "(f(x)[(i + 1):])"},
+	}
+
+	// 位置を省略した側はnilになる
+	program := New(lexer.New("a[:2]")).ParseProgram()
+	slice, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.SliceExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.SliceExpression. got=%T", program.Statements[0])
+	}
+	if slice.Low != nil || !testIntegerLiteral(t, slice.High, 2) {
+		t.Errorf("wrong bounds. low=%v, high=%v", slice.Low, slice.High)
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestParsingMemberExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"lib.add", "lib.add"},
+		{"lib.add(1, 2)", "lib.add(1, 2)"},
+		{"a.b.c", "a.b.c"},
+		{"a.b[0].c", "(a.b[0]).c"},
+		{"-a.b", "(-a.b)"},
+		{"a.b * c.d", "(a.b * c.d)"},
+		{"arr.push(1).len()", "arr.push(1).len()"},
+		{`"abc".upper()`, "abc.upper()"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("lib.add")).ParseProgram()
+	member, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.MemberExpression. got=%T", program.Statements[0])
+	}
+	if !testIdentifier(t, member.Object, "lib") || member.Member.Value != "add" {
+		t.Errorf("wrong member expression. got=%s", member.String())
+	}
+}
+
+func TestImportStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedPath  string
+		expectedAlias string
+		expectedNames []string
+	}{
+		{`import "lib/math.mk" as math;`, "lib/math.mk", "math", nil},
+		{`import { add } from "math.mk";`, "math.mk", "", []string{"add"}},
+		{`import { add, sub } from "math.mk"`, "math.mk", "", []string{"add", "sub"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Path.Value != tt.expectedPath {
+			t.Errorf("wrong path. want=%q, got=%q", tt.expectedPath, stmt.Path.Value)
+		}
+
+		alias := ""
+		if stmt.Alias != nil {
+			alias = stmt.Alias.Value
+		}
+		if alias != tt.expectedAlias {
+			t.Errorf("wrong alias. want=%q, got=%q", tt.expectedAlias, alias)
+		}
+
+		if len(stmt.Names) != len(tt.expectedNames) {
+			t.Fatalf("wrong number of names. want=%d, got=%d", len(tt.expectedNames), len(stmt.Names))
+		}
+		for i, name := range tt.expectedNames {
+			if stmt.Names[i].Value != name {
+				t.Errorf("wrong name at %d. want=%q, got=%q", i, name, stmt.Names[i].Value)
+			}
+		}
+	}
+}
+
+func TestExportStatement(t *testing.T) {
+	l := lexer.New("export let add = fn(a, b) { a + b };")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExportStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ExportStatement. got=%T", program.Statements[0])
+	}
+	if !testLetStatement(t, stmt.Statement, "add") {
+		return
+	}
+	if program.String() != "export let add = fn(a, b) (a + b);" {
+		t.Errorf("wrong string. got=%q", program.String())
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "a.mk" b;`, "1:15: Expected next token to be 'as', got IDENT instead"},
+		{`import { a } "a.mk";`, "1:14: Expected next token to be 'from', got STRING instead"},
+		{`import a as b;`, "1:8: Expected next token to be STRING, got IDENT instead"},
+		{`export fn() {};`, "1:8: Expected next token to be LET, got FUNCTION instead (hint: only let statements can be exported)"},
+		{`a.1`, "1:3: Expected next token to be IDENT, got INT instead (hint: '1' cannot be used as a name)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("no errors for %q", tt.input)
+		}
+		if !strings.HasPrefix(errors[0].Error(), tt.expected) {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, errors[0].Error())
+		}
+	}
+}
+
+// import と export はトップレベルでのみ使える
+func TestImportExportNotTopLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`if (true) { export let x = 1; }`, "1:13: export is only allowed at the top level"},
+		{`let f = fn() { import "a.mk" as a; };`, "1:16: import is only allowed at the top level"},
+		{`while (true) { import { a } from "a.mk"; }`, "1:16: import is only allowed at the top level"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("wrong number of errors for %q. want=1, got=%d", tt.input, len(errors))
+		}
+		if errors[0].Kind != NotTopLevel {
+			t.Errorf("wrong error kind. want=%s, got=%s", NotTopLevel, errors[0].Kind)
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0].Error())
+		}
+	}
+}
+
 func TestParsingHashLiteralStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
@@ -837,6 +1412,17 @@ func TestParsingHashLiteralStringKeys(t *testing.T) {
 
 		testIntegerLiteral(t, value, expectedValue)
 	}
+
+	// Keysは書かれた順に並ぶ
+	order := []string{"one", "two", "three"}
+	if len(hash.Keys) != len(order) {
+		t.Fatalf("hash.Keys has wrong length. got=%d", len(hash.Keys))
+	}
+	for i, key := range hash.Keys {
+		if key.String() != order[i] {
+			t.Errorf("hash.Keys[%d] wrong. want=%q, got=%q", i, order[i], key.String())
+		}
+	}
 }
 
 // 空のハッシュリテラルのテスト
@@ -907,4 +1493,154 @@ func TestParsingHashLiteralWithExpressions(t *testing.T) {
 
 		testFunc(value)
 	}
-}
\ No newline at end of file
+}
+
+// ノードの位置情報のテスト
+func TestNodePositions(t *testing.T) {
+	input := `let add = fn(x, y) {
+  x + y;
+};
+add(1, [2, 3][0]);`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	fn := let.Value.(*ast.FunctionLiteral)
+	body := fn.Body.Statements[0].(*ast.ExpressionStatement)
+	call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	index := call.Arguments[1].(*ast.IndexExpression)
+
+	tests := []struct {
+		node      ast.Node
+		pos, end  string
+	}{
+		{let, "1:1", "3:2"},
+		{fn, "1:11", "3:2"},
+		{fn.Body, "1:20", "3:2"},
+		{body, "2:3", "2:8"},
+		{body.Expression, "2:3", "2:8"},
+		{call, "4:1", "4:18"},
+		{index, "4:8", "4:17"},
+		{index.Left, "4:8", "4:14"},
+		{program, "1:1", "4:18"},
+	}
+
+	for i, tt := range tests {
+		if tt.node.Pos().String() != tt.pos {
+			t.Errorf("tests[%d] - %T pos wrong. expected=%s, got=%s",
+				i, tt.node, tt.pos, tt.node.Pos())
+		}
+		if tt.node.End().String() != tt.end {
+			t.Errorf("tests[%d] - %T end wrong. expected=%s, got=%s",
+				i, tt.node, tt.end, tt.node.End())
+		}
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	input := `let x 5;`
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("parser has no errors")
+	}
+
+	expected := "1:7: Expected next token to be =, got INT instead" +
+		" (hint: let statements have the form 'let <name> = <expression>;')"
+	if errors[0].Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, errors[0].Error())
+	}
+}
+
+// 1回のParseProgramで独立したエラーがすべて報告されるかのテスト
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"let x 5; let = 10; let y = 3;",
+			[]string{
+				"1:7: Expected next token to be =, got INT instead",
+				"1:14: Expected next token to be IDENT, got = instead",
+			},
+		},
+		{
+			"add(1, 2; let y = ; y + 1;",
+			[]string{
+				"1:9: Expected next token to be ), got ; instead",
+				"1:19: no prefix parse function for ; found",
+			},
+		},
+		{
+			`let f = fn(x) {
+  let = 1;
+  x + * 2;
+};
+let g = fn() { 1 } }
+let h = 2;`,
+			[]string{
+				"2:7: Expected next token to be IDENT, got = instead",
+				"3:7: no prefix parse function for * found",
+				"5:20: no prefix parse function for } found",
+			},
+		},
+		{
+			"let x = 1 @ 2; let y = #;",
+			[]string{
+				"1:11: illegal token \"@\"",
+				"1:24: illegal token \"#\"",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expected) {
+			for _, err := range errors {
+				t.Errorf("parser error: %q", err)
+			}
+			t.Fatalf("wrong number of errors for %q. expected=%d, got=%d",
+				tt.input, len(tt.expected), len(errors))
+		}
+
+		for i, err := range errors {
+			if err.Pos.String()+": "+err.Msg != tt.expected[i] {
+				t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, tt.expected[i], err.Pos.String()+": "+err.Msg)
+			}
+		}
+	}
+}
+
+// エラー回復後も後続の文が正しく解析されるかのテスト
+func TestParserRecoveryKeepsValidStatements(t *testing.T) {
+	input := `let a = ; let b = 2; if (b) { let = 3; b } let c = 4;`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 2 {
+		t.Fatalf("wrong number of errors. expected=2, got=%d", len(p.Errors()))
+	}
+
+	// エラーを含む文は捨てられる
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d: %s",
+			len(program.Statements), program.String())
+	}
+
+	testLetStatement(t, program.Statements[0], "b")
+	testLetStatement(t, program.Statements[1], "c")
+}
@@ -0,0 +1,32 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kakts/monkey/object"
+)
+
+// prefixで始まる名前を重複なく辞書順に返す
+// 組み込み関数の名前も候補に含める
+func completions(prefix string, names []string) []string {
+	seen := map[string]bool{}
+	candidates := []string{}
+
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+
+	for _, name := range names {
+		add(name)
+	}
+	for _, def := range object.Builtins {
+		add(def.Name)
+	}
+
+	sort.Strings(candidates)
+	return candidates
+}
@@ -0,0 +1,361 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// 端末からの入力を行編集しながら読む
+// 矢印キーでのカーソル移動と履歴の呼び出し、Emacs風のキー操作、タブでの補完に対応する
+type editor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history *history
+
+	// 端末をrawモードにし、元に戻す関数を返す
+	raw func() (func(), error)
+
+	// 入力中の単語から補完候補を返す
+	complete func(prefix string) []string
+}
+
+func newEditor(in *os.File, out io.Writer, h *history, complete func(string) []string) *editor {
+	fd := int(in.Fd())
+	return &editor{
+		in:       bufio.NewReader(in),
+		out:      out,
+		history:  h,
+		raw:      func() (func(), error) { return makeRaw(fd) },
+		complete: complete,
+	}
+}
+
+// 端末からの入力なら行編集を使い、そうでなければ1行ずつ読む
+func newLineReader(in io.Reader, out io.Writer, complete func(string) []string) lineReader {
+	f, ok := in.(*os.File)
+	if !ok || !isTerminal(int(f.Fd())) {
+		return newPlainReader(in, out)
+	}
+	if o, ok := out.(*os.File); !ok || !isTerminal(int(o.Fd())) {
+		return newPlainReader(in, out)
+	}
+	return newEditor(f, out, loadHistory(historyPath()), complete)
+}
+
+func (e *editor) AddHistory(line string) {
+	e.history.add(line)
+}
+
+func (e *editor) Close() error {
+	return e.history.save()
+}
+
+// 入力中の行の状態
+type lineState struct {
+	prompt string
+	buf    []rune
+	pos    int
+}
+
+func (e *editor) ReadLine(prompt string) (string, error) {
+	restore, err := e.raw()
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	s := &lineState{prompt: prompt}
+	// 履歴を遡っている位置と、遡る前に入力していた行
+	histIndex := len(e.history.entries)
+	pending := ""
+
+	e.refresh(s)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			return string(s.buf), nil
+		case 3: // Ctrl-C
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(s.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			s.deleteForward()
+		case 1: // Ctrl-A
+			s.pos = 0
+		case 5: // Ctrl-E
+			s.pos = len(s.buf)
+		case 2: // Ctrl-B
+			s.moveLeft()
+		case 6: // Ctrl-F
+			s.moveRight()
+		case 8, 127: // Backspace
+			s.deleteBackward()
+		case 11: // Ctrl-K
+			s.buf = s.buf[:s.pos]
+		case 21: // Ctrl-U
+			s.buf = append([]rune{}, s.buf[s.pos:]...)
+			s.pos = 0
+		case 23: // Ctrl-W
+			s.deleteWord()
+		case 12: // Ctrl-L
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case 16: // Ctrl-P
+			histIndex, pending = e.historyMove(s, histIndex, pending, -1)
+		case 14: // Ctrl-N
+			histIndex, pending = e.historyMove(s, histIndex, pending, 1)
+		case '\t':
+			e.completeWord(s)
+		case 27: // ESC
+			switch e.readEscape() {
+			case 'A':
+				histIndex, pending = e.historyMove(s, histIndex, pending, -1)
+			case 'B':
+				histIndex, pending = e.historyMove(s, histIndex, pending, 1)
+			case 'C':
+				s.moveRight()
+			case 'D':
+				s.moveLeft()
+			case 'H':
+				s.pos = 0
+			case 'F':
+				s.pos = len(s.buf)
+			case '~':
+				s.deleteForward()
+			}
+		default:
+			if unicode.IsPrint(r) {
+				s.insert(r)
+			}
+		}
+		e.refresh(s)
+	}
+}
+
+// エスケープシーケンスを読み、対応するキーを表す文字を返す
+// 上下左右はA B C D、Home/EndはH F、Deleteは~を返す
+func (e *editor) readEscape() rune {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return 0
+	}
+
+	r, _, err = e.in.ReadRune()
+	if err != nil {
+		return 0
+	}
+	if r < '0' || r > '9' {
+		return r
+	}
+
+	// ESC [ 数字 ~ の形式
+	num := r
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+		if r == '~' {
+			break
+		}
+	}
+	switch num {
+	case '1', '7':
+		return 'H'
+	case '4', '8':
+		return 'F'
+	case '3':
+		return '~'
+	}
+	return 0
+}
+
+// 履歴を前後に移動する
+// 履歴の末尾より先は、履歴を遡る前に入力していた行になる
+func (e *editor) historyMove(s *lineState, index int, pending string, delta int) (int, string) {
+	next := index + delta
+	if next < 0 || next > len(e.history.entries) {
+		return index, pending
+	}
+
+	if index == len(e.history.entries) {
+		pending = string(s.buf)
+	}
+
+	if next == len(e.history.entries) {
+		s.buf = []rune(pending)
+	} else {
+		s.buf = []rune(e.history.entries[next])
+	}
+	s.pos = len(s.buf)
+	return next, pending
+}
+
+// カーソルの直前の単語を補完する
+// 候補が1つならそれで置き換え、複数なら共通の接頭辞まで補って候補を一覧表示する
+func (e *editor) completeWord(s *lineState) {
+	if e.complete == nil {
+		return
+	}
+
+	start := s.pos
+	for start > 0 && isIdentRune(s.buf[start-1]) {
+		start--
+	}
+	prefix := string(s.buf[start:s.pos])
+	if prefix == "" {
+		return
+	}
+
+	candidates := e.complete(prefix)
+	switch len(candidates) {
+	case 0:
+		io.WriteString(e.out, "\a")
+		return
+	case 1:
+		s.replace(start, candidates[0])
+		return
+	}
+
+	common := commonPrefix(candidates)
+	if len(common) > len(prefix) {
+		s.replace(start, common)
+		return
+	}
+
+	fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+}
+
+// 入力中の行を描き直す
+// カーソルは文字数ではなく端末上の表示幅だけ戻す
+func (e *editor) refresh(s *lineState) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", s.prompt, string(s.buf))
+	if n := displayWidth(s.buf[s.pos:]); n > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", n)
+	}
+}
+
+// 端末で2桁を使う東アジアの全角文字
+var wideRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1},
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe4f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1},
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+// 全角文字は2桁、結合文字などの幅のない文字は0桁と数える
+func displayWidth(runes []rune) int {
+	width := 0
+	for _, r := range runes {
+		switch {
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		case unicode.Is(wideRunes, r):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+func (s *lineState) insert(r rune) {
+	s.buf = append(s.buf, 0)
+	copy(s.buf[s.pos+1:], s.buf[s.pos:])
+	s.buf[s.pos] = r
+	s.pos++
+}
+
+// start からカーソルまでを word で置き換える
+func (s *lineState) replace(start int, word string) {
+	rest := append([]rune(word), s.buf[s.pos:]...)
+	s.buf = append(s.buf[:start], rest...)
+	s.pos = start + len([]rune(word))
+}
+
+func (s *lineState) moveLeft() {
+	if s.pos > 0 {
+		s.pos--
+	}
+}
+
+func (s *lineState) moveRight() {
+	if s.pos < len(s.buf) {
+		s.pos++
+	}
+}
+
+func (s *lineState) deleteBackward() {
+	if s.pos == 0 {
+		return
+	}
+	s.buf = append(s.buf[:s.pos-1], s.buf[s.pos:]...)
+	s.pos--
+}
+
+func (s *lineState) deleteForward() {
+	if s.pos == len(s.buf) {
+		return
+	}
+	s.buf = append(s.buf[:s.pos], s.buf[s.pos+1:]...)
+}
+
+// カーソルの直前の単語とその前の空白を消す
+func (s *lineState) deleteWord() {
+	start := s.pos
+	for start > 0 && s.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && s.buf[start-1] != ' ' {
+		start--
+	}
+	s.buf = append(s.buf[:start], s.buf[s.pos:]...)
+	s.pos = start
+}
+
+// 識別子は字句解析器と同じくUnicodeの文字を含められる
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// マルチバイト文字の途中で切らないよう、コードポイント単位で比べる
+func commonPrefix(words []string) string {
+	prefix := []rune(words[0])
+	for _, w := range words[1:] {
+		n := 0
+		for _, r := range w {
+			if n == len(prefix) || prefix[n] != r {
+				break
+			}
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
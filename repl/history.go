@@ -0,0 +1,93 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 履歴ファイルに残す最大の行数
+const maxHistory = 1000
+
+// 入力行の履歴
+// pathが空でなければ、追加した行をそのファイルに書き足す
+type history struct {
+	entries []string
+	path    string
+}
+
+// 履歴ファイルの場所
+// 環境変数MONKEY_HISTORYで変更でき、空文字列なら履歴を保存しない
+func historyPath() string {
+	if path, ok := os.LookupEnv("MONKEY_HISTORY"); ok {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".monkey_history")
+}
+
+// 履歴ファイルを読み込む
+// ファイルが無い場合は空の履歴になる
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	return h
+}
+
+func (h *history) add(line string) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return
+	}
+	// 直前と同じ行は重ねない
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		// 履歴が保存できなくてもREPLは続ける
+		return
+	}
+	defer f.Close()
+	f.WriteString(line + "\n")
+}
+
+// 履歴ファイルを最大行数に切り詰める
+func (h *history) save() error {
+	if h.path == "" || len(h.entries) == 0 {
+		return nil
+	}
+	return os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600)
+}
@@ -0,0 +1,112 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/token"
+)
+
+// 入力が続くときの先頭文字
+const CONTINUE_PROMPT = ".. "
+
+// 入力中の行をCtrl-Cで破棄したときのエラー
+var errInterrupted = errors.New("interrupted")
+
+// 1行ずつ入力を読む
+// 入力の終わりではio.EOFを返す
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+	AddHistory(line string)
+	Close() error
+}
+
+// 端末でない入力から読む
+// 行編集や履歴は扱わない
+type plainReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func newPlainReader(in io.Reader, out io.Writer) *plainReader {
+	return &plainReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *plainReader) AddHistory(line string) {}
+
+func (r *plainReader) Close() error { return nil }
+
+// 入力をひとまとまり読む
+// 括弧や文字列が閉じていない間は続きの行を読み、改行で連結して返す
+func readInput(r lineReader) (string, error) {
+	var lines []string
+	prompt := PROMPT
+
+	for {
+		line, err := r.ReadLine(prompt)
+		if err == errInterrupted {
+			// 入力中のものを捨ててやり直す
+			lines = lines[:0]
+			prompt = PROMPT
+			continue
+		}
+		if err != nil {
+			if err == io.EOF && len(lines) != 0 {
+				// 閉じていないまま終わった入力もパーサーに渡してエラーを表示させる
+				return strings.Join(lines, "\n"), nil
+			}
+			return "", err
+		}
+
+		if strings.TrimSpace(line) != "" {
+			r.AddHistory(line)
+		}
+
+		lines = append(lines, line)
+		input := strings.Join(lines, "\n")
+		if isComplete(input) {
+			return input, nil
+		}
+		prompt = CONTINUE_PROMPT
+	}
+}
+
+// 入力が閉じているかどうか
+// 開き括弧が残っている場合と、文字列やブロックコメントが閉じていない場合は続きを待つ
+// 閉じ括弧が多すぎる場合はパーサーにエラーを報告させるため完結しているとみなす
+func isComplete(input string) bool {
+	l := lexer.New(input)
+	depth := 0
+
+	for {
+		tok := l.NextToken()
+		switch tok.Type {
+		case token.EOF:
+			return depth <= 0
+		case token.LPAREN, token.LBRACE, token.LBRACKET, token.STRING_START:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET, token.STRING_END:
+			depth--
+		case token.ILLEGAL:
+			// 閉じていない文字列とブロックコメントは入力の終端を越えたところで終わる
+			if tok.End.Offset > len(input) {
+				return false
+			}
+		}
+	}
+}
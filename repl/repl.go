@@ -1,31 +1,47 @@
 package repl
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/compiler"
 	"github.com/kakts/monkey/lexer"
 	"github.com/kakts/monkey/parser"
 	"github.com/kakts/monkey/evaluator"
 	"github.com/kakts/monkey/object"
+	"github.com/kakts/monkey/vm"
 )
 
 // replの先頭文字
 const PROMPT = ">> "
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+// 実行エンジン
+const (
+	EngineEval = "eval" // 木を辿る評価器
+	EngineVM   = "vm"   // バイトコードコンパイラと仮想マシン
+)
+
+// overflowは整数演算の桁あふれの扱い
+func Start(in io.Reader, out io.Writer, engine string, overflow object.OverflowPolicy) {
+	if engine == EngineVM {
+		startVM(in, out, overflow)
+		return
+	}
+
 	env := object.NewEnvironment()
+	r := newLineReader(in, out, func(prefix string) []string {
+		return completions(prefix, env.Names())
+	})
+	defer r.Close()
+
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		// 括弧が閉じるまで複数行を読む
+		input, err := readInput(r)
+		if err != nil {
 			return
 		}
 
-		// 入力行の内容取得
-		line := scanner.Text()
-		l := lexer.New(line)
+		l := lexer.New(input)
 		p := parser.New(l)
 
 		program := p.ParseProgram()
@@ -35,7 +51,9 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
+		e := evaluator.New()
+		e.Overflow = overflow
+		evaluated := e.Eval(program, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -43,10 +61,76 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
+// vmで実行するREPL
+// 入力をまたいで定数プール、シンボル表、グローバル束縛を引き継ぐ
+func startVM(in io.Reader, out io.Writer, overflow object.OverflowPolicy) {
+	constants := []object.Object{}
+	globals := vm.NewGlobalsStore()
+	symbolTable := compiler.NewSymbolTableWithBuiltins()
+
+	r := newLineReader(in, out, func(prefix string) []string {
+		return completions(prefix, symbolTable.GlobalNames())
+	})
+	defer r.Close()
+
+	for {
+		input, err := readInput(r)
+		if err != nil {
+			return
+		}
+
+		l := lexer.New(input)
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			printParserErrors(out, p.Errors())
+			continue
+		}
+
+		comp := compiler.NewWithState(symbolTable, constants)
+		err = comp.Compile(program)
+		if err != nil {
+			fmt.Fprintf(out, "Woops! Compilation failed:\n %s\n", err)
+			continue
+		}
+
+		code := comp.Bytecode()
+		constants = code.Constants
+
+		machine := vm.NewWithGlobalsStore(code, globals)
+		machine.Overflow = overflow
+		err = machine.Run()
+		if err != nil {
+			if errObj, ok := err.(*object.Error); ok {
+				io.WriteString(out, errObj.Inspect())
+				io.WriteString(out, "\n")
+				continue
+			}
+			fmt.Fprintf(out, "Woops! Executing bytecode failed:\n %s\n", err)
+			continue
+		}
+
+		// 文が無い入力や、let文だけの入力では何も表示しない
+		if len(program.Statements) == 0 {
+			continue
+		}
+		if _, ok := program.Statements[len(program.Statements)-1].(*ast.LetStatement); ok {
+			continue
+		}
+
+		lastPopped := machine.LastPoppedStackElem()
+		if lastPopped != nil {
+			io.WriteString(out, lastPopped.Inspect())
+			io.WriteString(out, "\n")
+		}
+	}
+}
+
+func printParserErrors(out io.Writer, errors []*parser.ParseError) {
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, err := range errors {
+		io.WriteString(out, "\t"+err.Error()+"\n")
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,227 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kakts/monkey/object"
+)
+
+func TestIsComplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"", true},
+		{"let x = 5;", true},
+		{"let f = fn(x) {", false},
+		{"let f = fn(x) {\n  x + 1\n}", true},
+		{"[1, 2,", false},
+		{"{\"a\": 1", false},
+		{"add(1,", false},
+		{`"hello`, false},
+		{"\"hello\n world\"", true},
+		{`"{"`, true},
+		{"}", true},
+		{"let x = 1; }", true},
+		{"let x = 1; /* note", false},
+		{"let x = 1; /* { */", true},
+		{"let f = fn() { // }", false},
+		{"`raw\nstring", false},
+		{"`raw\nstring`", true},
+		{`"a ${`, false},
+		{`"a ${f(`, false},
+		{`"a ${x} b`, false},
+		{`"a ${ {"k": 1}["k"] } b"`, true},
+		{`"a \" b`, false},
+	}
+
+	for _, tt := range tests {
+		if got := isComplete(tt.input); got != tt.expected {
+			t.Errorf("isComplete(%q) wrong. want=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStartMultiLineInput(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+add(1,
+  2)
+let h = {
+  "one": 1,
+}
+h["one"]
+`
+	for _, engine := range []string{EngineEval, EngineVM} {
+		var out bytes.Buffer
+		Start(strings.NewReader(input), &out, engine, object.OverflowWrap)
+
+		got := out.String()
+		if strings.Contains(got, "parser errors") {
+			t.Fatalf("engine %s: unexpected parser errors:\n%s", engine, got)
+		}
+		for _, want := range []string{"3\n", "1\n", CONTINUE_PROMPT} {
+			if !strings.Contains(got, want) {
+				t.Errorf("engine %s: output does not contain %q:\n%s", engine, want, got)
+			}
+		}
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	got := completions("fi", []string{"foo", "bar", "fib", "fizz", "fib"})
+	want := []string{"fib", "filter", "first", "fizz"}
+
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("wrong completions. want=%v, got=%v", want, got)
+	}
+}
+
+func newTestEditor(input string, h *history) (*editor, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	e := &editor{
+		in:      bufio.NewReader(strings.NewReader(input)),
+		out:     out,
+		history: h,
+		raw:     func() (func(), error) { return func() {}, nil },
+		complete: func(prefix string) []string {
+			return completions(prefix, []string{"fibonacci", "filter_all", "日本語", "日本話", "café"})
+		},
+	}
+	return e, out
+}
+
+func TestEditorKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x\r", "let x"},
+		{"abd\x7fc\r", "abc"},
+		{"bc\x01a\r", "abc"},
+		{"ac\x1b[Db\r", "abc"},
+		{"ab\x1b[D\x1b[Dx\x05c\r", "xabc"},
+		{"abc\x01\x1b[3~\r", "bc"},
+		{"hello world\x17\r", "hello "},
+		{"let x = 1\x17\x17\r", "let x "},
+		{"abc\x01\x0b\r", ""},
+		{"fib\t(1)\r", "fibonacci(1)"},
+		{"fi\t\r", "fi"},
+		{"pu\t\r", "pu"},
+		{"le\t\r", "len"},
+		// 共通の接頭辞はコードポイント単位で求める
+		{"日\t\r", "日本"},
+		{"x + 日本語\t\r", "x + 日本語"},
+		{"caf\t\r", "café"},
+		{"(caf\t)\r", "(café)"},
+	}
+
+	for _, tt := range tests {
+		e, _ := newTestEditor(tt.input, &history{})
+		got, err := e.ReadLine(PROMPT)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", tt.input, err)
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong line. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestEditorControl(t *testing.T) {
+	e, _ := newTestEditor("\x04", &history{})
+	if _, err := e.ReadLine(PROMPT); err != io.EOF {
+		t.Errorf("Ctrl-D on empty line should return io.EOF. got=%v", err)
+	}
+
+	e, _ = newTestEditor("abc\x03", &history{})
+	if _, err := e.ReadLine(PROMPT); err != errInterrupted {
+		t.Errorf("Ctrl-C should return errInterrupted. got=%v", err)
+	}
+}
+
+// 全角文字の上ではカーソルを2桁ずつ戻す
+func TestEditorCursorWidth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"日本語\x01\r", "\x1b[6D"},
+		{"日本語\x01\x1b[C\r", "\x1b[4D"},
+		{"a日b\x1b[D\x1b[D\r", "\x1b[3D"},
+		{"cafe\u0301\x01\r", "\x1b[4D"},
+	}
+
+	for _, tt := range tests {
+		e, out := newTestEditor(tt.input, &history{})
+		if _, err := e.ReadLine(PROMPT); err != nil {
+			t.Fatalf("%q: unexpected error: %s", tt.input, err)
+		}
+		if !strings.HasSuffix(out.String(), tt.expected+"\r\n") {
+			t.Errorf("%q: cursor not moved by %q. got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	h := &history{entries: []string{"let a = 1", "a + 1"}}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"\x1b[A\r", "a + 1"},
+		{"\x1b[A\x1b[A\r", "let a = 1"},
+		{"\x1b[A\x1b[A\x1b[A\r", "let a = 1"},
+		{"typed\x1b[A\x1b[B\r", "typed"},
+		{"\x10\x10\x0e\r", "a + 1"},
+	}
+
+	for _, tt := range tests {
+		e, _ := newTestEditor(tt.input, h)
+		got, err := e.ReadLine(PROMPT)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", tt.input, err)
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong line. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "monkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history")
+
+	h := loadHistory(path)
+	h.add("let a = 1")
+	h.add("let a = 1")
+	h.add("a")
+
+	loaded := loadHistory(path)
+	if strings.Join(loaded.entries, "|") != "let a = 1|a" {
+		t.Errorf("wrong history entries. got=%q", loaded.entries)
+	}
+
+	for i := 0; i < maxHistory+10; i++ {
+		loaded.add(strings.Repeat("x", i%2+1))
+	}
+	if err := loaded.save(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(loadHistory(path).entries); n != maxHistory {
+		t.Errorf("history was not truncated. want=%d, got=%d", maxHistory, n)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package repl
+
+import "errors"
+
+// linux以外では行編集を行わず、通常の行読み込みを使う
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw mode is not supported on this platform")
+}
@@ -1,13 +1,17 @@
 package token
 
+import "fmt"
+
 // TokenTypeの定義一覧
 const (
 	ILLEGAL = "ILLEGAL" // tokenが未知の文字
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // コメント (構文解析器には渡さない)
 
 	// 識別子 + リテラル
 	IDENT = "IDENT" // add, foober, x, y...
 	INT   = "INT"   // 123456
+	FLOAT = "FLOAT" // 3.14, 1e-9
 
 	// 演算子
 	ASSIGN   = "="
@@ -16,12 +20,25 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
+
+	// 複合代入
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	PERCENT_ASSIGN  = "%="
 
 	EQ       = "=="
 	NOT_EQ   = "!="
 
-	LT = "<"
-	GT = ">"
+	LT    = "<"
+	GT    = ">"
+	LT_EQ = "<="
+	GT_EQ = ">="
+
+	AND = "&&"
+	OR  = "||"
 
 	COMMA     = ","
 	SEMICOLON = ";"
@@ -35,6 +52,7 @@ const (
 	RBRACKET = "]"
 
 	COLON = ":"
+	DOT   = "."
 
 	// keyword
 	FUNCTION = "FUNCTION"
@@ -44,8 +62,21 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
 
 	STRING = "STRING"
+
+	// 埋め込み式 ("a ${x} b ${y} c") を含む文字列は
+	// STRING_START("a ") x STRING_MIDDLE(" b ") y STRING_END(" c") の順に分けて返す
+	STRING_START  = "STRING_START"
+	STRING_MIDDLE = "STRING_MIDDLE"
+	STRING_END    = "STRING_END"
 )
 
 type TokenType string
@@ -53,6 +84,38 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // トークンの開始位置
+	End     Position // トークンの終了位置（最後の文字の直後）
+}
+
+// ソースコード上の位置
+type Position struct {
+	Filename string // ファイル名 (無い場合は空)
+	Offset   int    // 先頭からのバイトオフセット 0始まり
+	Line     int    // 行番号 1始まり
+	Column   int    // 列番号 1始まり
+}
+
+// 位置情報が設定されているかどうか
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// file:line:column 形式の文字列を返す
+// ファイル名が無い場合は line:column
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+
+	s := fmt.Sprintf("%d:%d", p.Line, p.Column)
+	if p.Filename != "" {
+		s = p.Filename + ":" + s
+	}
+	return s
 }
 
 var keywords = map[string]TokenType{
@@ -63,6 +126,13 @@ var keywords = map[string]TokenType{
 	"if": IF,
 	"else": ELSE,
 	"return": RETURN,
+	"while": WHILE,
+	"for": FOR,
+	"in": IN,
+	"break": BREAK,
+	"continue": CONTINUE,
+	"import": IMPORT,
+	"export": EXPORT,
 }
 
 // keywordsテーブルをチェックして 渡された識別子が実はキーワードでなかったかチェック
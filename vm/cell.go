@@ -0,0 +1,40 @@
+package vm
+
+import "github.com/kakts/monkey/object"
+
+// クロージャに捕捉されたローカル束縛を保持する箱
+// 外側の関数のフレームとクロージャが同じセルを参照するため、
+// どちらから代入しても互いに新しい値が見える
+// セルは束縛を読み出す時に中身へ置き換えられ、スクリプトからは見えない
+type cell struct {
+	value object.Object
+}
+
+func (c *cell) Type() object.ObjectType { return "CELL" }
+func (c *cell) Inspect() string         { return "cell" }
+
+// セルなら中身を返す
+func deref(obj object.Object) object.Object {
+	if c, ok := obj.(*cell); ok {
+		return c.value
+	}
+	return obj
+}
+
+// 束縛の格納場所slotに値を書き込む
+// セルが入っている場合はセルの中身を書き換える
+func store(slot *object.Object, val object.Object) {
+	if c, ok := (*slot).(*cell); ok {
+		c.value = val
+		return
+	}
+	*slot = val
+}
+
+// for-inの間スタックに置かれる反復子
+type iterator struct {
+	it object.Iterator
+}
+
+func (i *iterator) Type() object.ObjectType { return "ITERATOR" }
+func (i *iterator) Inspect() string         { return "iterator" }
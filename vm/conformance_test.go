@@ -0,0 +1,500 @@
+package vm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kakts/monkey/compiler"
+	"github.com/kakts/monkey/evaluator"
+	"github.com/kakts/monkey/object"
+)
+
+// evaluatorとvmが同じ結果を返すことを確かめる共通のテストケース
+// 関数の値はInspectの表現が異なるため、結果が関数になるケースは含めない
+var conformanceTests = []string{
+	// 整数と真偽値
+	"5",
+	"-50 + 100 + -50",
+	"(5 + 10 * 2 + 15 / 3) * 2 + -10",
+	"1 < 2",
+	"1 > 2 == false",
+	"!5",
+	"!!true",
+	"1 == true",
+	"true != false",
+
+	// 比較と論理演算
+	"1 <= 2",
+	"2 >= 3",
+	"1.5 <= 1.5",
+	"0 / 0.0 <= 0 / 0.0",
+	"10 % 3",
+	"-7 % 2",
+	"7.5 % 2",
+	"1 % 0",
+	"7 % 0.0",
+	"true && 1",
+	"1 && if (false) { 1 }",
+	"false || true",
+	"0 || false",
+	"false && foobar",
+	"true || foobar",
+	"let f = fn() { 1 / 0 }; false && f()",
+	"let f = fn() { 1 / 0 }; true && f()",
+	`"a" >= "b"`,
+	"true <= false",
+	"let f = fn(x) { if (x % 2 == 0 && x > 0 || x == -1) { 1 } else { 0 } }; [f(0), f(2), f(3), f(-1)]",
+
+	// 浮動小数点数
+	"3.14",
+	"2.0",
+	"1 + 2.5 * 2",
+	"7 / 2.0",
+	"-1.5 - 1",
+	"1.0 / 0",
+	"0.1 + 0.2",
+	"1 == 1.0",
+	"1.5 > 1",
+	"1.5 < true",
+	"-(1.5 + 1.5)",
+	`{1: "int"}[1.0]`,
+	`{2.5: "a"}[2.5]`,
+	"[floor(1.5), ceil(1.5), round(1.5), sqrt(9), pow(2, 0.5), pow(3, 3)]",
+
+	// ゼロ除算
+	"1 / 0",
+	"let f = fn(x) { 10 / x }; f(0)",
+	"0 / 0.0 == 0 / 0.0",
+
+	// 条件式
+	"if (true) { 10 }",
+	"if (false) { 10 }",
+	"if (1 > 2) { 10 } else { 20 }",
+	"if (1) { 10 }",
+	"if (true) { if (false) { 1 } else { 2 } }",
+
+	// let文
+	"let a = 5; let b = a; let c = a + b + 5; c;",
+	"let a = 1; let a = a + 1; a",
+
+	// 代入
+	"let x = 1; x = 2; x",
+	"let x = 1; x += 2",
+	"let x = 7; x %= 4; x *= 3; x -= 1; x /= 2; x",
+	"let x = 1.5; x += 1; x",
+	"let a = 1; let b = 2; a = b = 3; [a, b]",
+	"let x = 1; let f = fn() { x = x + 1 }; f(); f(); x",
+	"let x = 1; let f = fn(x) { x = 10 }; f(0); x",
+	"let counter = fn() { let c = 0; fn() { c += 1 } }; let f = counter(); let g = counter(); f(); f(); [f(), g()]",
+	"let f = fn() { let n = 0; let inc = fn() { n += 1 }; inc(); inc(); n }; f()",
+	"let f = fn(n) { let g = fn() { fn() { n *= 2 } }; g()(); g()(); n }; f(3)",
+	"let f = fn() { let x = 1; let g = fn() { x }; let x = 2; g() }; f()",
+	"let arr = [1, 2, 3]; arr[0] = 10; arr[2] += arr[0]; arr",
+	"let a = [1]; let b = a; b[0] = 9; a",
+	`let h = {"a": 1}; h["a"] *= 5; h["b"] = 2; [h["a"], h["b"]]`,
+	"let grid = [[0, 0], [0, 0]]; grid[1][0] = 7; grid",
+	"x = 1",
+	"x += 1",
+	"let arr = [1]; arr[1] = 2",
+	"let arr = [1]; arr[5] += 2",
+	`let h = {}; h["k"] += 1`,
+	`let s = "abc"; s[0] = "x"`,
+	"let h = {}; h[[1]] = 1",
+	"let x = true; x += 1",
+	"let x = 1; x /= 0",
+
+	// ループ
+	"let i = 0; while (i < 5) { i += 1 }; i",
+	"while (false) { 1 }",
+	"let i = 0; while (true) { i += 1; if (i == 3) { break; } }; i",
+	"let s = 0; let i = 0; while (i < 10) { i += 1; if (i % 2 == 0) { continue; } s += i }; s",
+	"let s = 0; for (x in [1, 2, 3]) { s += x }; s",
+	`let s = ""; for (c in "héllo") { s = c + s }; s`,
+	`let n = 0; for (k in {"a": 1}) { n = k }; n`,
+	"let s = []; for (x in range(10, 0, -3)) { s = push(s, x) }; s",
+	"range(1, 5)",
+	"range(0, 10, 2)",
+	"let a = [1, 2]; for (x in a) { if (x < 4) { a = push(a, x + 2) } }; a",
+	"for (x in [1, 2, 3]) { x }; x",
+	"let f = fn() { for (x in range(10)) { if (x == 4) { return x; } } }; f()",
+	"let f = fn(n) { let i = 0; while (true) { i += 1; if (i == n) { return i * 10; } } }; f(3)",
+	"let n = 0; for (i in range(3)) { for (j in range(3)) { if (j == 1) { break; } n += 1 } }; n",
+	"let r = 0; for (i in range(3)) { r += [1, if (i == 1) { continue; } else { i }][0] }; r",
+	"let fs = []; for (i in range(3)) { fs = push(fs, fn() { i }) }; [fs[0](), fs[2]()]",
+	"let f = fn() { let t = 0; for (x in range(4)) { let g = fn() { t += x }; g() }; t }; f()",
+	"for (x in 5) { x }",
+	"for (x in range(3)) { x + true }",
+	"range(1, 2, 0)",
+	`range("a")`,
+	"range()",
+
+	// 文字列、配列、ハッシュ
+	`"Hello" + " " + "World!"`,
+	`[1, 2 * 2, 3 + 3]`,
+	`let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];`,
+	"[1, 2, 3][3]",
+	"[1, 2, 3][-1]",
+	"[1, 2, 3][-4]",
+	`["abc"[-1], "abc"[-3], "abc"[-4]]`,
+	// スライス
+	"let a = [1, 2, 3, 4]; [a[1:3], a[:2], a[2:], a[:], a[-2:], a[:-1], a[-100:100], a[3:1]]",
+	`let s = "日本語です"; [s[1:3], s[:-1], s[-2:], s[5:], s[10:20]]`,
+	"let a = [1, 2, 3]; let b = a[:]; b[0] = 9; [a, b]",
+	"let f = fn(a) { if (len(a) == 0) { 0 } else { a[0] + f(a[1:]) } }; f([1, 2, 3, 4])",
+	"[1, 2][if (false) { 1 }:]",
+	"let a = [1, 2, 3]; a[-1] = 9; a[-3] += 1; a",
+	"let a = [1]; a[-2] = 0",
+	"[1, 2][1.5:]",
+	`{"a": 1}[0:1]`,
+	`{"foo": 5}["foo"]`,
+	`{"foo": 5}["bar"]`,
+	`{true: 5}[true]`,
+	`let key = "foo"; {"foo": 5}[key]`,
+	`"日本語"[1]`,
+	`"abc"[3]`,
+	`let 挨拶 = "こんにちは"; [len(挨拶), 挨拶[4]]`,
+	`let s = ""; for (c in "🐒語") { s = s + c + "," }; s`,
+	`{"キー": 1}["キー"]`,
+	`let name = "世界"; "hello ${name}! ${1 + 2} ${[1, 2.5]} ${ {"k": "v"}["k"] }"`,
+	`"${"in${"ne"}r"}" + "\u{1F412}\t\"\\\$"`,
+	"`raw ${x} \\n`",
+	`let f = fn(x) { "<${x}>" }; [f(1), f(if (false) { 1 }), f(len)]`,
+	`"${if (false) { 1 }}${true}"`,
+	`let s = ""; for (i in range(3)) { s = "${s}${if (i == 1) { continue; } else { i }}" }; s`,
+	`"x: ${foobar}"`,
+	`"x: ${1 + true}"`,
+	`let s = "ab"; s[0] += "x"`,
+
+	// 関数とクロージャ
+	"let identity = fn(x) { x; }; identity(5);",
+	"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));",
+	"fn(x) { x; }(5)",
+	"let f = fn() { return 1; 2 }; f()",
+	"let newAdder = fn(x) { fn(y) { x + y } }; let addTwo = newAdder(2); addTwo(3);",
+	`let fib = fn(x) { if (x < 2) { x } else { fib(x - 1) + fib(x - 2) } }; fib(15)`,
+	`let f = fn() { let g = fn(n) { if (n == 0) { 0 } else { g(n - 1) } }; g(3) }; f()`,
+	"let f = fn() { g() }; let g = fn() { 7 }; f()",
+	"if (10 > 1) { if (10 > 1) { return 10; } return 1; }",
+	"return 5; 10",
+
+	// 組み込み関数
+	`len("hello world")`,
+	`len([1, 2, 3])`,
+	`first([1, 2, 3])`,
+	`last([1, 2, 3])`,
+	`rest([1, 2, 3])`,
+	`rest([])`,
+	`push([1], 2)`,
+	`let len = fn(x) { 42 }; len("a")`,
+	`[split("a,b", ","), join(["x", "y"], ", "), trim("  t  "), upper("é"), lower("Q")]`,
+	`[contains("monkey", "key"), index_of("日本語", "語"), replace("a.b.c", ".", "/")]`,
+	`[starts_with("abc", "a"), ends_with("abc", "a"), substr("日本語", 1), repeat("-", 3)]`,
+	`if (contains("abc", "b")) { "yes" } else { "no" }`,
+	`contains("abc", "z") == false`,
+	`format("%s=%d (%.2f%%)", "x", 7, 12.345)`,
+	`[str(1.5), int("12") + 1, int(2.9), parse_int("ff", 16), parse_int("nope")]`,
+	`int("99999999999999999999")`,
+	`[parse_int("99999999999999999999"), parse_int("-8000000000000000000", 16)]`,
+	`format("%d", true)`,
+	`repeat("a", -1)`,
+	`int("1.5")`,
+	`upper(1)`,
+	"let double = fn(x) { x * 2 }; [map([1, 2, 3], double), map(range(3), double), map(\"ab\", upper)]",
+	"[filter([1, 2, 3, 4], fn(x) { x % 2 == 0 }), reduce([1, 2, 3], fn(a, b) { a * b }), reduce([], fn(a, b) { a }, 0)]",
+	"let total = 0; each(range(5), fn(x) { total += x }); total",
+	"[sort([3, 1.5, -2]), sort([\"b\", \"a\"]), sort([[1, 1], [0, 2], [1, 3]], fn(a, b) { a[0] < b[0] })]",
+	"[reverse([1, 2]), reverse(\"日本\"), zip([1, 2], [3, 4], [5]), flatten([1, [2, [3]]]), flatten([[[1]]], 2)]",
+	"[any([1, 2], fn(x) { x > 1 }), all([1, 2], fn(x) { x > 1 }), any([], fn(x) { x })]",
+	"map([1, 2], fn(x) { if (x == 1) { return 10; } x })",
+	"let f = fn(n) { if (n == 0) { return 1; } reduce(range(n), fn(a, x) { a + f(x) }, 1) }; f(6)",
+	"map(range(3), fn(x) { map(range(x), fn(y) { y * 10 }) })",
+	"let fs = map(range(3), fn(i) { fn() { i } }); map(fs, fn(f) { f() })",
+	"map([1, 2], fn(x) { x + true })",
+	"map([1], fn(a, b) { a })",
+	"sort([1, \"a\"])",
+	"reduce([], fn(a, b) { a })",
+	"filter(1, fn(x) { x })",
+	"sort([2, 1], fn(a, b) { a + true })",
+	"any([1], 2)",
+	`let h = {"a": 1, "b": 2}; [len(h), keys(h), values(h), entries(h)]`,
+	`let h = {1: "x"}; [has(h, 1), has(h, 1.0), has(h, 2), h]`,
+	`let h = {"a": 1}; let g = set(h, "b", 2); [len(h), len(g), g["b"], delete(g, "a"), h]`,
+	`merge({"a": 1}, {"a": 2}, {"a": 3})`,
+	`{"z": 1, "a": 2, 10: 3, 2: 4, true: 5, 1.5: 6}`,
+	`let h = {"b": 1, "a": 1}; h["c"] = 2; h["b"] = 3; [h, keys(h), values(h), entries(h)]`,
+	`let s = ""; for (k in {"y": 1, "x": 2, "w": 3}) { s += k }; s`,
+	`merge({"b": 1, "a": 2}, {"c": 3, "b": 4})`,
+	`set(delete({"a": 1, "b": 2}, "a"), "a", 3)`,
+	`let h = {}; for (k in keys({"x": 1, "y": 2})) { h = set(h, k, true) }; len(h)`,
+	`has({}, [1])`,
+	// 等価性
+	`["a" == "a", "a" != "a", "a" == "b", "1" == 1, 1 == 1.0, [1, 2] == [1, 2], [1, 2] == [2, 1]]`,
+	`[[1, [2.0, "x"]] == [1.0, [2, "x"]], [] == [], [1] == [1, 1], [] == {}, {} == {}]`,
+	`[{"a": 1, "b": 2} == {"b": 2, "a": 1}, {"a": 1} == {"a": 1.0}, {"a": 1} != {"a": 2}, {1: true} == {1.0: true}]`,
+	`let n = if (false) { 1 }; [n == n, n == if (false) { 2 }, n == false, n != 0]`,
+	`let f = fn(x) { x }; [f == f, f == fn(x) { x }, len == len, len == first]`,
+	`let a = [1]; let b = a; push(b, 2); [a == b, a == [1], b == [1, 2]]`,
+	`let nan = 0.0 / 0.0; [nan == nan, [nan] == [nan]]`,
+	// 自分自身を含む配列とハッシュ
+	"let a = [0]; a[0] = a; [a, len(a), flatten(a, 3)]",
+	`let h = {}; h["h"] = h; h["x"] = [h]; h`,
+	`let a = [0]; a[0] = a; "${a}"`,
+	"let a = [0]; let b = [0]; a[0] = a; b[0] = b; [a == b, a != b, a == [a], a == [0]]",
+	`let h = {}; h["h"] = h; let g = {}; g["h"] = g; [h == g, h != g, [h] == [g]]`,
+	`values(1)`,
+	`merge()`,
+	// メンバーアクセスとメソッド
+	`let h = {"name": "monkey", "keys": 1}; [h.name, h.keys, h.missing, {"x": {"y": 2}}.x.y]`,
+	`[{"b": 1, "a": 2}.keys(), {"a": 1}.len(), "abc".upper(), [3, 1, 2].sort().reverse()]`,
+	`[1, 2, 3].map(fn(x) { x * 2 }).filter(fn(x) { x > 2 }).reduce(fn(a, b) { a + b })`,
+	`let f = fn(s) { s.split(",").map(fn(x) { x.trim().upper() }) }; f(" a, b ")`,
+	`let push = [1].push; push(2)`,
+	`"abc".foo()`,
+	`5.foo`,
+	`{}.x.y`,
+	`[1].map(fn(x) { x.foo })`,
+
+	// エラー
+	"5 + true;",
+	"5 + true; 5;",
+	"-true",
+	"true + false",
+	`"Hello" - "World"`,
+	`"a" < "b"`,
+	"1 < true",
+	"foobar",
+	"if (false) { 1 } else { foobar }",
+	"1(2)",
+	"fn(a) { a }()",
+	`{"name": "Monkey"}[[1]];`,
+	`[1, 2]["a"]`,
+	"1[0]",
+	`len(1)`,
+	`len("one", "two")`,
+	"let f = fn() { 1 + true }; f(); 5",
+
+	// エラーの位置
+	"let a = 1;\nlet b = a + c;",
+	"let f = fn(x) {\n  x + true\n};\nf(1)",
+	"5;\n  -true",
+	"let f = fn() {\n  return 1 / 0;\n};\nf()",
+	"map([1, 2], fn(x) {\n  x / 0\n})",
+	"[1, 2].filter(fn(x) {\n  x.foo\n})",
+	"let h = {};\nh[[1]] = 2",
+	"let x = 1;\nx += \"a\"",
+	"for (x in [1]) {\n  if (x) { y }\n}",
+	"let i = 0;\nwhile (i < 3) {\n  i += 1;\n  if (i == 2) { i(1) }\n}",
+	"let s = \"a\";\n\"x ${s - 1}\"",
+	"[1, 2][0:\"a\"]",
+	"let f = fn(a, b) { a };\n\n  f(1)",
+	// range
+	"len(range(3))",
+	"range(0, 10, 2)[1]",
+	"range(0, 10, 2)[-1]",
+	"range(3)[5]",
+	"sort(range(3))",
+	"reverse(range(3))",
+	"range(3) == range(3)",
+	"range(3) == [0, 1, 2]",
+}
+
+// 整数の桁あふれの扱いごとに比べるケース
+var overflowConformanceTests = []string{
+	"9223372036854775807 + 1",
+	"-9223372036854775807 - 2",
+	"4611686018427387904 * 2",
+	"let min = -9223372036854775807 - 1; min / -1",
+	"let min = -9223372036854775807 - 1; -min",
+	"pow(2, 64)",
+	"pow(2, 64) / pow(2, 60)",
+	"pow(2, 64) > 9223372036854775807",
+	"pow(2, 64) == pow(4, 32)",
+	"pow(2, 64) + 0.5",
+	"-pow(2, 64)",
+	`{pow(2, 64): "big"}[pow(2, 64)]`,
+	"pow(10, 19) - pow(10, 19) + 1",
+	"pow(2, 64) % 7",
+	"let min = -9223372036854775807 - 1; min % -1",
+	"pow(2, 64) >= pow(2, 63)",
+	"[1, 2][pow(2, 64)]",
+	`"ab"[-pow(2, 64)]`,
+	"let a = [1]; a[pow(2, 64)] = 2",
+	"range(pow(2, 64))",
+	"range(pow(2, 64) - pow(2, 64) + 3)",
+
+	// 関数の型名はどちらもFUNCTION
+	"{fn() {}: 1}",
+	"fn() {} + 1",
+	"-fn(x) { x }",
+	"len(fn() {})",
+	"let f = fn() {}; [f][0] == 1; f[0]",
+	"map([1], fn(x) { fn() { x } })[0] + 1",
+}
+
+func TestConformance(t *testing.T) {
+	for _, input := range conformanceTests {
+		evalResult := runEvaluator(input, object.OverflowWrap)
+		vmResult := runVM(t, input, object.OverflowWrap)
+
+		if evalResult != vmResult {
+			t.Errorf("backends disagree for %q.\neval=%s\nvm  =%s", input, evalResult, vmResult)
+		}
+	}
+}
+
+func TestOverflowConformance(t *testing.T) {
+	policies := []object.OverflowPolicy{object.OverflowWrap, object.OverflowError, object.OverflowPromote}
+
+	for _, policy := range policies {
+		for _, input := range overflowConformanceTests {
+			evalResult := runEvaluator(input, policy)
+			vmResult := runVM(t, input, policy)
+
+			if evalResult != vmResult {
+				t.Errorf("backends disagree for %q (%s).\neval=%s\nvm  =%s", input, policy, evalResult, vmResult)
+			}
+		}
+	}
+}
+
+// モジュールのテストケース DIRはモジュールを置いたディレクトリに置き換える
+var moduleConformanceTests = []string{
+	`import "DIR/math.mk" as m; m.double(21)`,
+	`import { double, pi } from "DIR/math.mk"; double(pi)`,
+	`import "DIR/math.mk" as m; m.pi + m.pi`,
+	`import "DIR/math.mk" as m; m.helper`,
+	`import "DIR/math.mk" as m; m.pi.x`,
+	`import "DIR/math.mk" as m; import { next } from "DIR/math.mk"; next(); m.next()`,
+	`import "DIR/uses_math.mk" as u; import "DIR/math.mk" as m; u.twice(); m.next()`,
+	`import { twice } from "DIR/uses_math.mk"; let f = fn() { twice() + 1 }; f()`,
+	// モジュールの束縛はメインから見えない
+	`import { pi } from "DIR/math.mk"; helper`,
+	`let helper = 10; import { double } from "DIR/math.mk"; double(1) + helper`,
+	`import "DIR/fail.mk" as f; f`,
+	`let m = 1; m.x`,
+}
+
+func TestModuleConformance(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"math.mk": `
+let helper = fn(x) { x * 2 };
+export let double = fn(x) { helper(x) };
+export let pi = 3;
+let count = 0;
+export let next = fn() { count = count + 1; count };
+`,
+		"uses_math.mk": `
+import { next } from "math.mk";
+export let twice = fn() { next(); next() };
+`,
+		"fail.mk": `export let x = 1 / 0;`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, input := range moduleConformanceTests {
+		input = strings.ReplaceAll(input, "DIR", dir)
+		evalResult := runEvaluator(input, object.OverflowWrap)
+		vmResult := runVM(t, input, object.OverflowWrap)
+
+		if evalResult != vmResult {
+			t.Errorf("backends disagree for %q.\neval=%s\nvm  =%s", input, evalResult, vmResult)
+		}
+	}
+}
+
+// exportされていない名前のimportは、モジュールを実行する前にエラーになる
+// vmはコンパイル時に検出するため、evaluatorもモジュールの副作用を起こさない
+func TestImportMissingExportConformance(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "loud.mk")
+	if err := os.WriteFile(path, []byte(`puts("loaded"); export let x = 1;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	stdout := object.Stdout
+	object.Stdout = &out
+	defer func() { object.Stdout = stdout }()
+
+	inputs := []string{
+		`import { missing } from "DIR/loud.mk"; x`,
+		`import { x, missing } from "DIR/loud.mk"; x`,
+	}
+	for _, input := range inputs {
+		input = strings.ReplaceAll(input, "DIR", dir)
+		expected := "module " + path + " does not export missing"
+
+		out.Reset()
+		result := evaluator.New().Eval(parse(input), object.NewEnvironment())
+		errObj, ok := result.(*object.Error)
+		if !ok || errObj.Message != expected {
+			t.Errorf("evaluator result for %q wrong. want=%q, got=%s", input, expected, describe(result))
+		}
+		if out.Len() != 0 {
+			t.Errorf("evaluator ran the module for %q. output=%q", input, out.String())
+		}
+
+		out.Reset()
+		err := compiler.New().Compile(parse(input))
+		if err == nil || err.Error() != expected {
+			t.Errorf("compiler error for %q wrong. want=%q, got=%v", input, expected, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("compiler ran the module for %q. output=%q", input, out.String())
+		}
+	}
+}
+
+// 結果を比較用の文字列にする
+// エラーは発生した位置も含めて比べる
+func describe(obj object.Object) string {
+	switch obj := obj.(type) {
+	case nil:
+		return "null"
+	case *object.Error:
+		return "ERROR: " + obj.Error()
+	default:
+		return string(obj.Type()) + " " + obj.Inspect()
+	}
+}
+
+func runEvaluator(input string, overflow object.OverflowPolicy) string {
+	program := parse(input)
+	env := object.NewEnvironment()
+
+	e := evaluator.New()
+	e.Overflow = overflow
+	return describe(e.Eval(program, env))
+}
+
+func runVM(t *testing.T, input string, overflow object.OverflowPolicy) string {
+	t.Helper()
+
+	program := parse(input)
+
+	comp := compiler.New()
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error for %q: %s", input, err)
+	}
+
+	vm := New(comp.Bytecode())
+	vm.Overflow = overflow
+	err = vm.Run()
+	if err != nil {
+		if errObj, ok := err.(*object.Error); ok {
+			return describe(errObj)
+		}
+		t.Fatalf("vm error for %q: %s", input, err)
+	}
+
+	return describe(vm.LastPoppedStackElem())
+}
@@ -0,0 +1,25 @@
+package vm
+
+import (
+	"github.com/kakts/monkey/code"
+	"github.com/kakts/monkey/object"
+)
+
+// 関数呼び出し1回分の実行状態
+type Frame struct {
+	cl          *object.Closure
+	ip          int // 命令ポインタ
+	basePointer int // 呼び出し時のスタックポインタ ローカル束縛はここから始まる
+
+	// 実行中のループに入った時点のスタックポインタ 最後が最も内側
+	// break と continue でスタックをこの高さに戻す
+	loops []int
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
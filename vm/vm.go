@@ -0,0 +1,1054 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/kakts/monkey/code"
+	"github.com/kakts/monkey/compiler"
+	"github.com/kakts/monkey/object"
+)
+
+const StackSize = 2048
+const GlobalsSize = 65536
+const MaxFrames = 1024
+
+var True = object.TRUE
+var False = object.FALSE
+var Null = &object.Null{}
+
+// バイトコードを実行するスタックマシン
+// 評価器(evaluator)と同じ意味論で動作する
+type VM struct {
+	constants []object.Object
+
+	stack []object.Object
+	sp    int // 次に積む位置 スタックの先頭は stack[sp-1]
+
+	globals     []object.Object
+	globalNames []string
+
+	frames      []*Frame
+	framesIndex int
+
+	// importしたモジュール (キーはモジュールの関数の定数プール内のインデックス)
+	modules map[int]object.Object
+
+	// 整数演算の結果がint64に収まらない場合の扱い
+	Overflow object.OverflowPolicy
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions, Positions: bytecode.Positions}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
+	return &VM{
+		constants: bytecode.Constants,
+
+		stack: make([]object.Object, StackSize),
+		sp:    0,
+
+		globals:     make([]object.Object, GlobalsSize),
+		globalNames: bytecode.GlobalNames,
+
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// REPLのように複数回実行する場合に、グローバル束縛を引き継ぐ
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals = s
+	return vm
+}
+
+// NewWithGlobalsStoreに渡すグローバル束縛の領域を生成する
+func NewGlobalsStore() []object.Object {
+	return make([]object.Object, GlobalsSize)
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return newError("stack overflow")
+	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// 最後にスタックから取り除かれた値 (プログラムの評価結果)
+func (vm *VM) LastPoppedStackElem() object.Object {
+	return vm.stack[vm.sp]
+}
+
+// 実行時エラーはevaluatorと同じく*object.Errorで返す
+func (vm *VM) Run() error {
+	return vm.run(0)
+}
+
+// フレームの数がstopに戻るまで命令を実行する
+// stopが0の場合はmainの命令を最後まで実行する
+func (vm *VM) run(stop int) error {
+	err := vm.execute(stop)
+
+	// エラーに発生した命令の位置を記録する
+	// 組み込み関数から呼び出した関数の中で起きたエラーなど、既に位置があれば上書きしない
+	if errObj, ok := err.(*object.Error); ok && !errObj.Pos.IsValid() {
+		frame := vm.currentFrame()
+		errObj.Pos = frame.cl.Fn.Positions.Lookup(frame.ip)
+	}
+	return err
+}
+
+func (vm *VM) execute(stop int) error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.framesIndex > stop && vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			err := vm.push(vm.constants[constIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod:
+			err := vm.executeBinaryOperation(op)
+			if err != nil {
+				return err
+			}
+
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan,
+			code.OpGreaterEqual, code.OpLessEqual:
+			err := vm.executeComparison(op)
+			if err != nil {
+				return err
+			}
+
+		case code.OpBang:
+			err := vm.executeBangOperator()
+			if err != nil {
+				return err
+			}
+
+		case code.OpMinus:
+			err := vm.executeMinusOperator()
+			if err != nil {
+				return err
+			}
+
+		case code.OpPop:
+			vm.pop()
+
+		case code.OpTrue:
+			err := vm.push(True)
+			if err != nil {
+				return err
+			}
+
+		case code.OpFalse:
+			err := vm.push(False)
+			if err != nil {
+				return err
+			}
+
+		case code.OpNull:
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			// ループでipがインクリメントされるので1つ手前にする
+			vm.currentFrame().ip = pos - 1
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.pop()
+			if !isTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			vm.globals[globalIndex] = vm.pop()
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			value := vm.globals[globalIndex]
+			if value == nil {
+				return newError("identifier not found: %s", vm.globalName(int(globalIndex)))
+			}
+
+			err := vm.push(value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			store(&vm.stack[frame.basePointer+int(localIndex)], vm.pop())
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			value := deref(vm.stack[frame.basePointer+int(localIndex)])
+			if value == nil {
+				// letで定義される前に参照された
+				return newError("identifier not found")
+			}
+
+			err := vm.push(value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			definition := object.Builtins[builtinIndex]
+
+			err := vm.push(definition.Builtin)
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(deref(currentClosure.Free[freeIndex]))
+			if err != nil {
+				return err
+			}
+
+		case code.OpCaptureLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			// 初めて捕捉される束縛はその場でセルに入れ替える
+			slot := &vm.stack[vm.currentFrame().basePointer+int(localIndex)]
+			if _, ok := (*slot).(*cell); !ok {
+				*slot = &cell{value: *slot}
+			}
+
+			err := vm.push(*slot)
+			if err != nil {
+				return err
+			}
+
+		case code.OpCaptureFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(currentClosure.Free[freeIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpAssignGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			if vm.globals[globalIndex] == nil {
+				return newError("identifier not found: %s", vm.globalName(int(globalIndex)))
+			}
+			vm.globals[globalIndex] = vm.stack[vm.sp-1]
+
+		case code.OpAssignLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			store(&vm.stack[frame.basePointer+int(localIndex)], vm.stack[vm.sp-1])
+
+		case code.OpAssignFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			store(&currentClosure.Free[freeIndex], vm.stack[vm.sp-1])
+
+		case code.OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+
+			err := vm.executeSetIndex(left, index, value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpDup2:
+			err := vm.push(vm.stack[vm.sp-2])
+			if err != nil {
+				return err
+			}
+			err = vm.push(vm.stack[vm.sp-2])
+			if err != nil {
+				return err
+			}
+
+		case code.OpLoopEnter:
+			frame := vm.currentFrame()
+			frame.loops = append(frame.loops, vm.sp)
+
+		case code.OpLoopExit:
+			frame := vm.currentFrame()
+			frame.loops = frame.loops[:len(frame.loops)-1]
+
+		case code.OpLoopJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+
+			// 式の途中で抜けた場合に残っている値を捨てる
+			frame := vm.currentFrame()
+			vm.sp = frame.loops[len(frame.loops)-1]
+			frame.ip = pos - 1
+
+		case code.OpIterator:
+			iterable := vm.pop()
+
+			it, errObj := object.Iterate(iterable)
+			if errObj != nil {
+				return errObj
+			}
+
+			err := vm.push(&iterator{it: it})
+			if err != nil {
+				return err
+			}
+
+		case code.OpIterNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			it := vm.stack[vm.sp-1].(*iterator)
+			value, ok := it.it.Next()
+			if ok {
+				err := vm.push(value)
+				if err != nil {
+					return err
+				}
+			} else {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpCurrentClosure:
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(currentClosure)
+			if err != nil {
+				return err
+			}
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+
+			err := vm.push(array)
+			if err != nil {
+				return err
+			}
+
+		case code.OpInterpolate:
+			numParts := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			str := vm.buildString(vm.sp-numParts, vm.sp)
+			vm.sp = vm.sp - numParts
+
+			err := vm.push(str)
+			if err != nil {
+				return err
+			}
+
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp = vm.sp - numElements
+
+			err = vm.push(hash)
+			if err != nil {
+				return err
+			}
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+
+			err := vm.executeIndexExpression(left, index)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSlice:
+			high := vm.pop()
+			low := vm.pop()
+			left := vm.pop()
+
+			result := object.Slice(left, low, high)
+			if err, ok := result.(*object.Error); ok {
+				return err
+			}
+			err := vm.push(result)
+			if err != nil {
+				return err
+			}
+
+		case code.OpImport:
+			fnIndex := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			mod, err := vm.importModule(fnIndex)
+			if err != nil {
+				return err
+			}
+			err = vm.push(mod)
+			if err != nil {
+				return err
+			}
+
+		case code.OpModule:
+			numExports := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			mod := vm.buildModule(vm.sp-2*numExports-1, vm.sp)
+			vm.sp = vm.sp - 2*numExports - 1
+
+			err := vm.push(mod)
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetMember:
+			nameIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			name := vm.constants[nameIndex].(*object.String)
+			result := object.Member(vm.pop(), name.Value)
+			if err, ok := result.(*object.Error); ok {
+				return err
+			}
+			if result == nil {
+				result = Null
+			}
+			err := vm.push(result)
+			if err != nil {
+				return err
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.executeCall(int(numArgs))
+			if err != nil {
+				return err
+			}
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			// トップレベルのreturnはプログラムの実行を終える
+			if vm.framesIndex == 1 {
+				vm.stack[vm.sp] = returnValue
+				return nil
+			}
+
+			frame := vm.popFrame()
+			// 呼び出された関数自身もスタックから取り除く
+			vm.sp = frame.basePointer - 1
+
+			err := vm.push(returnValue)
+			if err != nil {
+				return err
+			}
+
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.pushClosure(int(constIndex), int(numFree))
+			if err != nil {
+				return err
+			}
+
+		default:
+			def, err := code.Lookup(byte(op))
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("unsupported opcode %s", def.Name)
+		}
+	}
+
+	return nil
+}
+
+func (vm *VM) push(o object.Object) error {
+	if vm.sp >= StackSize {
+		return newError("stack overflow")
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+func (vm *VM) globalName(index int) string {
+	if index < len(vm.globalNames) {
+		return vm.globalNames[index]
+	}
+	return fmt.Sprintf("global#%d", index)
+}
+
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	leftType := left.Type()
+	rightType := right.Type()
+
+	switch {
+	case object.IsInteger(left) && object.IsInteger(right):
+		return vm.executeBinaryIntegerOperation(op, left, right)
+	case isNumber(left) && isNumber(right):
+		return vm.executeBinaryFloatOperation(op, left, right)
+	case leftType != rightType:
+		return newError("type mismatch: %s %s %s", leftType, operators[op], rightType)
+	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
+		return vm.executeBinaryStringOperation(op, left, right)
+	default:
+		return newError("unknown operator: %s %s %s", leftType, operators[op], rightType)
+	}
+}
+
+// ゼロ除算はエラーになり、int64に収まらない結果はvm.Overflowに従って扱う
+func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.Object) error {
+	result := object.IntegerArithmetic(operators[op], left, right, vm.Overflow)
+	if errObj, ok := result.(*object.Error); ok {
+		return errObj
+	}
+
+	return vm.push(result)
+}
+
+// 片方が浮動小数点数なら、もう片方も浮動小数点数にして計算する
+func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	var result float64
+
+	switch op {
+	case code.OpAdd:
+		result = leftValue + rightValue
+	case code.OpSub:
+		result = leftValue - rightValue
+	case code.OpMul:
+		result = leftValue * rightValue
+	case code.OpDiv:
+		result = leftValue / rightValue
+	case code.OpMod:
+		result = math.Mod(leftValue, rightValue)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+
+	return vm.push(&object.Float{Value: result})
+}
+
+func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
+	if op != code.OpAdd {
+		return newError("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	return vm.push(&object.String{Value: leftValue + rightValue})
+}
+
+func (vm *VM) executeComparison(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	if object.IsInteger(left) && object.IsInteger(right) {
+		return vm.executeIntegerComparison(op, left, right)
+	}
+	if isNumber(left) && isNumber(right) {
+		return vm.executeFloatComparison(op, left, right)
+	}
+
+	switch {
+	case op == code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(object.Equals(left, right)))
+	case op == code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(!object.Equals(left, right)))
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), operators[op], right.Type())
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+}
+
+func (vm *VM) executeIntegerComparison(op code.Opcode, left, right object.Object) error {
+	cmp := object.CompareIntegers(left, right)
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp == 0))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp != 0))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(cmp > 0))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(cmp < 0))
+	case code.OpGreaterEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp >= 0))
+	case code.OpLessEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp <= 0))
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+}
+
+func (vm *VM) executeFloatComparison(op code.Opcode, left, right object.Object) error {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue == leftValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpGreaterEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	case code.OpLessEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return object.IsInteger(obj) || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *object.Float:
+		return obj.Value
+	}
+	return 0
+}
+
+func (vm *VM) executeBangOperator() error {
+	operand := vm.pop()
+
+	switch operand {
+	case True:
+		return vm.push(False)
+	case False:
+		return vm.push(True)
+	case Null:
+		return vm.push(True)
+	default:
+		return vm.push(False)
+	}
+}
+
+func (vm *VM) executeMinusOperator() error {
+	operand := vm.pop()
+
+	if f, ok := operand.(*object.Float); ok {
+		return vm.push(&object.Float{Value: -f.Value})
+	}
+	if !object.IsInteger(operand) {
+		return newError("unknown operator: -%s", operand.Type())
+	}
+
+	result := object.NegateInteger(operand, vm.Overflow)
+	if errObj, ok := result.(*object.Error); ok {
+		return errObj
+	}
+	return vm.push(result)
+}
+
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+
+	for i := startIndex; i < endIndex; i++ {
+		elements[i-startIndex] = vm.stack[i]
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// 埋め込み式の値はputsと同じくInspectの表現で文字列に埋め込む
+func (vm *VM) buildString(startIndex, endIndex int) object.Object {
+	var out strings.Builder
+
+	for i := startIndex; i < endIndex; i++ {
+		out.WriteString(vm.stack[i].Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	hash := object.NewHash()
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		pair := object.HashPair{Key: key, Value: value}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, newError("unusable as hash key: %s", key.Type())
+		}
+
+		hash.Set(hashKey.HashKey(), pair)
+	}
+
+	return hash, nil
+}
+
+// モジュールの関数を初回だけ呼び出す 以降は同じモジュールを返す
+func (vm *VM) importModule(fnIndex int) (object.Object, error) {
+	if mod, ok := vm.modules[fnIndex]; ok {
+		return mod, nil
+	}
+
+	fn := vm.constants[fnIndex].(*object.CompiledFunction)
+	mod := vm.callFunction(&object.Closure{Fn: fn})
+	if err, ok := mod.(*object.Error); ok {
+		return nil, err
+	}
+
+	if vm.modules == nil {
+		vm.modules = map[int]object.Object{}
+	}
+	vm.modules[fnIndex] = mod
+	return mod, nil
+}
+
+// スタック上のモジュール名と、名前と値の組からモジュールを作る
+func (vm *VM) buildModule(startIndex, endIndex int) *object.Module {
+	name := vm.stack[startIndex].(*object.String)
+	mod := &object.Module{Name: name.Value, Exports: map[string]object.Object{}}
+
+	for i := startIndex + 1; i < endIndex; i += 2 {
+		key := vm.stack[i].(*object.String)
+		mod.Exports[key.Value] = vm.stack[i+1]
+	}
+	return mod
+}
+
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	if left.Type() == object.ARRAY_OBJ || left.Type() == object.STRING_OBJ || left.Type() == object.RANGE_OBJ {
+		index = object.IndexInteger(index)
+		if err, ok := index.(*object.Error); ok {
+			return err
+		}
+	}
+
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeArrayIndex(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeStringIndex(left, index)
+	case left.Type() == object.RANGE_OBJ && index.Type() == object.INTEGER_OBJ:
+		if elem, ok := left.(*object.Range).At(index.(*object.Integer).Value); ok {
+			return vm.push(elem)
+		}
+		return vm.push(Null)
+	case left.Type() == object.HASH_OBJ:
+		return vm.executeHashIndex(left, index)
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
+
+// 負の添字は末尾から数える
+func (vm *VM) executeArrayIndex(array, index object.Object) error {
+	arrayObject := array.(*object.Array)
+	i, ok := object.NormalizeIndex(index.(*object.Integer).Value, int64(len(arrayObject.Elements)))
+	if !ok {
+		return vm.push(Null)
+	}
+
+	return vm.push(arrayObject.Elements[i])
+}
+
+// 文字列の添字はコードポイント単位で数える
+func (vm *VM) executeStringIndex(str, index object.Object) error {
+	ch, ok := str.(*object.String).CharAt(index.(*object.Integer).Value)
+	if !ok {
+		return vm.push(Null)
+	}
+	return vm.push(ch)
+}
+
+func (vm *VM) executeHashIndex(hash, index object.Object) error {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return vm.push(Null)
+	}
+
+	return vm.push(pair.Value)
+}
+
+// 添字を指定した代入 arr[i] = v, hash[k] = v
+// 配列は範囲内の添字のみ書き換えられる
+func (vm *VM) executeSetIndex(left, index, value object.Object) error {
+	if left.Type() == object.ARRAY_OBJ {
+		index = object.IndexInteger(index)
+		if err, ok := index.(*object.Error); ok {
+			return err
+		}
+	}
+
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		arrayObject := left.(*object.Array)
+		i, ok := object.NormalizeIndex(index.(*object.Integer).Value, int64(len(arrayObject.Elements)))
+		if !ok {
+			return newError("index out of range: %d", index.(*object.Integer).Value)
+		}
+		arrayObject.Elements[i] = value
+	case left.Type() == object.HASH_OBJ:
+		hashObject := left.(*object.Hash)
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		hashObject.Set(key.HashKey(), object.HashPair{Key: index, Value: value})
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+
+	return vm.push(value)
+}
+
+// 関数呼び出し
+// スタックには呼び出される関数、その上に引数が積まれている
+func (vm *VM) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return newError("not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return newError("wrong number of arguments: want=%d, got=%d",
+			cl.Fn.NumParameters, numArgs)
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs)
+	err := vm.pushFrame(frame)
+	if err != nil {
+		return err
+	}
+
+	// 引数より後ろのローカル束縛の領域を確保する
+	if frame.basePointer+cl.Fn.NumLocals >= StackSize {
+		return newError("stack overflow")
+	}
+	for i := vm.sp; i < frame.basePointer+cl.Fn.NumLocals; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+
+	return nil
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+
+	result := object.ApplyOverflowPolicy(builtin.Call(vm.callFunction, args...), vm.Overflow)
+	vm.sp = vm.sp - numArgs - 1
+
+	// 組み込み関数のエラーはevaluatorと同様に実行を中断する
+	if err, ok := result.(*object.Error); ok {
+		return err
+	}
+
+	if result != nil {
+		return vm.push(result)
+	}
+	return vm.push(Null)
+}
+
+// 組み込み関数から関数を呼び出す
+// 関数と引数をスタックに積んで呼び出し、関数から戻るまで命令を実行する
+func (vm *VM) callFunction(fn object.Object, args ...object.Object) object.Object {
+	base := vm.sp
+	stop := vm.framesIndex
+
+	err := vm.push(fn)
+	for _, arg := range args {
+		if err == nil {
+			err = vm.push(arg)
+		}
+	}
+	if err == nil {
+		err = vm.executeCall(len(args))
+	}
+	if err == nil {
+		err = vm.run(stop)
+	}
+	if err != nil {
+		if errObj, ok := err.(*object.Error); ok {
+			return errObj
+		}
+		return newError("%s", err)
+	}
+
+	result := vm.pop()
+	vm.sp = base
+	return result
+}
+
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]object.Object, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i]
+	}
+	vm.sp = vm.sp - numFree
+
+	closure := &object.Closure{Fn: function, Free: free}
+	return vm.push(closure)
+}
+
+// オペコードに対応する演算子 (エラーメッセージ用)
+var operators = map[code.Opcode]string{
+	code.OpAdd:          "+",
+	code.OpSub:          "-",
+	code.OpMul:          "*",
+	code.OpDiv:          "/",
+	code.OpMod:          "%",
+	code.OpEqual:        "==",
+	code.OpNotEqual:     "!=",
+	code.OpGreaterThan:  ">",
+	code.OpLessThan:     "<",
+	code.OpGreaterEqual: ">=",
+	code.OpLessEqual:    "<=",
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return True
+	}
+	return False
+}
+
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
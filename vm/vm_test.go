@@ -0,0 +1,400 @@
+package vm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kakts/monkey/ast"
+	"github.com/kakts/monkey/compiler"
+	"github.com/kakts/monkey/lexer"
+	"github.com/kakts/monkey/object"
+	"github.com/kakts/monkey/parser"
+)
+
+type vmTestCase struct {
+	input    string
+	expected interface{}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1", 1},
+		{"1 + 2", 3},
+		{"4 / 2", 2},
+		{"50 / 2 * 2 + 10 - 5", 55},
+		{"5 * (2 + 10)", 60},
+		{"-50 + 100 + -50", 0},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"10 % 3", 1},
+		{"-10 % 3", -1},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"2.5", 2.5},
+		{"-2.5", -2.5},
+		{"1 + 0.5", 1.5},
+		{"10 / 4.0", 2.5},
+		{"1.5 < 2", true},
+		{"1 == 1.0", true},
+		{"sqrt(16)", 4.0},
+		{"floor(2.5)", 2},
+		{"5.5 % 2", 1.5},
+		{"1.5 >= 2", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		policy   object.OverflowPolicy
+		expected string
+	}{
+		{"9223372036854775807 + 1", object.OverflowWrap, "-9223372036854775808"},
+		{"9223372036854775807 + 1", object.OverflowError, "integer overflow: 9223372036854775807 + 1"},
+		{"9223372036854775807 + 1", object.OverflowPromote, "9223372036854775808"},
+		{"pow(2, 63)", object.OverflowError, "integer overflow: 9223372036854775808"},
+		{"pow(2, 63) - 1", object.OverflowPromote, "9223372036854775807"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.Overflow = tt.policy
+
+		var got string
+		if err := vm.Run(); err != nil {
+			got = err.(*object.Error).Message
+		} else {
+			got = vm.LastPoppedStackElem().Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%q (%s): want=%s, got=%s", tt.input, tt.policy, tt.expected, got)
+		}
+	}
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", true},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 == 1", true},
+		{"true != false", true},
+		{"(1 < 2) == true", true},
+		{"!5", false},
+		{"!!true", true},
+		{"!(if (false) { 5; })", true},
+		{"2 <= 2", true},
+		{"1 >= 2", false},
+		{"true && false", false},
+		{"1 && 2", true},
+		{"false || 0", true},
+		{"false || false", false},
+		{"false && foobar", false},
+		{"true || foobar", true},
+		{"1 < 2 && 2 < 3", true},
+		{`"a" == "a"`, true},
+		{`"a" != "b"`, true},
+		{"[1, [2]] == [1, [2]]", true},
+		{`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, true},
+		{"if (false) { 1 } == if (false) { 2 }", true},
+		{"let f = fn() { 1 }; f == f", true},
+		{"fn() { 1 } == fn() { 1 }", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", 10},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 > 2) { 10 }", Null},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		{"if (true) { let a = 1; }", Null},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", 1},
+		{"let one = 1; let two = one + one; one + two", 3},
+		{"let a = 1; let a = a + 1; a", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestCollections(t *testing.T) {
+	tests := []vmTestCase{
+		{`"mon" + "key"`, "monkey"},
+		{`let n = 2; "${n} + ${n} = ${n + n}"`, "2 + 2 = 4"},
+		{`"a\tb"`, "a\tb"},
+		{"[1 + 2, 3 * 4][1]", 12},
+		{"[1, 2, 3][3]", Null},
+		{"[1][-1]", 1},
+		{"[1][-2]", Null},
+		{`"abc"[-1]`, "c"},
+		{"[1, 2, 3][1:]", []int{2, 3}},
+		{"[1, 2, 3][:-1]", []int{1, 2}},
+		{"[1, 2, 3][:]", []int{1, 2, 3}},
+		{`"hello"[1:3]`, "el"},
+		{"{1: 1, 2: 2}[2]", 2},
+		{"{}[0]", Null},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []vmTestCase{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x += 2", 3},
+		{"let x = 10; x -= 2; x *= 3; x /= 4; x %= 4; x", 2},
+		{"let a = 1; let b = 2; a = b = 3; a + b", 6},
+		{"let x = 1; let f = fn() { x = 10 }; f(); x", 10},
+		{"let f = fn(x) { x += 1; x }; f(1)", 2},
+		{"let counter = fn() { let c = 0; fn() { c += 1 } }; let f = counter(); f(); f(); f()", 3},
+		{"let counter = fn() { let c = 0; let inc = fn() { c += 1 }; inc(); inc(); c }; counter()", 2},
+		{"let f = fn() { let c = 0; let g = fn() { fn() { c = 5 } }; g()(); c }; f()", 5},
+		{"let f = fn() { let x = 1; let g = fn() { x }; let x = 2; g() }; f()", 2},
+		{"let arr = [1, 2, 3]; arr[1] = 20; arr", []int{1, 20, 3}},
+		{"let arr = [1, 2, 3]; arr[2] *= 5", 15},
+		{`let h = {"a": 1}; h["a"] += 1; h["b"] = 5; h["a"] + h["b"]`, 7},
+		{"x = 1", &object.Error{Message: "identifier not found: x"}},
+		{"let arr = [1]; arr[1] = 2", &object.Error{Message: "index out of range: 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 0; while (i < 5) { i += 1 }; i", 5},
+		{"while (false) { 1 }", Null},
+		{"let i = 0; while (true) { i += 1; if (i == 3) { break; } }; i", 3},
+		{"let s = 0; let i = 0; while (i < 10) { i += 1; if (i % 2 == 0) { continue; } s += i }; s", 25},
+		{"let s = 0; for (x in [1, 2, 3]) { s += x }; s", 6},
+		{"let s = 0; for (x in range(2, 10, 3)) { s += x }; s", 15},
+		{"let f = fn() { let s = 0; for (x in range(5)) { s += x }; s }; f()", 10},
+		{"let f = fn() { for (x in range(10)) { if (x == 4) { return x; } } }; f()", 4},
+		{"let n = 0; for (i in range(3)) { for (j in range(3)) { if (j == 1) { break; } n += 1 } }; n", 3},
+		{"for (x in 5) { x }", &object.Error{Message: "not iterable: INTEGER"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestCallingFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let f = fn() { 5 + 10; }; f();", 15},
+		{"let early = fn() { return 99; 100; }; early();", 99},
+		{"let noReturn = fn() { }; noReturn();", Null},
+		{"let sum = fn(a, b) { let c = a + b; c; }; sum(1, 2) + sum(3, 4);", 10},
+		{"let g = 10; let f = fn(a) { let l = 1; a + l + g }; f(1) + f(2);", 25},
+		{"return 5; 10", 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`let newAdder = fn(a, b) { fn(c) { a + b + c } };
+			let adder = newAdder(1, 2);
+			adder(8);`,
+			11,
+		},
+		{
+			`let countDown = fn(x) { if (x == 0) { return 0; } else { countDown(x - 1); } };
+			let wrapper = fn() { countDown(1); };
+			wrapper();`,
+			0,
+		},
+		{
+			`let wrapper = fn() {
+				let countDown = fn(x) { if (x == 0) { return 0; } else { countDown(x - 1); } };
+				countDown(1);
+			};
+			wrapper();`,
+			0,
+		},
+		{
+			`let fibonacci = fn(x) {
+				if (x == 0) { return 0; }
+				if (x == 1) { return 1; }
+				fibonacci(x - 1) + fibonacci(x - 2);
+			};
+			fibonacci(15);`,
+			610,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`len("four")`, 4},
+		{`len("日本")`, 2},
+		{`join(split("a-b-c", "-"), "+")`, "a+b+c"},
+		{`if (starts_with("monkey", "mon")) { 1 } else { 2 }`, 1},
+		{`map([1, 2, 3], fn(x) { x * 2 })`, []int{2, 4, 6}},
+		{`let n = 10; filter(range(n), fn(x) { x * x > n })`, []int{4, 5, 6, 7, 8, 9}},
+		{`let f = fn() { let k = 3; reduce([1, 2], fn(a, x) { a + x * k }, 0) }; f()`, 9},
+		{`sort([3, 1, 2])`, []int{1, 2, 3}},
+		{`map([1], fn(x) { x + true })`, &object.Error{Message: "type mismatch: INTEGER + BOOLEAN"}},
+		{`len([1, 2, 3])`, 3},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, Null},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`push([], 1)`, []int{1}},
+		{`puts("hello")`, Null},
+		{`len(1)`, &object.Error{Message: "argument to `len` not supported, got INTEGER"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"5 + true;", &object.Error{Message: "type mismatch: INTEGER + BOOLEAN"}},
+		{"-true", &object.Error{Message: "unknown operator: -BOOLEAN"}},
+		{`"a" < "b"`, &object.Error{Message: "unknown operator: STRING < STRING"}},
+		{"foobar", &object.Error{Message: "identifier not found: foobar"}},
+		{"1(2)", &object.Error{Message: "not a function: INTEGER"}},
+		{"fn(a) { a }()", &object.Error{Message: "wrong number of arguments: want=1, got=0"}},
+		{"{fn() {}: 1}", &object.Error{Message: "unusable as hash key: FUNCTION"}},
+		{"1[0]", &object.Error{Message: "index operator not supported: INTEGER"}},
+		{"let f = fn() { f() }; f()", &object.Error{Message: "stack overflow"}},
+		{"1 / 0", &object.Error{Message: "division by zero"}},
+		{"1 % 0", &object.Error{Message: "division by zero"}},
+		{"true && foobar", &object.Error{Message: "identifier not found: foobar"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func runVmTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+
+		if expectedErr, ok := tt.expected.(*object.Error); ok {
+			if err == nil {
+				t.Errorf("expected VM error but resulted in none for %q", tt.input)
+				continue
+			}
+			errObj, ok := err.(*object.Error)
+			if !ok || errObj.Message != expectedErr.Message {
+				t.Errorf("wrong VM error for %q: want=%q, got=%q", tt.input, expectedErr.Message, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("vm error for %q: %s", tt.input, err)
+		}
+
+		stackElem := vm.LastPoppedStackElem()
+
+		testExpectedObject(t, tt.input, tt.expected, stackElem)
+	}
+}
+
+func testExpectedObject(t *testing.T, input string, expected interface{}, actual object.Object) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		err := testIntegerObject(int64(expected), actual)
+		if err != nil {
+			t.Errorf("testIntegerObject failed for %q: %s", input, err)
+		}
+
+	case float64:
+		result, ok := actual.(*object.Float)
+		if !ok || result.Value != expected {
+			t.Errorf("object is not Float %g for %q. got=%T (%+v)", expected, input, actual, actual)
+		}
+
+	case bool:
+		result, ok := actual.(*object.Boolean)
+		if !ok || result.Value != expected {
+			t.Errorf("object is not Boolean %t for %q. got=%T (%+v)", expected, input, actual, actual)
+		}
+
+	case string:
+		result, ok := actual.(*object.String)
+		if !ok || result.Value != expected {
+			t.Errorf("object is not String %q for %q. got=%T (%+v)", expected, input, actual, actual)
+		}
+
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Errorf("object not Array for %q: %T (%+v)", input, actual, actual)
+			return
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong num of elements for %q. want=%d, got=%d", input, len(expected), len(array.Elements))
+			return
+		}
+
+		for i, expectedElem := range expected {
+			err := testIntegerObject(int64(expectedElem), array.Elements[i])
+			if err != nil {
+				t.Errorf("testIntegerObject failed for %q: %s", input, err)
+			}
+		}
+
+	case *object.Null:
+		if actual != Null {
+			t.Errorf("object is not Null for %q: %T (%+v)", input, actual, actual)
+		}
+	}
+}
+
+func testIntegerObject(expected int64, actual object.Object) error {
+	result, ok := actual.(*object.Integer)
+	if !ok {
+		return fmt.Errorf("object is not Integer. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+	}
+
+	return nil
+}